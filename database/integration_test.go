@@ -164,3 +164,261 @@ func TestUpdateUserSettings_Integration(t *testing.T) {
 		t.Errorf("settings not updated: %+v", updated)
 	}
 }
+
+func TestTimeEntries_Integration(t *testing.T) {
+	requireTestDB(t)
+
+	telegramID := time.Now().UnixNano() + 3
+	user, err := GetOrCreateUser(telegramID, "tracker", "Time", "Tracker")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := DB.Exec("DELETE FROM users WHERE telegram_id = $1", telegramID); err != nil {
+			t.Logf("cleanup: %v", err)
+		}
+	})
+
+	task := &models.Task{UserID: user.ID, Title: "Tracked", HoursRequired: 8, Priority: 5}
+	if err := CreateTask(task); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := LogTime(user.ID, task.ID, 1.5); err != nil {
+		t.Fatalf("LogTime: %v", err)
+	}
+	if stopped, err := StartTimer(user.ID, task.ID); err != nil || stopped != nil {
+		t.Fatalf("StartTimer: stopped=%v err=%v", stopped, err)
+	}
+	if running, err := GetRunningTimer(user.ID); err != nil || running == nil || running.TaskID != task.ID {
+		t.Fatalf("GetRunningTimer: running=%+v err=%v", running, err)
+	}
+	if stopped, err := StopTimer(user.ID); err != nil || stopped == nil || stopped.EndedAt == nil {
+		t.Fatalf("StopTimer: stopped=%+v err=%v", stopped, err)
+	}
+
+	got, err := GetTaskByIDForUser(task.ID, user.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetTaskByIDForUser: %v", err)
+	}
+	if got.HoursSpent < 1.5 || got.Status != "in_progress" {
+		t.Errorf("expected >= 1.5 spent hours and in_progress status, got %.2f %q", got.HoursSpent, got.Status)
+	}
+}
+
+func TestSaveTaskSchedules_KeepsInProgressStatus_Integration(t *testing.T) {
+	requireTestDB(t)
+
+	telegramID := time.Now().UnixNano() + 6
+	user, err := GetOrCreateUser(telegramID, "rebuilder", "Re", "Build")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := DB.Exec("DELETE FROM users WHERE telegram_id = $1", telegramID); err != nil {
+			t.Logf("cleanup: %v", err)
+		}
+	})
+
+	started := &models.Task{UserID: user.ID, Title: "Started", HoursRequired: 4, Priority: 5}
+	fresh := &models.Task{UserID: user.ID, Title: "Fresh", HoursRequired: 2, Priority: 5}
+	for _, task := range []*models.Task{started, fresh} {
+		if err := CreateTask(task); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+	if _, err := StartTimer(user.ID, started.ID); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	if _, err := StopTimer(user.ID); err != nil {
+		t.Fatalf("StopTimer: %v", err)
+	}
+
+	// A rebuild clears the plan and saves it again.
+	day := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := ClearTaskSchedules([]int64{started.ID, fresh.ID}); err != nil {
+		t.Fatalf("ClearTaskSchedules: %v", err)
+	}
+	if err := SaveTaskSchedules([]models.DaySchedule{{Date: day, Tasks: []models.ScheduledTaskInfo{
+		{TaskID: started.ID, HoursAllocated: 2},
+		{TaskID: fresh.ID, HoursAllocated: 2},
+	}}}); err != nil {
+		t.Fatalf("SaveTaskSchedules: %v", err)
+	}
+
+	for task, want := range map[*models.Task]string{started: "in_progress", fresh: "scheduled"} {
+		got, err := GetTaskByIDForUser(task.ID, user.ID)
+		if err != nil || got == nil {
+			t.Fatalf("GetTaskByIDForUser: %v", err)
+		}
+		if got.Status != want {
+			t.Errorf("%s: expected status %q after a rebuild, got %q", task.Title, want, got.Status)
+		}
+	}
+}
+
+func TestAddTaskDependency_RejectsCycle_Integration(t *testing.T) {
+	requireTestDB(t)
+
+	telegramID := time.Now().UnixNano() + 7
+	user, err := GetOrCreateUser(telegramID, "linker", "Link", "Tasks")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := DB.Exec("DELETE FROM users WHERE telegram_id = $1", telegramID); err != nil {
+			t.Logf("cleanup: %v", err)
+		}
+	})
+
+	first := &models.Task{UserID: user.ID, Title: "First", HoursRequired: 1, Priority: 5}
+	second := &models.Task{UserID: user.ID, Title: "Second", HoursRequired: 1, Priority: 5}
+	for _, task := range []*models.Task{first, second} {
+		if err := CreateTask(task); err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+	}
+	createsCycle := func(taskID, dependsOnID int64) func(map[int64][]int64) bool {
+		return func(deps map[int64][]int64) bool {
+			for _, p := range deps[dependsOnID] {
+				if p == taskID {
+					return true
+				}
+			}
+			return taskID == dependsOnID
+		}
+	}
+
+	if added, err := AddTaskDependency(user.ID, second.ID, first.ID, createsCycle(second.ID, first.ID)); err != nil || !added {
+		t.Fatalf("AddTaskDependency: added=%v err=%v", added, err)
+	}
+	if added, err := AddTaskDependency(user.ID, first.ID, second.ID, createsCycle(first.ID, second.ID)); err != nil || added {
+		t.Errorf("the reverse link must be rejected: added=%v err=%v", added, err)
+	}
+	deps, err := GetUserTaskDependencies(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserTaskDependencies: %v", err)
+	}
+	if len(deps[first.ID]) != 0 || len(deps[second.ID]) != 1 {
+		t.Errorf("expected only second -> first, got %v", deps)
+	}
+}
+
+func TestMissedWorkAnswer_Integration(t *testing.T) {
+	requireTestDB(t)
+
+	telegramID := time.Now().UnixNano() + 4
+	user, err := GetOrCreateUser(telegramID, "missed", "Missed", "Work")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := DB.Exec("DELETE FROM users WHERE telegram_id = $1", telegramID); err != nil {
+			t.Logf("cleanup: %v", err)
+		}
+	})
+
+	task := &models.Task{UserID: user.ID, Title: "Missed", HoursRequired: 4, Priority: 5}
+	if err := CreateTask(task); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	older := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(0, 0, 1)
+	if err := SaveTaskSchedules([]models.DaySchedule{
+		{Date: older, Tasks: []models.ScheduledTaskInfo{{TaskID: task.ID, HoursAllocated: 2}}},
+	}); err != nil {
+		t.Fatalf("SaveTaskSchedules: %v", err)
+	}
+	if err := MarkMissedWorkPrompted([]int64{task.ID}, newer); err != nil {
+		t.Fatalf("MarkMissedWorkPrompted: %v", err)
+	}
+	// A newer prompt about the next day arrives before the first one is answered.
+	if err := SaveTaskSchedules([]models.DaySchedule{
+		{Date: newer, Tasks: []models.ScheduledTaskInfo{{TaskID: task.ID, HoursAllocated: 1}}},
+	}); err != nil {
+		t.Fatalf("SaveTaskSchedules: %v", err)
+	}
+	if err := MarkMissedWorkPrompted([]int64{task.ID}, newer.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("MarkMissedWorkPrompted: %v", err)
+	}
+
+	if pending, err := HasMissedWorkPrompt(task.ID, older, older); err != nil || !pending {
+		t.Fatalf("HasMissedWorkPrompt before answer: pending=%v err=%v", pending, err)
+	}
+	if claimed, err := AnswerMissedWork(user.ID, task.ID, older, older, 1.5); err != nil || !claimed {
+		t.Fatalf("first answer: claimed=%v err=%v", claimed, err)
+	}
+	if claimed, err := AnswerMissedWork(user.ID, task.ID, older, older, 1.5); err != nil || claimed {
+		t.Errorf("repeated answer must be rejected: claimed=%v err=%v", claimed, err)
+	}
+	if pending, err := HasMissedWorkPrompt(task.ID, older, older); err != nil || pending {
+		t.Errorf("HasMissedWorkPrompt after answer: pending=%v err=%v", pending, err)
+	}
+
+	got, err := GetTaskByIDForUser(task.ID, user.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetTaskByIDForUser: %v", err)
+	}
+	if got.HoursSpent != 1.5 {
+		t.Errorf("expected the answer to log 1.5 hours once, got %.2f", got.HoursSpent)
+	}
+	if pending, err := HasMissedWorkPrompt(task.ID, newer, newer); err != nil || !pending {
+		t.Errorf("answering the older prompt must keep the newer one: pending=%v err=%v", pending, err)
+	}
+	if planned, err := PlannedTaskHours(task.ID); err != nil || planned != 1 {
+		t.Errorf("expected the newer prompt's 1 hour to stay planned, got %v err=%v", planned, err)
+	}
+}
+
+func TestUpdateRecurringTask_KeepsStartedInstances_Integration(t *testing.T) {
+	requireTestDB(t)
+
+	telegramID := time.Now().UnixNano() + 5
+	user, err := GetOrCreateUser(telegramID, "repeater", "Repeat", "User")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := DB.Exec("DELETE FROM users WHERE telegram_id = $1", telegramID); err != nil {
+			t.Logf("cleanup: %v", err)
+		}
+	})
+
+	rt := &models.RecurringTask{UserID: user.ID, Title: "Weekly report", HoursRequired: 2, Priority: 5, RRule: "FREQ=WEEKLY;BYDAY=MO"}
+	if err := CreateRecurringTask(rt); err != nil {
+		t.Fatalf("CreateRecurringTask: %v", err)
+	}
+	started := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	untouched := started.AddDate(0, 0, 7)
+	if _, err := MaterializeRecurringTask(rt, []time.Time{started, untouched}, untouched); err != nil {
+		t.Fatalf("MaterializeRecurringTask: %v", err)
+	}
+
+	instanceID := func(date time.Time) int64 {
+		t.Helper()
+		var id int64
+		err := DB.QueryRow(`SELECT id FROM tasks WHERE recurring_id = $1 AND occurrence_date = $2`, rt.ID, date).Scan(&id)
+		if err == sql.ErrNoRows {
+			return 0
+		}
+		if err != nil {
+			t.Fatalf("find instance: %v", err)
+		}
+		return id
+	}
+	startedID := instanceID(started)
+	if err := LogTime(user.ID, startedID, 0.5); err != nil {
+		t.Fatalf("LogTime: %v", err)
+	}
+
+	rt.RRule = "FREQ=WEEKLY;BYDAY=TU"
+	if err := UpdateRecurringTask(rt, started, true); err != nil {
+		t.Fatalf("UpdateRecurringTask: %v", err)
+	}
+
+	if id := instanceID(started); id != startedID {
+		t.Errorf("in-progress instance must survive a rule change, got id %d want %d", id, startedID)
+	}
+	if id := instanceID(untouched); id != 0 {
+		t.Errorf("untouched instance %d should have been removed", id)
+	}
+}
@@ -18,6 +18,120 @@ func EnsureSchema() error {
 		`CREATE INDEX IF NOT EXISTS idx_google_calendar_events_user_id ON google_calendar_events(user_id)`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_google_calendar_events_user_event ON google_calendar_events(user_id, google_event_id)`,
 		`ALTER TABLE google_calendar_events ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'planbot'`,
+		`CREATE TABLE IF NOT EXISTS task_dependencies (
+			task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			depends_on_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (task_id, depends_on_id),
+			CHECK (task_id <> depends_on_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_task_dependencies_depends_on ON task_dependencies(depends_on_id)`,
+		`CREATE TABLE IF NOT EXISTS user_work_windows (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+			start_time VARCHAR(5) NOT NULL,
+			end_time VARCHAR(5) NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_work_windows_user_id ON user_work_windows(user_id)`,
+		`CREATE TABLE IF NOT EXISTS user_days_off (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			start_date DATE NOT NULL,
+			end_date DATE NOT NULL,
+			reason VARCHAR(255),
+			source VARCHAR(20) NOT NULL DEFAULT 'manual',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (end_date >= start_date)
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_days_off_user_range ON user_days_off(user_id, start_date, end_date)`,
+		`CREATE TABLE IF NOT EXISTS user_capacity_overrides (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			start_date DATE NOT NULL,
+			end_date DATE NOT NULL,
+			hours DECIMAL(5,2) NOT NULL,
+			reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (end_date >= start_date),
+			CHECK (hours >= 0)
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_capacity_overrides_user_range ON user_capacity_overrides(user_id, start_date, end_date)`,
+		`CREATE TABLE IF NOT EXISTS user_project_budgets (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			project VARCHAR(64) NOT NULL,
+			weekly_hours DECIMAL(5,2) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (weekly_hours > 0)
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_project_budgets_user_project ON user_project_budgets(user_id, project)`,
+		`CREATE TABLE IF NOT EXISTS user_project_time_prefs (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			project VARCHAR(64) NOT NULL,
+			time_preference TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_project_time_prefs_user_project ON user_project_time_prefs(user_id, project)`,
+		`CREATE TABLE IF NOT EXISTS recurring_tasks (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			title VARCHAR(500) NOT NULL,
+			description TEXT,
+			hours_required DECIMAL(5,2) NOT NULL,
+			priority INTEGER DEFAULT 0,
+			rrule TEXT NOT NULL,
+			materialized_through DATE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_recurring_tasks_user_id ON recurring_tasks(user_id)`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurring_id BIGINT REFERENCES recurring_tasks(id) ON DELETE SET NULL`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS occurrence_date DATE`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurring_occurrence ON tasks(recurring_id, occurrence_date)`,
+		`CREATE TABLE IF NOT EXISTS time_entries (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			started_at TIMESTAMP NOT NULL,
+			ended_at TIMESTAMP,
+			hours DECIMAL(5,2),
+			source VARCHAR(20) NOT NULL DEFAULT 'log',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries(task_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS inflate_estimates BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS min_chunk_minutes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS max_hours_per_day DECIMAL(5,2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS min_chunk_minutes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS max_task_hours_per_day DECIMAL(5,2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS pinned_start TIMESTAMP`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS pinned_end TIMESTAMP`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS start_after TIMESTAMP`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS scheduling_strategy VARCHAR(20) NOT NULL DEFAULT 'greedy'`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS time_preference TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS break_every_minutes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS break_minutes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS lunch_start VARCHAR(5) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS lunch_end VARCHAR(5) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS export_breaks BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS buffer_before_minutes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS buffer_after_minutes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS travel_minutes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE task_schedules ADD COLUMN IF NOT EXISTS missed_prompted BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS project VARCHAR(64) NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_user_project ON tasks(user_id, project)`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES tasks(id) ON DELETE CASCADE`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks(parent_id)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS cluster_projects BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS max_tasks_per_day INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS energy VARCHAR(10) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS energy_curve TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS max_high_energy_hours DECIMAL(5,2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS priority_aging_days INTEGER NOT NULL DEFAULT 0`,
 	}
 
 	for _, q := range queries {
@@ -26,6 +140,6 @@ func EnsureSchema() error {
 		}
 	}
 
-	log.Println("Database schema ensured (google_calendar_events)")
+	log.Println("Database schema ensured (google_calendar_events, task_dependencies, user_work_windows, user_days_off, user_capacity_overrides, user_project_budgets, user_project_time_prefs, recurring_tasks, time_entries)")
 	return nil
 }
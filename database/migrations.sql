@@ -110,4 +110,171 @@ BEGIN
                    WHERE table_name='google_calendar_events' AND column_name='source') THEN
         ALTER TABLE google_calendar_events ADD COLUMN source VARCHAR(50) NOT NULL DEFAULT 'planbot';
     END IF;
-END $$;
\ No newline at end of file
+END $$;
+
+-- Task dependencies (blocked-by)
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    depends_on_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, depends_on_id),
+    CHECK (task_id <> depends_on_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_depends_on ON task_dependencies(depends_on_id);
+
+-- Weekly availability template (several work windows per weekday)
+CREATE TABLE IF NOT EXISTS user_work_windows (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7), -- 1=Monday, 7=Sunday
+    start_time VARCHAR(5) NOT NULL, -- HH:MM
+    end_time VARCHAR(5) NOT NULL -- HH:MM
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_work_windows_user_id ON user_work_windows(user_id);
+
+-- Vacations, public holidays and single days off (inclusive date ranges)
+CREATE TABLE IF NOT EXISTS user_days_off (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason VARCHAR(255),
+    source VARCHAR(20) NOT NULL DEFAULT 'manual', -- manual | holiday
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_date >= start_date)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_days_off_user_range ON user_days_off(user_id, start_date, end_date);
+
+-- Recurring task templates and their materialized instances
+CREATE TABLE IF NOT EXISTS recurring_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(500) NOT NULL,
+    description TEXT,
+    hours_required DECIMAL(5,2) NOT NULL,
+    priority INTEGER DEFAULT 0,
+    rrule TEXT NOT NULL, -- FREQ=WEEKLY;BYDAY=FR;DTSTART=20261016
+    materialized_through DATE, -- last date already turned into tasks rows
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_tasks_user_id ON recurring_tasks(user_id);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurring_id BIGINT REFERENCES recurring_tasks(id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS occurrence_date DATE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurring_occurrence ON tasks(recurring_id, occurrence_date);
+
+-- Time tracking: manual entries and timers (at most one running timer per user)
+CREATE TABLE IF NOT EXISTS time_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    hours DECIMAL(5,2),
+    source VARCHAR(20) NOT NULL DEFAULT 'log', -- log | timer
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries(task_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
+
+-- Estimate accuracy: optionally scale estimates by the learned bias
+ALTER TABLE users ADD COLUMN IF NOT EXISTS inflate_estimates BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Session length limits: per task, with user defaults (0 = not set)
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS min_chunk_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS max_hours_per_day DECIMAL(5,2) NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS min_chunk_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_task_hours_per_day DECIMAL(5,2) NOT NULL DEFAULT 0;
+
+-- Pinned tasks: fixed-time appointments the scheduler never moves
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS pinned_start TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS pinned_end TIMESTAMP;
+
+-- Earliest start: a task is not planned before this moment
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS start_after TIMESTAMP;
+
+-- Scheduling strategy: greedy (default), asap or balanced
+ALTER TABLE users ADD COLUMN IF NOT EXISTS scheduling_strategy VARCHAR(20) NOT NULL DEFAULT 'greedy';
+
+-- Preferred time of day: soft, e.g. "before 12:00 not mon"
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS time_preference TEXT NOT NULL DEFAULT '';
+
+-- Breaks: a short break every N minutes of work, a fixed lunch window, optional export to Google Calendar
+ALTER TABLE users ADD COLUMN IF NOT EXISTS break_every_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS break_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lunch_start VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lunch_end VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS export_breaks BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Meeting buffers: free minutes around calendar events, travel time around events held at a place
+ALTER TABLE users ADD COLUMN IF NOT EXISTS buffer_before_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS buffer_after_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS travel_minutes INTEGER NOT NULL DEFAULT 0;
+
+-- Per-date capacity caps (/capacity), e.g. conference days
+CREATE TABLE IF NOT EXISTS user_capacity_overrides (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    hours DECIMAL(5,2) NOT NULL, -- cap on task hours per day in the range
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_date >= start_date),
+    CHECK (hours >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_capacity_overrides_user_range ON user_capacity_overrides(user_id, start_date, end_date);
+
+-- Carry-over of missed work: past blocks the user has already been asked about
+ALTER TABLE task_schedules ADD COLUMN IF NOT EXISTS missed_prompted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Projects: one tag per task (/addtask Отчёт #clienta | 4)
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS project VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_tasks_user_project ON tasks(user_id, project);
+
+-- Weekly hour budgets per project (/budget)
+CREATE TABLE IF NOT EXISTS user_project_budgets (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project VARCHAR(64) NOT NULL,
+    weekly_hours DECIMAL(5,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (weekly_hours > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_project_budgets_user_project ON user_project_budgets(user_id, project);
+
+-- Subtasks: a parent's hours are the sum of its open subtasks, planned in position order
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES tasks(id) ON DELETE CASCADE;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks(parent_id);
+
+-- Fewer context switches: group a day's tasks by project, cap distinct tasks per day
+ALTER TABLE users ADD COLUMN IF NOT EXISTS cluster_projects BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_tasks_per_day INTEGER NOT NULL DEFAULT 0;
+
+-- Energy: demanding tasks in peak hours, a daily cap on high-energy hours
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS energy VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS energy_curve TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_high_energy_hours DECIMAL(5,2) NOT NULL DEFAULT 0;
+
+-- Priority aging: tasks without a deadline move up the queue the longer they wait
+ALTER TABLE users ADD COLUMN IF NOT EXISTS priority_aging_days INTEGER NOT NULL DEFAULT 0;
+
+-- Preferred time of day per project (/prefer); a task's own time_preference wins
+CREATE TABLE IF NOT EXISTS user_project_time_prefs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project VARCHAR(64) NOT NULL,
+    time_preference TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_project_time_prefs_user_project ON user_project_time_prefs(user_id, project);
@@ -12,17 +12,19 @@ import (
 	"github.com/adkhorst/planbot/models"
 )
 
-// GetOrCreateUser gets existing user or creates a new one
-func GetOrCreateUser(telegramID int64, username, firstName, lastName string) (*models.User, error) {
+// userColumns is the column list read by scanUser; keep both in sync.
+const userColumns = `id, telegram_id, username, first_name, last_name, time_zone, work_start, work_end, daily_capacity, work_days,
+			  inflate_estimates, min_chunk_minutes, max_task_hours_per_day, scheduling_strategy,
+			  break_every_minutes, break_minutes, lunch_start, lunch_end, export_breaks,
+			  buffer_before_minutes, buffer_after_minutes, travel_minutes, cluster_projects, max_tasks_per_day,
+			  energy_curve, max_high_energy_hours, priority_aging_days, created_at, updated_at`
+
+// scanUser reads one row selected with userColumns.
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-
-	// Try to get existing user
-	query := `SELECT id, telegram_id, username, first_name, last_name, time_zone, work_start, work_end, daily_capacity, work_days, created_at, updated_at
-			  FROM users WHERE telegram_id = $1`
-
 	var workDays pq.Int64Array
 	var usernameNull, fName, lName sql.NullString
-	err := DB.QueryRow(query, telegramID).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.TelegramID,
 		&usernameNull,
@@ -33,30 +35,56 @@ func GetOrCreateUser(telegramID int64, username, firstName, lastName string) (*m
 		&user.WorkEnd,
 		&user.DailyCapacity,
 		&workDays,
+		&user.InflateEstimates,
+		&user.MinChunkMinutes,
+		&user.MaxTaskHoursPerDay,
+		&user.SchedulingStrategy,
+		&user.BreakEveryMinutes,
+		&user.BreakMinutes,
+		&user.LunchStart,
+		&user.LunchEnd,
+		&user.ExportBreaks,
+		&user.BufferBefore,
+		&user.BufferAfter,
+		&user.TravelMinutes,
+		&user.ClusterProjects,
+		&user.MaxTasksPerDay,
+		&user.EnergyCurve,
+		&user.MaxHighEnergyHours,
+		&user.PriorityAgingDays,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	user.Username = usernameNull.String
+	user.FirstName = fName.String
+	user.LastName = lName.String
+
+	// Convert pq.Int64Array to []int
+	user.WorkDays = make([]int, len(workDays))
+	for i, v := range workDays {
+		user.WorkDays[i] = int(v)
+	}
+	return user, nil
+}
+
+// GetOrCreateUser gets existing user or creates a new one
+func GetOrCreateUser(telegramID int64, username, firstName, lastName string) (*models.User, error) {
+	// Try to get existing user
+	query := `SELECT ` + userColumns + `
+			  FROM users WHERE telegram_id = $1`
+
+	user, err := scanUser(DB.QueryRow(query, telegramID))
 	if err == sql.ErrNoRows {
 		// Create new user
 		insertQuery := `INSERT INTO users (telegram_id, username, first_name, last_name)
 						VALUES ($1, $2, $3, $4)
-						RETURNING id, telegram_id, username, first_name, last_name, time_zone, work_start, work_end, daily_capacity, work_days, created_at, updated_at`
-
-		err = DB.QueryRow(insertQuery, telegramID, username, firstName, lastName).Scan(
-			&user.ID,
-			&user.TelegramID,
-			&usernameNull,
-			&fName,
-			&lName,
-			&user.TimeZone,
-			&user.WorkStart,
-			&user.WorkEnd,
-			&user.DailyCapacity,
-			&workDays,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+						RETURNING ` + userColumns
+
+		user, err = scanUser(DB.QueryRow(insertQuery, telegramID, username, firstName, lastName))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create user: %w", err)
 		}
@@ -64,14 +92,29 @@ func GetOrCreateUser(telegramID int64, username, firstName, lastName string) (*m
 		return nil, fmt.Errorf("failed to query user: %w", err)
 	}
 
-	user.Username = usernameNull.String
-	user.FirstName = fName.String
-	user.LastName = lName.String
+	user.WorkWindows, err = GetUserWorkWindows(user.ID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Convert pq.Int64Array to []int
-	user.WorkDays = make([]int, len(workDays))
-	for i, v := range workDays {
-		user.WorkDays[i] = int(v)
+	user.DaysOff, err = GetUserDaysOff(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.CapacityOverrides, err = GetUserCapacityOverrides(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ProjectBudgets, err = GetUserProjectBudgets(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ProjectTimePrefs, err = GetUserProjectTimePrefs(user.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	return user, nil
@@ -109,11 +152,120 @@ func UpdateUserTimeZone(userID int64, timeZone string) error {
 	return nil
 }
 
-// CreateTask creates a new task
+// UpdateUserInflateEstimates turns scaling of estimates by the learned bias on or off.
+func UpdateUserInflateEstimates(userID int64, enabled bool) error {
+	query := `UPDATE users SET inflate_estimates = $1, updated_at = NOW()
+			  WHERE id = $2`
+
+	_, err := DB.Exec(query, enabled, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update estimate inflation: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserChunkDefaults sets the default minimum session length and per-day cap for tasks.
+func UpdateUserChunkDefaults(userID int64, minChunkMinutes int, maxTaskHoursPerDay float64) error {
+	query := `UPDATE users SET min_chunk_minutes = $1, max_task_hours_per_day = $2, updated_at = NOW()
+			  WHERE id = $3`
+
+	_, err := DB.Exec(query, minChunkMinutes, maxTaskHoursPerDay, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update chunk defaults: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserSchedulingStrategy sets how the scheduler spreads tasks over days.
+func UpdateUserSchedulingStrategy(userID int64, strategy string) error {
+	query := `UPDATE users SET scheduling_strategy = $1, updated_at = NOW()
+			  WHERE id = $2`
+
+	_, err := DB.Exec(query, strategy, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduling strategy: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserBreaks sets the short-break rule, the lunch window and whether breaks go to Google Calendar.
+func UpdateUserBreaks(userID int64, everyMinutes, breakMinutes int, lunchStart, lunchEnd string, export bool) error {
+	query := `UPDATE users SET break_every_minutes = $1, break_minutes = $2, lunch_start = $3, lunch_end = $4,
+			  export_breaks = $5, updated_at = NOW()
+			  WHERE id = $6`
+
+	_, err := DB.Exec(query, everyMinutes, breakMinutes, lunchStart, lunchEnd, export, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update breaks: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserBuffers sets the free minutes kept around calendar meetings and the travel time.
+func UpdateUserBuffers(userID int64, before, after, travel int) error {
+	query := `UPDATE users SET buffer_before_minutes = $1, buffer_after_minutes = $2, travel_minutes = $3, updated_at = NOW()
+			  WHERE id = $4`
+
+	_, err := DB.Exec(query, before, after, travel, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update meeting buffers: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserFocus sets whether a day's tasks of one project are laid out back to back
+// and the limit of distinct tasks per day (0 = none).
+func UpdateUserFocus(userID int64, cluster bool, maxTasks int) error {
+	query := `UPDATE users SET cluster_projects = $1, max_tasks_per_day = $2, updated_at = NOW()
+			  WHERE id = $3`
+
+	_, err := DB.Exec(query, cluster, maxTasks, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update focus settings: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserEnergy sets the user's energy curve and the daily cap on high-energy task hours.
+func UpdateUserEnergy(userID int64, curve string, maxHigh float64) error {
+	query := `UPDATE users SET energy_curve = $1, max_high_energy_hours = $2, updated_at = NOW()
+			  WHERE id = $3`
+
+	_, err := DB.Exec(query, curve, maxHigh, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update energy settings: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserAging sets after how many days of waiting a task without a deadline gains a priority step.
+func UpdateUserAging(userID int64, days int) error {
+	query := `UPDATE users SET priority_aging_days = $1, updated_at = NOW()
+			  WHERE id = $2`
+
+	_, err := DB.Exec(query, days, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update priority aging: %w", err)
+	}
+
+	return nil
+}
+
+// CreateTask creates a new task. A subtask (ParentID set) is put after the parent's other subtasks.
 func CreateTask(task *models.Task) error {
-	query := `INSERT INTO tasks (user_id, title, description, hours_required, priority, deadline)
-			  VALUES ($1, $2, $3, $4, $5, $6)
-			  RETURNING id, created_at, updated_at, status`
+	query := `INSERT INTO tasks (user_id, title, description, hours_required, priority, deadline, min_chunk_minutes, max_hours_per_day,
+			                     pinned_start, pinned_end, start_after, time_preference, project, parent_id, position, energy)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14,
+			          CASE WHEN $14::BIGINT IS NULL THEN 0
+			               ELSE (SELECT COALESCE(MAX(position), 0) + 1 FROM tasks WHERE parent_id = $14) END, $15)
+			  RETURNING id, created_at, updated_at, status, position`
 
 	err := DB.QueryRow(query,
 		task.UserID,
@@ -122,7 +274,16 @@ func CreateTask(task *models.Task) error {
 		task.HoursRequired,
 		task.Priority,
 		task.Deadline,
-	).Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt, &task.Status)
+		task.MinChunkMinutes,
+		task.MaxHoursPerDay,
+		task.PinnedStart,
+		task.PinnedEnd,
+		task.StartAfter,
+		task.TimePreference,
+		task.Project,
+		task.ParentID,
+		task.Energy,
+	).Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt, &task.Status, &task.Position)
 
 	if err != nil {
 		return fmt.Errorf("failed to create task: %w", err)
@@ -131,40 +292,116 @@ func CreateTask(task *models.Task) error {
 	return nil
 }
 
-// GetUserTasks retrieves all tasks for a user
-func GetUserTasks(userID int64) ([]models.Task, error) {
-	query := `SELECT id, user_id, title, description, hours_required, priority, status, deadline, 
-			  created_at, updated_at, completed_at
-			  FROM tasks WHERE user_id = $1 ORDER BY priority DESC, deadline ASC NULLS LAST`
+// UpdateTaskDetails saves the editable fields of a task (/edittask).
+func UpdateTaskDetails(task *models.Task) error {
+	query := `UPDATE tasks
+			  SET title = $1, description = $2, hours_required = $3, priority = $4, deadline = $5,
+			      min_chunk_minutes = $6, max_hours_per_day = $7, pinned_start = $8, pinned_end = $9,
+			      start_after = $10, time_preference = $11, project = $12, energy = $13, updated_at = NOW()
+			  WHERE id = $14 AND user_id = $15`
+
+	_, err := DB.Exec(query,
+		task.Title,
+		task.Description,
+		task.HoursRequired,
+		task.Priority,
+		task.Deadline,
+		task.MinChunkMinutes,
+		task.MaxHoursPerDay,
+		task.PinnedStart,
+		task.PinnedEnd,
+		task.StartAfter,
+		task.TimePreference,
+		task.Project,
+		task.Energy,
+		task.ID,
+		task.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	return nil
+}
+
+// taskColumns is the column list read by scanTask; keep both in sync.
+const taskColumns = `id, user_id, title, description, hours_required, priority, status, deadline,
+			  created_at, updated_at, completed_at, recurring_id, occurrence_date, min_chunk_minutes, max_hours_per_day,
+			  pinned_start, pinned_end, start_after, time_preference, project, parent_id, position, energy, (SELECT COALESCE(SUM(te.hours), 0) FROM time_entries te WHERE te.task_id = tasks.id)`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
-	rows, err := DB.Query(query, userID)
+// scanTask reads one row selected with taskColumns.
+func scanTask(row rowScanner) (*models.Task, error) {
+	task := &models.Task{}
+	var desc sql.NullString
+	err := row.Scan(
+		&task.ID,
+		&task.UserID,
+		&task.Title,
+		&desc,
+		&task.HoursRequired,
+		&task.Priority,
+		&task.Status,
+		&task.Deadline,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.CompletedAt,
+		&task.RecurringID,
+		&task.Occurrence,
+		&task.MinChunkMinutes,
+		&task.MaxHoursPerDay,
+		&task.PinnedStart,
+		&task.PinnedEnd,
+		&task.StartAfter,
+		&task.TimePreference,
+		&task.Project,
+		&task.ParentID,
+		&task.Position,
+		&task.Energy,
+		&task.HoursSpent,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query tasks: %w", err)
+		return nil, err
+	}
+	task.Description = desc.String
+	return task, nil
+}
+
+// queryTasks runs a query selecting taskColumns and scans every row.
+func queryTasks(query string, args ...any) ([]models.Task, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
 	defer closeRows(rows)
 
 	tasks := []models.Task{}
 	for rows.Next() {
-		task := models.Task{}
-		var desc sql.NullString
-		err := rows.Scan(
-			&task.ID,
-			&task.UserID,
-			&task.Title,
-			&desc,
-			&task.HoursRequired,
-			&task.Priority,
-			&task.Status,
-			&task.Deadline,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.CompletedAt,
-		)
-		task.Description = desc.String
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %w", err)
 		}
-		tasks = append(tasks, task)
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
+
+// GetUserTasks retrieves all tasks for a user
+func GetUserTasks(userID int64) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + `
+			  FROM tasks WHERE user_id = $1 ORDER BY priority DESC, deadline ASC NULLS LAST`
+
+	tasks, err := queryTasks(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+
+	if err := attachTaskDependencies(userID, tasks); err != nil {
+		return nil, err
 	}
 
 	return tasks, nil
@@ -172,40 +409,14 @@ func GetUserTasks(userID int64) ([]models.Task, error) {
 
 // GetPendingTasks retrieves all pending tasks for a user
 func GetPendingTasks(userID int64) ([]models.Task, error) {
-	query := `SELECT id, user_id, title, description, hours_required, priority, status, deadline, 
-			  created_at, updated_at, completed_at
+	query := `SELECT ` + taskColumns + `
 			  FROM tasks WHERE user_id = $1 AND status = 'pending' 
 			  ORDER BY priority DESC, deadline ASC NULLS LAST`
 
-	rows, err := DB.Query(query, userID)
+	tasks, err := queryTasks(query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query pending tasks: %w", err)
 	}
-	defer closeRows(rows)
-
-	tasks := []models.Task{}
-	for rows.Next() {
-		task := models.Task{}
-		var desc sql.NullString
-		err := rows.Scan(
-			&task.ID,
-			&task.UserID,
-			&task.Title,
-			&desc,
-			&task.HoursRequired,
-			&task.Priority,
-			&task.Status,
-			&task.Deadline,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.CompletedAt,
-		)
-		task.Description = desc.String
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan task: %w", err)
-		}
-		tasks = append(tasks, task)
-	}
 
 	return tasks, nil
 }
@@ -214,41 +425,19 @@ func GetPendingTasks(userID int64) ([]models.Task, error) {
 // "Hard" rescheduling treats all non-completed / non-cancelled tasks as current.
 func GetActiveTasks(userID int64) ([]models.Task, error) {
 	query := `
-		SELECT id, user_id, title, description, hours_required, priority, status, deadline,
-			   created_at, updated_at, completed_at
+		SELECT ` + taskColumns + `
 		FROM tasks
 		WHERE user_id = $1 AND status NOT IN ('completed', 'cancelled')
 		ORDER BY priority DESC, deadline ASC NULLS LAST
 	`
 
-	rows, err := DB.Query(query, userID)
+	tasks, err := queryTasks(query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query active tasks: %w", err)
 	}
-	defer closeRows(rows)
 
-	tasks := []models.Task{}
-	for rows.Next() {
-		task := models.Task{}
-		var desc sql.NullString
-		err := rows.Scan(
-			&task.ID,
-			&task.UserID,
-			&task.Title,
-			&desc,
-			&task.HoursRequired,
-			&task.Priority,
-			&task.Status,
-			&task.Deadline,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.CompletedAt,
-		)
-		task.Description = desc.String
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan active task: %w", err)
-		}
-		tasks = append(tasks, task)
+	if err := attachTaskDependencies(userID, tasks); err != nil {
+		return nil, err
 	}
 
 	return tasks, nil
@@ -279,6 +468,60 @@ func CompleteTask(taskID int64) error {
 	return nil
 }
 
+// CompleteSubtasks completes the open subtasks of a parent and returns their IDs.
+func CompleteSubtasks(parentID int64) ([]int64, error) {
+	rows, err := DB.Query(`UPDATE tasks SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		WHERE parent_id = $1 AND status NOT IN ('completed', 'cancelled')
+		RETURNING id`, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete subtasks: %w", err)
+	}
+	defer closeRows(rows)
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan subtask: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CompleteParentIfDone completes an open parent once it has completed subtasks and no open ones.
+// It reports whether the parent was completed now.
+func CompleteParentIfDone(parentID int64) (bool, error) {
+	res, err := DB.Exec(`UPDATE tasks SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status NOT IN ('completed', 'cancelled')
+		  AND EXISTS (SELECT 1 FROM tasks c WHERE c.parent_id = $1 AND c.status = 'completed')
+		  AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_id = $1 AND c.status NOT IN ('completed', 'cancelled'))`, parentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete parent task: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to complete parent task: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetTaskFamily returns a parent task and all its subtasks, the parent first and subtasks by position.
+func GetTaskFamily(parentID, userID int64) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + `
+			  FROM tasks WHERE user_id = $2 AND (id = $1 OR parent_id = $1)
+			  ORDER BY parent_id NULLS FIRST, position, id`
+
+	tasks, err := queryTasks(query, parentID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task family: %w", err)
+	}
+	if err := attachTaskDependencies(userID, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
 // DeleteTask deletes a task
 func DeleteTask(taskID int64) error {
 	query := `DELETE FROM tasks WHERE id = $1`
@@ -339,9 +582,9 @@ func SaveTaskSchedules(schedules []models.DaySchedule) error {
 		}
 	}
 
-	// Update task status to 'scheduled'
+	// Mark pending tasks as 'scheduled'; tasks already in progress keep their status
 	for taskID := range taskIDs {
-		_, err := tx.Exec(`UPDATE tasks SET status = 'scheduled', updated_at = NOW() WHERE id = $1`, taskID)
+		_, err := tx.Exec(`UPDATE tasks SET status = 'scheduled', updated_at = NOW() WHERE id = $1 AND status = 'pending'`, taskID)
 		if err != nil {
 			return fmt.Errorf("failed to update task status: %w", err)
 		}
@@ -357,29 +600,22 @@ func SaveTaskSchedules(schedules []models.DaySchedule) error {
 
 // GetTaskByIDForUser returns a task if it belongs to the user.
 func GetTaskByIDForUser(taskID, userID int64) (*models.Task, error) {
-	query := `SELECT id, user_id, title, description, hours_required, priority, status, deadline, created_at, updated_at, completed_at
+	query := `SELECT ` + taskColumns + `
 			  FROM tasks WHERE id = $1 AND user_id = $2`
 
-	task := &models.Task{}
-	err := DB.QueryRow(query, taskID, userID).Scan(
-		&task.ID,
-		&task.UserID,
-		&task.Title,
-		&task.Description,
-		&task.HoursRequired,
-		&task.Priority,
-		&task.Status,
-		&task.Deadline,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.CompletedAt,
-	)
+	task, err := scanTask(DB.QueryRow(query, taskID, userID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
+
+	deps, err := GetUserTaskDependencies(userID)
+	if err != nil {
+		return nil, err
+	}
+	task.DependsOn = deps[task.ID]
 	return task, nil
 }
 
@@ -413,7 +649,8 @@ func GetAllUserSchedulesFrom(userID int64, fromDate time.Time) ([]models.DaySche
 
 // GetScheduleForDateRange retrieves schedule for a date range
 func GetScheduleForDateRange(userID int64, startDate, endDate time.Time) ([]models.DaySchedule, error) {
-	query := `SELECT ts.scheduled_date, ts.task_id, t.title, ts.hours_allocated, t.priority, t.deadline
+	query := `SELECT ts.scheduled_date, ts.task_id, t.title, ts.hours_allocated, t.priority, t.deadline, t.min_chunk_minutes,
+			         t.pinned_start, t.pinned_end, t.start_after, t.time_preference, t.project, t.energy
 			  FROM task_schedules ts
 			  JOIN tasks t ON ts.task_id = t.id
 			  WHERE t.user_id = $1 AND ts.scheduled_date >= $2 AND ts.scheduled_date <= $3
@@ -439,6 +676,13 @@ func GetScheduleForDateRange(userID int64, startDate, endDate time.Time) ([]mode
 			&taskInfo.HoursAllocated,
 			&taskInfo.Priority,
 			&taskInfo.Deadline,
+			&taskInfo.MinChunkMinutes,
+			&taskInfo.PinnedStart,
+			&taskInfo.PinnedEnd,
+			&taskInfo.StartAfter,
+			&taskInfo.TimePreference,
+			&taskInfo.Project,
+			&taskInfo.Energy,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan schedule: %w", err)
@@ -459,9 +703,20 @@ func GetScheduleForDateRange(userID int64, startDate, endDate time.Time) ([]mode
 		daySchedule.TotalHours += taskInfo.HoursAllocated
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	deps, err := GetUserTaskDependencies(userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert map to slice
 	schedules := make([]models.DaySchedule, 0, len(scheduleMap))
 	for _, schedule := range scheduleMap {
+		for i := range schedule.Tasks {
+			schedule.Tasks[i].DependsOn = deps[schedule.Tasks[i].TaskID]
+		}
 		schedules = append(schedules, *schedule)
 	}
 
@@ -571,7 +826,9 @@ func SaveGoogleCalendarEvents(userID int64, events []models.GoogleCalendarEvent)
 		if source == "" {
 			source = "planbot"
 		}
-		_, err := stmt.Exec(userID, ev.GoogleEventID, ev.TaskID, source, ev.StartTime, ev.EndTime)
+		// Break events belong to no task
+		taskID := sql.NullInt64{Int64: ev.TaskID, Valid: ev.TaskID != 0}
+		_, err := stmt.Exec(userID, ev.GoogleEventID, taskID, source, ev.StartTime, ev.EndTime)
 
 		if err != nil {
 			return fmt.Errorf("failed to insert google event: %w", err)
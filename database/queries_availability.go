@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// GetUserWorkWindows returns the user's weekly availability template ordered by weekday and start.
+func GetUserWorkWindows(userID int64) ([]models.WorkWindow, error) {
+	rows, err := DB.Query(`SELECT weekday, start_time, end_time
+		FROM user_work_windows
+		WHERE user_id = $1
+		ORDER BY weekday, start_time`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query work windows: %w", err)
+	}
+	defer closeRows(rows)
+
+	var windows []models.WorkWindow
+	for rows.Next() {
+		var w models.WorkWindow
+		if err := rows.Scan(&w.Weekday, &w.Start, &w.End); err != nil {
+			return nil, fmt.Errorf("failed to scan work window: %w", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// SetUserWorkWindows replaces the windows of the given weekdays with the same set of windows.
+// Passing no windows removes the template for those weekdays (they fall back to work_start/work_end).
+func SetUserWorkWindows(userID int64, weekdays []int, windows []models.WorkWindow) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	if _, err := tx.Exec(`DELETE FROM user_work_windows WHERE user_id = $1 AND weekday = ANY($2)`, userID, pq.Array(weekdays)); err != nil {
+		return fmt.Errorf("failed to clear work windows: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO user_work_windows (user_id, weekday, start_time, end_time) VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare work windows insert: %w", err)
+	}
+	defer closeStmt(stmt)
+
+	for _, weekday := range weekdays {
+		for _, w := range windows {
+			if _, err := stmt.Exec(userID, weekday, w.Start, w.End); err != nil {
+				return fmt.Errorf("failed to insert work window: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ClearUserWorkWindows removes the whole weekly template.
+func ClearUserWorkWindows(userID int64) error {
+	if _, err := DB.Exec(`DELETE FROM user_work_windows WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear work windows: %w", err)
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
+
 	"github.com/adkhorst/planbot/models"
 )
 
@@ -106,3 +108,37 @@ func SaveImportedCalendarLink(userID int64, googleEventID string, taskID int64,
 	}
 	return nil
 }
+
+// GetPlanBotEventsFrom returns exported PlanBot events that start at or after from.
+func GetPlanBotEventsFrom(userID int64, from time.Time) ([]models.GoogleCalendarEvent, error) {
+	rows, err := DB.Query(`SELECT google_event_id, COALESCE(task_id, 0), start_time, end_time
+		FROM google_calendar_events
+		WHERE user_id = $1 AND source = 'planbot' AND start_time >= $2
+		ORDER BY start_time`, userID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query planbot events: %w", err)
+	}
+	defer closeRows(rows)
+
+	var events []models.GoogleCalendarEvent
+	for rows.Next() {
+		ev := models.GoogleCalendarEvent{UserID: userID, Source: "planbot"}
+		if err := rows.Scan(&ev.GoogleEventID, &ev.TaskID, &ev.StartTime, &ev.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan planbot event: %w", err)
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// DeleteGoogleCalendarEventRecords removes stored metadata of the given events.
+func DeleteGoogleCalendarEventRecords(userID int64, eventIDs []string) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+	_, err := DB.Exec(`DELETE FROM google_calendar_events WHERE user_id = $1 AND google_event_id = ANY($2)`, userID, pq.Array(eventIDs))
+	if err != nil {
+		return fmt.Errorf("failed to delete google event records: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// GetUserCapacityOverrides returns the user's per-date capacity caps ordered by start date.
+func GetUserCapacityOverrides(userID int64) ([]models.CapacityOverride, error) {
+	rows, err := DB.Query(`SELECT id, user_id, start_date, end_date, hours, reason
+		FROM user_capacity_overrides
+		WHERE user_id = $1
+		ORDER BY start_date, end_date`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capacity overrides: %w", err)
+	}
+	defer closeRows(rows)
+
+	var overrides []models.CapacityOverride
+	for rows.Next() {
+		var o models.CapacityOverride
+		var reason sql.NullString
+		if err := rows.Scan(&o.ID, &o.UserID, &o.StartDate, &o.EndDate, &o.Hours, &reason); err != nil {
+			return nil, fmt.Errorf("failed to scan capacity override: %w", err)
+		}
+		o.Reason = reason.String
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SetUserCapacityOverride stores a cap for a date range (replacing one for the same range) and returns its ID.
+func SetUserCapacityOverride(userID int64, o models.CapacityOverride) (int64, error) {
+	var id int64
+	err := DB.QueryRow(`INSERT INTO user_capacity_overrides (user_id, start_date, end_date, hours, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, start_date, end_date) DO UPDATE SET hours = EXCLUDED.hours, reason = EXCLUDED.reason
+		RETURNING id`,
+		userID, o.StartDate, o.EndDate, o.Hours, o.Reason).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set capacity override: %w", err)
+	}
+	return id, nil
+}
+
+// DeleteUserCapacityOverride removes one cap; it reports false when the entry does not belong to the user.
+func DeleteUserCapacityOverride(userID, overrideID int64) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM user_capacity_overrides WHERE id = $1 AND user_id = $2`, overrideID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete capacity override: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete capacity override: %w", err)
+	}
+	return n > 0, nil
+}
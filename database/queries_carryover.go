@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// GetMissedWork returns, per open flexible task, the blocks planned before today that the user
+// has not been asked about yet, with the time logged for the task since the first such day.
+func GetMissedWork(userID int64, today time.Time) ([]models.MissedWork, error) {
+	query := `WITH past AS (
+			SELECT ts.task_id, MIN(ts.scheduled_date) AS first_day, MAX(ts.scheduled_date) AS last_day,
+			       SUM(ts.hours_allocated) AS planned
+			FROM task_schedules ts
+			JOIN tasks t ON t.id = ts.task_id
+			WHERE t.user_id = $1 AND ts.scheduled_date < $2 AND NOT ts.missed_prompted
+			  AND t.status NOT IN ('completed', 'cancelled') AND t.pinned_start IS NULL
+			GROUP BY ts.task_id
+		)
+		SELECT p.task_id, t.title, p.first_day, p.last_day, p.planned,
+		       COALESCE((SELECT SUM(te.hours) FROM time_entries te
+		                 WHERE te.task_id = p.task_id AND te.hours IS NOT NULL
+		                   AND te.started_at >= p.first_day AND te.started_at < $2), 0)
+		FROM past p
+		JOIN tasks t ON t.id = p.task_id
+		ORDER BY p.first_day, p.task_id`
+
+	rows, err := DB.Query(query, userID, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query missed work: %w", err)
+	}
+	defer closeRows(rows)
+
+	var missed []models.MissedWork
+	for rows.Next() {
+		var m models.MissedWork
+		if err := rows.Scan(&m.TaskID, &m.Title, &m.FirstDay, &m.LastDay, &m.Planned, &m.Logged); err != nil {
+			return nil, fmt.Errorf("failed to scan missed work: %w", err)
+		}
+		missed = append(missed, m)
+	}
+	return missed, rows.Err()
+}
+
+// MarkMissedWorkPrompted flags the tasks' blocks before today as already asked about.
+func MarkMissedWorkPrompted(taskIDs []int64, today time.Time) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	_, err := DB.Exec(`UPDATE task_schedules SET missed_prompted = TRUE WHERE task_id = ANY($1) AND scheduled_date < $2`,
+		pq.Array(taskIDs), today)
+	if err != nil {
+		return fmt.Errorf("failed to mark missed work: %w", err)
+	}
+	return nil
+}
+
+// PlannedTaskHours returns the hours of all blocks still planned for the task, past ones included.
+func PlannedTaskHours(taskID int64) (float64, error) {
+	var hours float64
+	err := DB.QueryRow(`SELECT COALESCE(SUM(hours_allocated), 0) FROM task_schedules WHERE task_id = $1`, taskID).Scan(&hours)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum planned task hours: %w", err)
+	}
+	return hours, nil
+}
+
+// HasMissedWorkPrompt reports whether the prompt about the task's blocks from firstDay to lastDay
+// is still unanswered.
+func HasMissedWorkPrompt(taskID int64, firstDay, lastDay time.Time) (bool, error) {
+	var exists bool
+	err := DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM task_schedules
+		WHERE task_id = $1 AND scheduled_date BETWEEN $2 AND $3 AND missed_prompted)`,
+		taskID, firstDay, lastDay).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check missed work prompt: %w", err)
+	}
+	return exists, nil
+}
+
+// AnswerMissedWork takes the answer to the prompt about the task's blocks from firstDay to lastDay
+// once: in one transaction it removes the prompted blocks of that range and logs the hours done.
+// It reports false when the prompt was already answered; blocks of a newer prompt are kept.
+func AnswerMissedWork(userID, taskID int64, firstDay, lastDay time.Time, done float64) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	res, err := tx.Exec(`DELETE FROM task_schedules
+		WHERE task_id = $1 AND scheduled_date BETWEEN $2 AND $3 AND missed_prompted`,
+		taskID, firstDay, lastDay)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim missed work answer: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim missed work answer: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	if done > 0 {
+		endedAt := time.Now()
+		startedAt := endedAt.Add(-time.Duration(done * float64(time.Hour)))
+		if _, err := tx.Exec(`INSERT INTO time_entries (user_id, task_id, started_at, ended_at, hours, source)
+			VALUES ($1, $2, $3, $4, $5, 'log')`, userID, taskID, startedAt, endedAt, done); err != nil {
+			return false, fmt.Errorf("failed to log time: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
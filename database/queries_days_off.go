@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// GetUserDaysOff returns the user's vacations, holidays and days off ordered by start date.
+func GetUserDaysOff(userID int64) ([]models.DayOff, error) {
+	rows, err := DB.Query(`SELECT id, user_id, start_date, end_date, reason, source
+		FROM user_days_off
+		WHERE user_id = $1
+		ORDER BY start_date, end_date`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query days off: %w", err)
+	}
+	defer closeRows(rows)
+
+	var days []models.DayOff
+	for rows.Next() {
+		var d models.DayOff
+		var reason sql.NullString
+		if err := rows.Scan(&d.ID, &d.UserID, &d.StartDate, &d.EndDate, &reason, &d.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan day off: %w", err)
+		}
+		d.Reason = reason.String
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// AddUserDayOff stores a day-off range and returns its ID.
+func AddUserDayOff(userID int64, day models.DayOff) (int64, error) {
+	source := day.Source
+	if source == "" {
+		source = "manual"
+	}
+
+	var id int64
+	err := DB.QueryRow(`INSERT INTO user_days_off (user_id, start_date, end_date, reason, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, start_date, end_date) DO UPDATE SET reason = EXCLUDED.reason, source = EXCLUDED.source
+		RETURNING id`,
+		userID, day.StartDate, day.EndDate, day.Reason, source).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add day off: %w", err)
+	}
+	return id, nil
+}
+
+// AddUserHolidays inserts holiday dates, skipping dates the user already has, and returns how many were added.
+func AddUserHolidays(userID int64, days []models.DayOff) (int, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	stmt, err := tx.Prepare(`INSERT INTO user_days_off (user_id, start_date, end_date, reason, source)
+		VALUES ($1, $2, $3, $4, 'holiday')
+		ON CONFLICT (user_id, start_date, end_date) DO NOTHING`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare holidays insert: %w", err)
+	}
+	defer closeStmt(stmt)
+
+	added := 0
+	for _, d := range days {
+		res, err := stmt.Exec(userID, d.StartDate, d.EndDate, d.Reason)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert holiday: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			added += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return added, nil
+}
+
+// DeleteUserDayOff removes one day-off entry; it reports false when the entry does not belong to the user.
+func DeleteUserDayOff(userID, dayOffID int64) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM user_days_off WHERE id = $1 AND user_id = $2`, dayOffID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete day off: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete day off: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteUserDaysOffBySource removes all entries of a source (e.g. every loaded "holiday").
+func DeleteUserDaysOffBySource(userID int64, source string) (int64, error) {
+	res, err := DB.Exec(`DELETE FROM user_days_off WHERE user_id = $1 AND source = $2`, userID, source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete days off: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete days off: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// AddTaskDependency records that taskID cannot start before dependsOnID is finished, unless
+// createsCycle reports that the user's current links plus this one would form a cycle; then it
+// returns false and changes nothing. The check and the insert run in one transaction that holds
+// the user's row, so concurrent calls for the same user cannot create a cycle between them.
+func AddTaskDependency(userID, taskID, dependsOnID int64, createsCycle func(deps map[int64][]int64) bool) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return false, fmt.Errorf("failed to lock user dependencies: %w", err)
+	}
+	deps, err := userTaskDependencies(tx, userID)
+	if err != nil {
+		return false, err
+	}
+	if createsCycle(deps) {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`INSERT INTO task_dependencies (task_id, depends_on_id)
+		VALUES ($1, $2)
+		ON CONFLICT (task_id, depends_on_id) DO NOTHING`, taskID, dependsOnID)
+	if err != nil {
+		return false, fmt.Errorf("failed to add task dependency: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// RemoveTaskDependency deletes a blocked-by link between two tasks.
+func RemoveTaskDependency(taskID, dependsOnID int64) error {
+	_, err := DB.Exec(`DELETE FROM task_dependencies WHERE task_id = $1 AND depends_on_id = $2`, taskID, dependsOnID)
+	if err != nil {
+		return fmt.Errorf("failed to remove task dependency: %w", err)
+	}
+	return nil
+}
+
+// GetUserTaskDependencies returns task ID -> predecessor IDs for all of the user's tasks.
+func GetUserTaskDependencies(userID int64) (map[int64][]int64, error) {
+	return userTaskDependencies(DB, userID)
+}
+
+// querier is what userTaskDependencies needs from *sql.DB or *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func userTaskDependencies(q querier, userID int64) (map[int64][]int64, error) {
+	rows, err := q.Query(`SELECT d.task_id, d.depends_on_id
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE t.user_id = $1
+		ORDER BY d.task_id, d.depends_on_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task dependencies: %w", err)
+	}
+	defer closeRows(rows)
+
+	deps := make(map[int64][]int64)
+	for rows.Next() {
+		var taskID, dependsOnID int64
+		if err := rows.Scan(&taskID, &dependsOnID); err != nil {
+			return nil, fmt.Errorf("failed to scan task dependency: %w", err)
+		}
+		deps[taskID] = append(deps[taskID], dependsOnID)
+	}
+	return deps, rows.Err()
+}
+
+// attachTaskDependencies fills Task.DependsOn for tasks belonging to the user.
+func attachTaskDependencies(userID int64, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	deps, err := GetUserTaskDependencies(userID)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].DependsOn = deps[tasks[i].ID]
+	}
+	return nil
+}
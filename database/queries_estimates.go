@@ -0,0 +1,33 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// GetEstimateSamples returns completed tasks with logged time, oldest completion first.
+func GetEstimateSamples(userID int64) ([]models.EstimateSample, error) {
+	rows, err := DB.Query(`SELECT t.id, t.priority, t.hours_required, SUM(te.hours), t.completed_at
+		FROM tasks t
+		JOIN time_entries te ON te.task_id = t.id
+		WHERE t.user_id = $1 AND t.status = 'completed' AND t.completed_at IS NOT NULL AND t.hours_required > 0
+		  AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_id = t.id)
+		GROUP BY t.id
+		HAVING SUM(te.hours) > 0
+		ORDER BY t.completed_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query estimate samples: %w", err)
+	}
+	defer closeRows(rows)
+
+	var samples []models.EstimateSample
+	for rows.Next() {
+		var s models.EstimateSample
+		if err := rows.Scan(&s.TaskID, &s.Priority, &s.Estimated, &s.Actual, &s.CompletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan estimate sample: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// GetUserProjectBudgets returns the user's weekly project budgets ordered by project.
+func GetUserProjectBudgets(userID int64) ([]models.ProjectBudget, error) {
+	rows, err := DB.Query(`SELECT id, user_id, project, weekly_hours
+		FROM user_project_budgets
+		WHERE user_id = $1
+		ORDER BY project`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project budgets: %w", err)
+	}
+	defer closeRows(rows)
+
+	var budgets []models.ProjectBudget
+	for rows.Next() {
+		var b models.ProjectBudget
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Project, &b.WeeklyHours); err != nil {
+			return nil, fmt.Errorf("failed to scan project budget: %w", err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// SetUserProjectBudget stores the weekly budget of a project, replacing the previous one.
+func SetUserProjectBudget(userID int64, project string, weeklyHours float64) error {
+	_, err := DB.Exec(`INSERT INTO user_project_budgets (user_id, project, weekly_hours)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, project) DO UPDATE SET weekly_hours = EXCLUDED.weekly_hours`,
+		userID, project, weeklyHours)
+	if err != nil {
+		return fmt.Errorf("failed to set project budget: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserProjectBudget removes a project's budget; it reports false when there was none.
+func DeleteUserProjectBudget(userID int64, project string) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM user_project_budgets WHERE user_id = $1 AND project = $2`, userID, project)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete project budget: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete project budget: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetUserProjectTimePrefs returns the user's preferred times per project ordered by project.
+func GetUserProjectTimePrefs(userID int64) ([]models.ProjectTimePref, error) {
+	rows, err := DB.Query(`SELECT id, user_id, project, time_preference
+		FROM user_project_time_prefs
+		WHERE user_id = $1
+		ORDER BY project`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project time preferences: %w", err)
+	}
+	defer closeRows(rows)
+
+	var prefs []models.ProjectTimePref
+	for rows.Next() {
+		var p models.ProjectTimePref
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Project, &p.TimePreference); err != nil {
+			return nil, fmt.Errorf("failed to scan project time preference: %w", err)
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, rows.Err()
+}
+
+// SetUserProjectTimePref stores the preferred time of a project, replacing the previous one.
+func SetUserProjectTimePref(userID int64, project, timePreference string) error {
+	_, err := DB.Exec(`INSERT INTO user_project_time_prefs (user_id, project, time_preference)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, project) DO UPDATE SET time_preference = EXCLUDED.time_preference`,
+		userID, project, timePreference)
+	if err != nil {
+		return fmt.Errorf("failed to set project time preference: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserProjectTimePref removes a project's preferred time; it reports false when there was none.
+func DeleteUserProjectTimePref(userID int64, project string) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM user_project_time_prefs WHERE user_id = $1 AND project = $2`, userID, project)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete project time preference: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete project time preference: %w", err)
+	}
+	return n > 0, nil
+}
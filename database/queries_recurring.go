@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+const recurringColumns = `id, user_id, title, description, hours_required, priority, rrule, materialized_through, created_at, updated_at`
+
+func scanRecurringTask(row rowScanner) (*models.RecurringTask, error) {
+	rt := &models.RecurringTask{}
+	var desc sql.NullString
+	err := row.Scan(
+		&rt.ID,
+		&rt.UserID,
+		&rt.Title,
+		&desc,
+		&rt.HoursRequired,
+		&rt.Priority,
+		&rt.RRule,
+		&rt.MaterializedThru,
+		&rt.CreatedAt,
+		&rt.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rt.Description = desc.String
+	return rt, nil
+}
+
+// CreateRecurringTask stores a new recurring task template.
+func CreateRecurringTask(rt *models.RecurringTask) error {
+	query := `INSERT INTO recurring_tasks (user_id, title, description, hours_required, priority, rrule)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, created_at, updated_at`
+
+	err := DB.QueryRow(query, rt.UserID, rt.Title, rt.Description, rt.HoursRequired, rt.Priority, rt.RRule).
+		Scan(&rt.ID, &rt.CreatedAt, &rt.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create recurring task: %w", err)
+	}
+	return nil
+}
+
+// GetRecurringTasks returns all recurring task templates of the user.
+func GetRecurringTasks(userID int64) ([]models.RecurringTask, error) {
+	rows, err := DB.Query(`SELECT `+recurringColumns+` FROM recurring_tasks WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recurring tasks: %w", err)
+	}
+	defer closeRows(rows)
+
+	var templates []models.RecurringTask
+	for rows.Next() {
+		rt, err := scanRecurringTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recurring task: %w", err)
+		}
+		templates = append(templates, *rt)
+	}
+	return templates, rows.Err()
+}
+
+// GetRecurringTaskForUser returns a template if it belongs to the user.
+func GetRecurringTaskForUser(id, userID int64) (*models.RecurringTask, error) {
+	rt, err := scanRecurringTask(DB.QueryRow(`SELECT `+recurringColumns+` FROM recurring_tasks WHERE id = $1 AND user_id = $2`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring task: %w", err)
+	}
+	return rt, nil
+}
+
+// MaterializeRecurringTask creates one task per occurrence date (deadline = that date) and
+// remembers through which date the template has been materialized. Existing occurrences are kept.
+func MaterializeRecurringTask(rt *models.RecurringTask, dates []time.Time, through time.Time) (int, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	stmt, err := tx.Prepare(`INSERT INTO tasks (user_id, title, description, hours_required, priority, deadline, recurring_id, occurrence_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (recurring_id, occurrence_date) DO NOTHING`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare recurring instance insert: %w", err)
+	}
+	defer closeStmt(stmt)
+
+	created := 0
+	for _, d := range dates {
+		res, err := stmt.Exec(rt.UserID, rt.Title, rt.Description, rt.HoursRequired, rt.Priority, d, rt.ID, d)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert recurring instance: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			created += int(n)
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE recurring_tasks SET materialized_through = $1 WHERE id = $2`, through, rt.ID); err != nil {
+		return 0, fmt.Errorf("failed to update recurring task: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+// GetRecurringInstanceID returns the task materialized for a template's occurrence, or 0.
+func GetRecurringInstanceID(recurringID int64, occurrence time.Time) (int64, error) {
+	var id int64
+	err := DB.QueryRow(`SELECT id FROM tasks WHERE recurring_id = $1 AND occurrence_date = $2`, recurringID, occurrence).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recurring instance: %w", err)
+	}
+	return id, nil
+}
+
+// untouchedInstance matches recurring instances nobody has started: not begun and without time
+// logged or a running timer. Only those are removed when a rule changes or a series is deleted;
+// work already under way stays.
+const untouchedInstance = `status IN ('pending', 'scheduled')
+	AND NOT EXISTS (SELECT 1 FROM time_entries te WHERE te.task_id = tasks.id)`
+
+// UpdateRecurringTask saves the template and applies it to uncompleted instances from fromDate on.
+// When the rule changed the untouched instances are removed and will be materialized again;
+// instances already in progress are kept as they are.
+func UpdateRecurringTask(rt *models.RecurringTask, fromDate time.Time, ruleChanged bool) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	if _, err := tx.Exec(`UPDATE recurring_tasks
+		SET title = $1, description = $2, hours_required = $3, priority = $4, rrule = $5, updated_at = NOW()
+		WHERE id = $6 AND user_id = $7`,
+		rt.Title, rt.Description, rt.HoursRequired, rt.Priority, rt.RRule, rt.ID, rt.UserID); err != nil {
+		return fmt.Errorf("failed to update recurring task: %w", err)
+	}
+
+	if ruleChanged {
+		if _, err := tx.Exec(`DELETE FROM tasks
+			WHERE recurring_id = $1 AND occurrence_date >= $2 AND `+untouchedInstance,
+			rt.ID, fromDate); err != nil {
+			return fmt.Errorf("failed to delete recurring instances: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE recurring_tasks SET materialized_through = $1 WHERE id = $2`,
+			fromDate.AddDate(0, 0, -1), rt.ID); err != nil {
+			return fmt.Errorf("failed to reset recurring task: %w", err)
+		}
+	} else {
+		if _, err := tx.Exec(`UPDATE tasks
+			SET title = $1, description = $2, hours_required = $3, priority = $4, updated_at = NOW()
+			WHERE recurring_id = $5 AND occurrence_date >= $6 AND status NOT IN ('completed', 'cancelled')`,
+			rt.Title, rt.Description, rt.HoursRequired, rt.Priority, rt.ID, fromDate); err != nil {
+			return fmt.Errorf("failed to update recurring instances: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecurringTask removes the template and its untouched instances from fromDate on.
+// Completed and started instances stay with recurring_id set to NULL.
+func DeleteRecurringTask(id, userID int64, fromDate time.Time) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	if _, err := tx.Exec(`DELETE FROM tasks
+		WHERE recurring_id = $1 AND user_id = $2 AND occurrence_date >= $3 AND `+untouchedInstance,
+		id, userID, fromDate); err != nil {
+		return fmt.Errorf("failed to delete recurring instances: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM recurring_tasks WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		return fmt.Errorf("failed to delete recurring task: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
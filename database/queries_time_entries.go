@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+const timeEntryColumns = `id, user_id, task_id, started_at, ended_at, COALESCE(hours, 0), source`
+
+func scanTimeEntry(row rowScanner) (*models.TimeEntry, error) {
+	e := &models.TimeEntry{}
+	if err := row.Scan(&e.ID, &e.UserID, &e.TaskID, &e.StartedAt, &e.EndedAt, &e.Hours, &e.Source); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// LogTime records hours already spent on a task, ending now.
+func LogTime(userID, taskID int64, hours float64) error {
+	endedAt := time.Now()
+	startedAt := endedAt.Add(-time.Duration(hours * float64(time.Hour)))
+
+	_, err := DB.Exec(`INSERT INTO time_entries (user_id, task_id, started_at, ended_at, hours, source)
+		VALUES ($1, $2, $3, $4, $5, 'log')`, userID, taskID, startedAt, endedAt, hours)
+	if err != nil {
+		return fmt.Errorf("failed to log time: %w", err)
+	}
+	return nil
+}
+
+// StartTimer starts a timer on the task. A timer already running for the user is stopped first
+// and returned; nil means there was none.
+func StartTimer(userID, taskID int64) (*models.TimeEntry, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	stopped, err := stopTimer(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO time_entries (user_id, task_id, started_at, source)
+		VALUES ($1, $2, NOW(), 'timer')`, userID, taskID); err != nil {
+		return nil, fmt.Errorf("failed to start timer: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE tasks SET status = 'in_progress', updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'scheduled')`, taskID); err != nil {
+		return nil, fmt.Errorf("failed to update task status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stopped, nil
+}
+
+// StopTimer stops the user's running timer and returns it, or nil when no timer is running.
+func StopTimer(userID int64) (*models.TimeEntry, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackTx(tx)
+
+	stopped, err := stopTimer(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stopped, nil
+}
+
+func stopTimer(tx *sql.Tx, userID int64) (*models.TimeEntry, error) {
+	e, err := scanTimeEntry(tx.QueryRow(`UPDATE time_entries
+		SET ended_at = NOW(), hours = ROUND(EXTRACT(EPOCH FROM (NOW() - started_at)) / 3600.0, 2)
+		WHERE user_id = $1 AND ended_at IS NULL
+		RETURNING `+timeEntryColumns, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop timer: %w", err)
+	}
+	return e, nil
+}
+
+// GetRunningTimer returns the user's running timer, or nil.
+func GetRunningTimer(userID int64) (*models.TimeEntry, error) {
+	e, err := scanTimeEntry(DB.QueryRow(`SELECT `+timeEntryColumns+`
+		FROM time_entries WHERE user_id = $1 AND ended_at IS NULL`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running timer: %w", err)
+	}
+	return e, nil
+}
+
+// RunningTimerHours returns how long the user's timer on the task has been running, rounded like
+// StopTimer rounds it, or 0 when no timer runs on the task. Nothing is changed.
+func RunningTimerHours(userID, taskID int64) (float64, error) {
+	var hours float64
+	err := DB.QueryRow(`SELECT ROUND(EXTRACT(EPOCH FROM (NOW() - started_at)) / 3600.0, 2)
+		FROM time_entries WHERE user_id = $1 AND task_id = $2 AND ended_at IS NULL`, userID, taskID).Scan(&hours)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get running timer hours: %w", err)
+	}
+	return hours, nil
+}
@@ -10,6 +10,37 @@ CREATE TABLE IF NOT EXISTS users (
     work_end VARCHAR(5) DEFAULT '18:00',
     daily_capacity DECIMAL(5,2) DEFAULT 8.0, -- hours per day
     work_days INTEGER[] DEFAULT ARRAY[1,2,3,4,5], -- 1=Monday, 7=Sunday
+    inflate_estimates BOOLEAN NOT NULL DEFAULT FALSE, -- scale estimates by the learned bias
+    min_chunk_minutes INTEGER NOT NULL DEFAULT 0, -- default shortest work session, 0 = any
+    max_task_hours_per_day DECIMAL(5,2) NOT NULL DEFAULT 0, -- default per-task daily cap, 0 = none
+    scheduling_strategy VARCHAR(20) NOT NULL DEFAULT 'greedy', -- greedy, asap, balanced or optimal
+    break_every_minutes INTEGER NOT NULL DEFAULT 0, -- work minutes between short breaks, 0 = none
+    break_minutes INTEGER NOT NULL DEFAULT 0, -- length of a short break
+    lunch_start VARCHAR(5) NOT NULL DEFAULT '', -- fixed lunch window, '' = none
+    lunch_end VARCHAR(5) NOT NULL DEFAULT '',
+    export_breaks BOOLEAN NOT NULL DEFAULT FALSE, -- export breaks to Google Calendar
+    buffer_before_minutes INTEGER NOT NULL DEFAULT 0, -- kept free before a calendar meeting
+    buffer_after_minutes INTEGER NOT NULL DEFAULT 0, -- kept free after a calendar meeting
+    travel_minutes INTEGER NOT NULL DEFAULT 0, -- travel time around meetings held at a place
+    cluster_projects BOOLEAN NOT NULL DEFAULT FALSE, -- lay out a day's tasks of one project back to back
+    max_tasks_per_day INTEGER NOT NULL DEFAULT 0, -- distinct flexible tasks per day, 0 = no limit
+    energy_curve TEXT NOT NULL DEFAULT '', -- energy by time of day, e.g. 'high 09:00-12:00 low after 16:00'
+    max_high_energy_hours DECIMAL(5,2) NOT NULL DEFAULT 0, -- cap on high-energy task hours per day, 0 = none
+    priority_aging_days INTEGER NOT NULL DEFAULT 0, -- +1 priority per N days a task without a deadline waits, 0 = off
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Recurring task templates (materialized into tasks within the planning horizon)
+CREATE TABLE IF NOT EXISTS recurring_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(500) NOT NULL,
+    description TEXT,
+    hours_required DECIMAL(5,2) NOT NULL,
+    priority INTEGER DEFAULT 0,
+    rrule TEXT NOT NULL, -- FREQ=WEEKLY;BYDAY=FR;DTSTART=20261016
+    materialized_through DATE, -- last date already turned into tasks rows
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -26,7 +57,19 @@ CREATE TABLE IF NOT EXISTS tasks (
     deadline TIMESTAMP, -- hard deadline
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    completed_at TIMESTAMP
+    completed_at TIMESTAMP,
+    recurring_id BIGINT REFERENCES recurring_tasks(id) ON DELETE SET NULL, -- template of a recurring instance
+    occurrence_date DATE, -- occurrence of the template this row was created for
+    min_chunk_minutes INTEGER NOT NULL DEFAULT 0, -- shortest work session, 0 = user default
+    max_hours_per_day DECIMAL(5,2) NOT NULL DEFAULT 0, -- daily cap for this task, 0 = user default
+    pinned_start TIMESTAMP, -- fixed-time appointment: exact start (user's wall clock)
+    pinned_end TIMESTAMP, -- exact end; set together with pinned_start
+    start_after TIMESTAMP, -- earliest start (user's wall clock), NULL = any time
+    time_preference TEXT NOT NULL DEFAULT '', -- preferred time of day, e.g. "after 14:00 not mon"; '' = any
+    project VARCHAR(64) NOT NULL DEFAULT '', -- lower-case project tag without "#", '' = none
+    parent_id BIGINT REFERENCES tasks(id) ON DELETE CASCADE, -- parent of a subtask, NULL = top-level task
+    position INTEGER NOT NULL DEFAULT 0, -- order among the parent's subtasks, from 1
+    energy VARCHAR(10) NOT NULL DEFAULT '' -- high, medium or low; '' = medium
 );
 
 -- Task schedules table (tracks when tasks are scheduled)
@@ -35,6 +78,7 @@ CREATE TABLE IF NOT EXISTS task_schedules (
     task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
     scheduled_date DATE NOT NULL, -- which day
     hours_allocated DECIMAL(5,2) NOT NULL, -- how many hours on this day
+    missed_prompted BOOLEAN NOT NULL DEFAULT FALSE, -- past block: the user was asked whether it was done
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -58,6 +102,80 @@ CREATE TABLE IF NOT EXISTS google_calendar_events (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Task dependencies (task_id is blocked by depends_on_id)
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    depends_on_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, depends_on_id),
+    CHECK (task_id <> depends_on_id)
+);
+
+-- Weekly availability template (several work windows per weekday)
+CREATE TABLE IF NOT EXISTS user_work_windows (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7), -- 1=Monday, 7=Sunday
+    start_time VARCHAR(5) NOT NULL, -- HH:MM
+    end_time VARCHAR(5) NOT NULL -- HH:MM
+);
+
+-- Vacations, public holidays and single days off (inclusive date ranges)
+CREATE TABLE IF NOT EXISTS user_days_off (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason VARCHAR(255),
+    source VARCHAR(20) NOT NULL DEFAULT 'manual', -- manual | holiday
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_date >= start_date)
+);
+
+-- Per-date capacity caps (/capacity), e.g. conference days
+CREATE TABLE IF NOT EXISTS user_capacity_overrides (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    hours DECIMAL(5,2) NOT NULL, -- cap on task hours per day in the range
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_date >= start_date),
+    CHECK (hours >= 0)
+);
+
+-- Weekly hour budgets per project (/budget)
+CREATE TABLE IF NOT EXISTS user_project_budgets (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project VARCHAR(64) NOT NULL, -- tasks.project
+    weekly_hours DECIMAL(5,2) NOT NULL, -- cap on the project's task hours per Monday–Sunday week
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (weekly_hours > 0)
+);
+
+-- Preferred time of day per project (/prefer); a task's own time_preference wins
+CREATE TABLE IF NOT EXISTS user_project_time_prefs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project VARCHAR(64) NOT NULL, -- tasks.project
+    time_preference TEXT NOT NULL, -- same format as tasks.time_preference
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Time spent on tasks: manual /log entries and /start–/stop timers
+CREATE TABLE IF NOT EXISTS time_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP, -- NULL while the timer is running
+    hours DECIMAL(5,2), -- NULL while the timer is running
+    source VARCHAR(20) NOT NULL DEFAULT 'log', -- log | timer
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
 CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id);
@@ -66,4 +184,16 @@ CREATE INDEX IF NOT EXISTS idx_tasks_deadline ON tasks(deadline);
 CREATE INDEX IF NOT EXISTS idx_task_schedules_task_id ON task_schedules(task_id);
 CREATE INDEX IF NOT EXISTS idx_task_schedules_date ON task_schedules(scheduled_date);
 CREATE INDEX IF NOT EXISTS idx_google_calendar_events_user_id ON google_calendar_events(user_id);
-CREATE UNIQUE INDEX IF NOT EXISTS idx_google_calendar_events_user_event ON google_calendar_events(user_id, google_event_id);
\ No newline at end of file
+CREATE UNIQUE INDEX IF NOT EXISTS idx_google_calendar_events_user_event ON google_calendar_events(user_id, google_event_id);
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_depends_on ON task_dependencies(depends_on_id);
+CREATE INDEX IF NOT EXISTS idx_user_work_windows_user_id ON user_work_windows(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_days_off_user_range ON user_days_off(user_id, start_date, end_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_capacity_overrides_user_range ON user_capacity_overrides(user_id, start_date, end_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_project_budgets_user_project ON user_project_budgets(user_id, project);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_project_time_prefs_user_project ON user_project_time_prefs(user_id, project);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_project ON tasks(user_id, project);
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks(parent_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_tasks_user_id ON recurring_tasks(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurring_occurrence ON tasks(recurring_id, occurrence_date);
+CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries(task_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
@@ -8,6 +8,7 @@ import (
 	"google.golang.org/api/calendar/v3"
 
 	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
 )
 
 // ExportSlotAllocations creates timed calendar events and returns saved event metadata.
@@ -31,14 +32,29 @@ func (c *Client) ExportSlotAllocations(ctx context.Context, calendarID string, u
 		if summary == "" {
 			summary = fmt.Sprintf("Задача #%d", alloc.TaskID)
 		}
-		if !strings.HasPrefix(summary, "☐ ") && !strings.HasPrefix(summary, "✅ ") {
-			summary = "☐ " + summary
+		private := map[string]string{
+			"planbot": "1",
+			"task_id": fmt.Sprintf("%d", alloc.TaskID),
 		}
 
 		duration := alloc.End.Sub(alloc.Start).Hours()
 		description := fmt.Sprintf("PlanBot\nДлительность: %.1f ч\nПриоритет: %d", duration, alloc.Priority)
+		if alloc.Break {
+			// Breaks are not tasks: no checkbox and no task_id to tick off
+			description = "PlanBot\nПерерыв между блоками работы"
+			private = map[string]string{"planbot": "1", "break": "1"}
+		} else if !strings.HasPrefix(summary, "☐ ") && !strings.HasPrefix(summary, "✅ ") {
+			summary = "☐ " + summary
+		}
 		if alloc.Deadline != nil {
-			description += fmt.Sprintf("\nДедлайн: %s", alloc.Deadline.Format("02.01.2006"))
+			layout := "02.01.2006"
+			if scheduler.HasDeadlineTime(*alloc.Deadline) {
+				layout = "02.01.2006 15:04"
+			}
+			description += fmt.Sprintf("\nДедлайн: %s", alloc.Deadline.Format(layout))
+		}
+		if alloc.Pinned {
+			description += "\n📌 Фиксированное время"
 		}
 
 		ev := &calendar.Event{
@@ -53,10 +69,7 @@ func (c *Client) ExportSlotAllocations(ctx context.Context, calendarID string, u
 				TimeZone: tz,
 			},
 			ExtendedProperties: &calendar.EventExtendedProperties{
-				Private: map[string]string{
-					"planbot": "1",
-					"task_id": fmt.Sprintf("%d", alloc.TaskID),
-				},
+				Private: private,
 			},
 		}
 
@@ -9,6 +9,7 @@ import (
 	"google.golang.org/api/calendar/v3"
 
 	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
 )
 
 // FetchBusyIntervals returns occupied time from Google Calendar.
@@ -89,11 +90,12 @@ func eventToBusyInterval(ev *calendar.Event, defaultLoc *time.Location, user *mo
 	}
 
 	return models.BusyInterval{
-		Start:   start,
-		End:     end,
-		Summary: summary,
-		Source:  "calendar",
-		AllDay:  false,
+		Start:    start,
+		End:      end,
+		Summary:  summary,
+		Source:   "calendar",
+		AllDay:   false,
+		Location: ev.Location,
 	}, true
 }
 
@@ -121,18 +123,9 @@ func parseEventDateTime(dt *calendar.EventDateTime, defaultLoc *time.Location) (
 }
 
 func allDayBusyInterval(user *models.User, day time.Time, summary string) models.BusyInterval {
-	workStart := user.WorkStart
-	workEnd := user.WorkEnd
-	if workStart == "" {
-		workStart = "09:00"
-	}
-	if workEnd == "" {
-		workEnd = "18:00"
-	}
-	loc := day.Location()
-	startClock, err1 := time.ParseInLocation("15:04", workStart, loc)
-	endClock, err2 := time.ParseInLocation("15:04", workEnd, loc)
-	if err1 != nil || err2 != nil || !endClock.After(startClock) {
+	// Cover the whole working part of the day (all windows of the weekly template).
+	periods := scheduler.WorkPeriodsOn(user, day)
+	if len(periods) == 0 {
 		return models.BusyInterval{
 			Start:   day,
 			End:     day.AddDate(0, 0, 1),
@@ -141,11 +134,9 @@ func allDayBusyInterval(user *models.User, day time.Time, summary string) models
 			AllDay:  true,
 		}
 	}
-	start := time.Date(day.Year(), day.Month(), day.Day(), startClock.Hour(), startClock.Minute(), 0, 0, loc)
-	end := time.Date(day.Year(), day.Month(), day.Day(), endClock.Hour(), endClock.Minute(), 0, 0, loc)
 	return models.BusyInterval{
-		Start:   start,
-		End:     end,
+		Start:   periods[0].Start,
+		End:     periods[len(periods)-1].End,
 		Summary: summary,
 		Source:  "calendar",
 		AllDay:  true,
@@ -94,6 +94,19 @@ func TestEventToBusyInterval_TimedEvent(t *testing.T) {
 	}
 }
 
+func TestEventToBusyInterval_KeepsLocation(t *testing.T) {
+	ev := &calendar.Event{
+		Summary:  "Клиент",
+		Location: "Тверская 1",
+		Start:    &calendar.EventDateTime{DateTime: "2025-06-02T10:00:00Z"},
+		End:      &calendar.EventDateTime{DateTime: "2025-06-02T11:00:00Z"},
+	}
+	interval, ok := eventToBusyInterval(ev, time.UTC, &models.User{})
+	if !ok || interval.Location != "Тверская 1" {
+		t.Errorf("expected the location kept, got %+v", interval)
+	}
+}
+
 func TestAllDayBusyInterval_UsesWorkHours(t *testing.T) {
 	loc := time.UTC
 	day := time.Date(2025, 6, 2, 0, 0, 0, 0, loc)
@@ -108,6 +121,24 @@ func TestAllDayBusyInterval_UsesWorkHours(t *testing.T) {
 	}
 }
 
+func TestAllDayBusyInterval_SpansAllWindows(t *testing.T) {
+	loc := time.UTC
+	day := time.Date(2025, 6, 6, 0, 0, 0, 0, loc) // Friday
+	user := &models.User{
+		WorkStart: "09:00",
+		WorkEnd:   "18:00",
+		WorkWindows: []models.WorkWindow{
+			{Weekday: 5, Start: "14:00", End: "16:00"},
+			{Weekday: 5, Start: "10:00", End: "12:00"},
+		},
+	}
+
+	interval := allDayBusyInterval(user, day, "Offsite")
+	if interval.Start.Hour() != 10 || interval.End.Hour() != 16 {
+		t.Errorf("expected Friday windows 10-16, got %v-%v", interval.Start, interval.End)
+	}
+}
+
 func TestEventTimeRange_AllDay(t *testing.T) {
 	loc := time.UTC
 	ev := &calendar.Event{
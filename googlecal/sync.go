@@ -49,6 +49,21 @@ func (c *Client) DeleteStoredEvents(ctx context.Context, calendarID string, user
 		return err
 	}
 
+	c.deleteEvents(ctx, calendarID, userID, eventIDs)
+	return database.ClearGoogleCalendarEvents(userID)
+}
+
+// ReplaceEvents deletes the given PlanBot events and exports allocations in their place;
+// all other events stay as they are.
+func ReplaceEvents(ctx context.Context, c *Client, user *models.User, staleEventIDs []string, allocations []models.SlotAllocation) error {
+	c.deleteEvents(ctx, calendarIDPrimary, user.ID, staleEventIDs)
+	if err := database.DeleteGoogleCalendarEventRecords(user.ID, staleEventIDs); err != nil {
+		return err
+	}
+	return AppendScheduleEvents(ctx, c, user, allocations)
+}
+
+func (c *Client) deleteEvents(ctx context.Context, calendarID string, userID int64, eventIDs []string) {
 	for _, eventID := range eventIDs {
 		if err := c.svc.Events.Delete(calendarID, eventID).Context(ctx).Do(); err != nil {
 			if apiErr, ok := err.(*googleapi.Error); ok && apiErr.Code == 410 {
@@ -57,8 +72,6 @@ func (c *Client) DeleteStoredEvents(ctx context.Context, calendarID string, user
 			log.Printf("googlecal: delete event %s for user %d: %v", eventID, userID, err)
 		}
 	}
-
-	return database.ClearGoogleCalendarEvents(userID)
 }
 
 // TrySyncUserSchedule runs calendar sync when Google is connected; logs errors without failing scheduling.
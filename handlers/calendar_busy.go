@@ -50,7 +50,8 @@ func (h *BotHandler) fetchCalendarBusy(user *models.User, startDate time.Time, f
 	if apiBusy, err := client.FetchBusyIntervals(ctx, "primary", user, startDate, end, forRebuild); err != nil {
 		log.Printf("calendar busy: fetch: %v", err)
 	} else {
-		parts = append(parts, apiBusy)
+		// Buffers and travel time apply to real meetings, not to stored PlanBot blocks
+		parts = append(parts, scheduler.PadBusyIntervals(user, apiBusy))
 	}
 
 	// Stored PlanBot exports are only used when inserting into an existing plan.
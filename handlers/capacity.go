@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+const capacityUsage = `Формат: /capacity ДАТА[..ДАТА] ЧАСЫ [причина]
+Примеры:
+/capacity 2026-10-20 3 Конференция
+/capacity 20.10.2026..22.10.2026 2
+
+/capacity — список ограничений
+/capacity remove ID — удалить`
+
+// handleCapacity handles /capacity: list, cap task hours on a date or range, remove a cap.
+func (h *BotHandler) handleCapacity(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		h.sendMessage(msg.Chat.ID, formatCapacityOverrides(user))
+		return
+	}
+	if strings.EqualFold(fields[0], "remove") {
+		h.handleCapacityRemove(msg.Chat.ID, user, fields[1:])
+		return
+	}
+
+	if len(fields) < 2 {
+		h.sendMessage(msg.Chat.ID, capacityUsage)
+		return
+	}
+	start, end, err := parseDateRange(fields[0])
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, capacityUsage)
+		return
+	}
+	hours, err := strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", "."), 64)
+	if err != nil || hours < 0 || hours > 24 {
+		h.sendMessage(msg.Chat.ID, "Часы — число от 0 до 24.\n\n"+capacityUsage)
+		return
+	}
+	reason := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(args, fields[0])), fields[1]))
+
+	override := models.CapacityOverride{StartDate: start, EndDate: end, Hours: hours, Reason: reason}
+	if _, err := database.SetUserCapacityOverride(user.ID, override); err != nil {
+		log.Printf("Error saving capacity override: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при сохранении ограничения")
+		return
+	}
+	fresh, err := database.GetUserCapacityOverrides(user.ID)
+	if err != nil {
+		log.Printf("Error loading capacity overrides: %v", err)
+	} else {
+		user.CapacityOverrides = fresh
+	}
+
+	response := fmt.Sprintf("📉 %s: не больше %g ч задач в день", formatDateRange(start, end), hours)
+	if reason != "" {
+		response += " — " + reason
+	}
+
+	schedules, err := database.GetScheduleForDateRange(user.ID, start, end)
+	if err != nil {
+		log.Printf("Error checking schedules for capacity override: %v", err)
+		h.sendMessage(msg.Chat.ID, response)
+		return
+	}
+	if over := hoursOverCapacity(user, schedules); over > 1e-9 {
+		response += fmt.Sprintf("\n\n⚠️ На эти дни уже запланировано на %.1f ч больше лимита. Перепланировать расписание?", over)
+		h.offerRebuild(msg.Chat.ID, response)
+		return
+	}
+	h.sendMessage(msg.Chat.ID, response)
+}
+
+func (h *BotHandler) handleCapacityRemove(chatID int64, user *models.User, args []string) {
+	if len(args) != 1 {
+		h.sendMessage(chatID, "Формат: /capacity remove ID (ID из списка /capacity)")
+		return
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		h.sendMessage(chatID, "Неверный ID")
+		return
+	}
+
+	removed, err := database.DeleteUserCapacityOverride(user.ID, id)
+	if err != nil {
+		log.Printf("Error deleting capacity override: %v", err)
+		h.sendMessage(chatID, "Ошибка при удалении ограничения")
+		return
+	}
+	if !removed {
+		h.sendMessage(chatID, "Ограничение не найдено")
+		return
+	}
+	h.sendMessage(chatID, "🗑 Ограничение удалено. Чтобы снова использовать эти дни полностью, выполните /schedule.")
+}
+
+// hoursOverCapacity sums how far the saved plan exceeds each day's capacity.
+func hoursOverCapacity(user *models.User, schedules []models.DaySchedule) float64 {
+	var over float64
+	for _, day := range schedules {
+		if excess := day.TotalHours - scheduler.DailyCapacityOn(user, day.Date); excess > 0 {
+			over += excess
+		}
+	}
+	return over
+}
+
+func formatCapacityOverrides(user *models.User) string {
+	if len(user.CapacityOverrides) == 0 {
+		return fmt.Sprintf("Ограничений по датам нет: каждый рабочий день — до %.1f ч задач.\n\n%s", user.DailyCapacity, capacityUsage)
+	}
+
+	response := "📉 Ограничения нагрузки по датам:\n\n"
+	for _, o := range user.CapacityOverrides {
+		response += fmt.Sprintf("ID:%d | %s — %g ч", o.ID, formatDateRange(o.StartDate, o.EndDate), o.Hours)
+		if o.Reason != "" {
+			response += " — " + o.Reason
+		}
+		response += "\n"
+	}
+	return response + "\nУдалить: /capacity remove ID"
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+// handleMissedPartial answers "🌓 Частично" on a missed-work prompt with a choice of how much was done.
+func (h *BotHandler) handleMissedPartial(chatID int64, user *models.User, answer missedAnswer) {
+	task, err := database.GetTaskByIDForUser(answer.taskID, user.ID)
+	if err != nil || task == nil {
+		h.sendMessage(chatID, "Задача не найдена.")
+		return
+	}
+	pending, err := database.HasMissedWorkPrompt(task.ID, answer.firstDay, answer.lastDay)
+	if err != nil {
+		log.Printf("Error checking missed work prompt: %v", err)
+		h.sendMessage(chatID, "Ошибка чтения расписания.")
+		return
+	}
+	if !pending {
+		h.sendMessage(chatID, missedAnsweredText)
+		return
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	key := answer.firstDay.Format(models.MissedDayLayout) + ":" + answer.lastDay.Format(models.MissedDayLayout)
+	for _, done := range partialHours(answer.hours) {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%g ч", done), fmt.Sprintf("missed_did:%d:%g:%s", task.ID, done, key)))
+	}
+	if len(row) == 0 {
+		h.sendMessage(chatID, "Слишком мало времени, чтобы делить. Выберите «Сделал» или «Не сделал».")
+		return
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	h.sendMessageWithReplyMarkup(chatID, fmt.Sprintf("Сколько из %g ч по «%s» сделано?", answer.hours, task.Title), &keyboard)
+}
+
+const missedAnsweredText = "Ответ по этой работе уже учтён. Отметить время вручную: /log ID часы."
+
+// handleMissedDone records the answer to a missed-work prompt: logs the hours done together with
+// dropping the prompt's blocks and inserts what is still missing from the plan into free slots.
+// When the rest no longer fits before the deadline, it offers a rebuild instead. Only the first
+// answer to a prompt counts: a repeated tap or a tap on an old message is rejected.
+func (h *BotHandler) handleMissedDone(chatID int64, user *models.User, answer missedAnswer) {
+	task, err := database.GetTaskByIDForUser(answer.taskID, user.ID)
+	if err != nil || task == nil {
+		h.sendMessage(chatID, "Задача не найдена.")
+		return
+	}
+	if task.Status == "completed" || task.Status == "cancelled" {
+		h.sendMessage(chatID, "Задача уже закрыта.")
+		return
+	}
+	task = rolledUpTask(task, user.ID)
+
+	done := answer.hours
+	claimed, err := database.AnswerMissedWork(user.ID, task.ID, answer.firstDay, answer.lastDay, done)
+	if err != nil {
+		log.Printf("Error answering missed work: %v", err)
+		h.sendMessage(chatID, "Ошибка обновления расписания.")
+		return
+	}
+	if !claimed {
+		h.sendMessage(chatID, missedAnsweredText)
+		return
+	}
+	task.HoursSpent += done
+
+	// Blocks of other prompts that are not answered yet still hold their hours, so only what no
+	// block of the task covers is carried over.
+	planned, err := database.PlannedTaskHours(task.ID)
+	if err != nil {
+		log.Printf("Error loading planned hours: %v", err)
+		h.sendMessage(chatID, "Ошибка чтения текущего расписания.")
+		return
+	}
+	rest := scheduler.RemainingHours(task) - planned
+	response := ""
+	if done > 0 {
+		response = fmt.Sprintf("⏱ Записано %g ч: %s\n%s\n\n", done, task.Title, formatTaskProgressLine(task))
+	}
+	if rest < 0.05 {
+		h.sendMessage(chatID, response+"✅ Переносить нечего: оставшееся уже есть в плане.")
+		return
+	}
+
+	startDate := scheduleStartDate(user)
+	existing, err := database.GetAllUserSchedulesFrom(user.ID, startDate)
+	if err != nil {
+		log.Printf("Error loading existing schedules: %v", err)
+		h.sendMessage(chatID, "Ошибка чтения текущего расписания.")
+		return
+	}
+	carry := *task
+	carry.HoursRequired = carry.HoursSpent + rest
+	busy := h.fetchCalendarBusy(user, startDate, false)
+	newDays, ok := scheduler.ScheduleTaskIntoExisting(user, &carry, existing, startDate, busy)
+	if !ok || len(newDays) == 0 {
+		h.offerRebuild(chatID, response+fmt.Sprintf("⚠️ Несделанные %.1f ч по «%s» не помещаются в текущее расписание — дедлайн под угрозой.\n\nПерепланировать?", rest, task.Title))
+		return
+	}
+
+	if err := database.SaveTaskSchedules(newDays); err != nil {
+		log.Printf("Error saving carried-over schedule: %v", err)
+		h.sendMessage(chatID, "Ошибка сохранения расписания.")
+		return
+	}
+
+	var dates []string
+	for _, day := range newDays {
+		dates = append(dates, day.Date.Format("02.01"))
+	}
+	response += fmt.Sprintf("↪️ Перенесено %.1f ч по «%s»: %s", rest, task.Title, strings.Join(dates, ", "))
+
+	allocations := scheduler.PlanTimeAllocations(user, newDays, startDate, busy)
+	if _, failed, detail := h.syncGoogleCalendarAppend(user, allocations); failed {
+		response += "\n⚠️ Не удалось обновить Google Calendar."
+		if detail != "" {
+			response += " Причина: " + detail
+		}
+	}
+	h.sendMessage(chatID, response)
+}
+
+// missedAnswer is a tap on a missed-work button: the task, the hours and the prompt's day range.
+type missedAnswer struct {
+	taskID            int64
+	hours             float64
+	firstDay, lastDay time.Time
+}
+
+// parseMissedCallback parses "<prefix><taskID>:<hours>:<first day>:<last day>" from a missed-work button.
+func parseMissedCallback(data, prefix string) (missedAnswer, error) {
+	parts := strings.Split(strings.TrimPrefix(data, prefix), ":")
+	if len(parts) != 4 {
+		return missedAnswer{}, fmt.Errorf("expected task, hours and prompt days")
+	}
+	taskID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return missedAnswer{}, err
+	}
+	hours, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || hours < 0 || math.IsNaN(hours) || math.IsInf(hours, 0) {
+		return missedAnswer{}, fmt.Errorf("invalid hours %q", parts[1])
+	}
+	firstDay, err := time.Parse(models.MissedDayLayout, parts[2])
+	if err != nil {
+		return missedAnswer{}, err
+	}
+	lastDay, err := time.Parse(models.MissedDayLayout, parts[3])
+	if err != nil || lastDay.Before(firstDay) {
+		return missedAnswer{}, fmt.Errorf("invalid prompt days %q–%q", parts[2], parts[3])
+	}
+	return missedAnswer{taskID: taskID, hours: hours, firstDay: firstDay, lastDay: lastDay}, nil
+}
+
+// partialHours offers a quarter, a half and three quarters of hours, rounded to 15 minutes,
+// without repeats and without zero or the whole.
+func partialHours(hours float64) []float64 {
+	var out []float64
+	for _, part := range []float64{0.25, 0.5, 0.75} {
+		done := math.Round(hours*part*4) / 4
+		if done <= 0 || done >= hours || (len(out) > 0 && done == out[len(out)-1]) {
+			continue
+		}
+		out = append(out, done)
+	}
+	return out
+}
+
+// plannedHoursOf sums the hours of the task across the day plans.
+func plannedHoursOf(taskID int64, days []models.DaySchedule) float64 {
+	var total float64
+	for _, day := range days {
+		for _, info := range day.Tasks {
+			if info.TaskID == taskID {
+				total += info.HoursAllocated
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/holidays"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+// handleDayOff handles /dayoff: list, add a date or range, remove, load national holidays.
+func (h *BotHandler) handleDayOff(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		h.sendMessage(msg.Chat.ID, formatDaysOff(user.DaysOff))
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "remove":
+		h.handleDayOffRemove(msg.Chat.ID, user, fields[1:])
+		return
+	case "holidays":
+		h.handleDayOffHolidays(msg.Chat.ID, user, fields[1:])
+		return
+	}
+
+	start, end, err := parseDateRange(fields[0])
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, dayOffUsage)
+		return
+	}
+	reason := strings.TrimSpace(strings.TrimPrefix(args, fields[0]))
+
+	if _, err := database.AddUserDayOff(user.ID, models.DayOff{StartDate: start, EndDate: end, Reason: reason, Source: "manual"}); err != nil {
+		log.Printf("Error adding day off: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при сохранении выходного")
+		return
+	}
+
+	response := fmt.Sprintf("🏖 Добавлено: %s", formatDateRange(start, end))
+	if reason != "" {
+		response += " — " + reason
+	}
+	h.promptRebuildOnCollision(msg.Chat.ID, user, start, end, response)
+}
+
+const dayOffUsage = `Формат: /dayoff ДАТА[..ДАТА] [причина]
+Примеры:
+/dayoff 2026-12-24..2027-01-08 Отпуск
+/dayoff 20.10.2026 Отгул
+
+/dayoff — список выходных
+/dayoff remove ID — удалить
+/dayoff holidays RU — загрузить государственные праздники
+/dayoff holidays off — удалить загруженные праздники`
+
+func (h *BotHandler) handleDayOffRemove(chatID int64, user *models.User, args []string) {
+	if len(args) != 1 {
+		h.sendMessage(chatID, "Формат: /dayoff remove ID (ID из списка /dayoff)")
+		return
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		h.sendMessage(chatID, "Неверный ID")
+		return
+	}
+
+	removed, err := database.DeleteUserDayOff(user.ID, id)
+	if err != nil {
+		log.Printf("Error deleting day off: %v", err)
+		h.sendMessage(chatID, "Ошибка при удалении выходного")
+		return
+	}
+	if !removed {
+		h.sendMessage(chatID, "Выходной не найден")
+		return
+	}
+	h.sendMessage(chatID, "🗑 Выходной удалён. Чтобы вернуть этот день в расписание, выполните /schedule.")
+}
+
+// handleDayOffHolidays loads the bundled holiday list for this and next year, or removes loaded holidays.
+func (h *BotHandler) handleDayOffHolidays(chatID int64, user *models.User, args []string) {
+	countries := strings.Join(holidays.Countries(), ", ")
+	if len(args) != 1 {
+		h.sendMessage(chatID, fmt.Sprintf("Формат: /dayoff holidays СТРАНА\nДоступно: %s\nУдалить загруженные праздники: /dayoff holidays off", countries))
+		return
+	}
+
+	if strings.EqualFold(args[0], "off") {
+		n, err := database.DeleteUserDaysOffBySource(user.ID, "holiday")
+		if err != nil {
+			log.Printf("Error deleting holidays: %v", err)
+			h.sendMessage(chatID, "Ошибка при удалении праздников")
+			return
+		}
+		h.sendMessage(chatID, fmt.Sprintf("🗑 Удалено праздничных дней: %d", n))
+		return
+	}
+
+	now := time.Now()
+	if user.TimeZone != "" {
+		if loc, err := time.LoadLocation(user.TimeZone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	days, err := holidays.Load(args[0], now.Year(), now.Year()+1)
+	if err != nil || len(days) == 0 {
+		h.sendMessage(chatID, fmt.Sprintf("Нет списка праздников для %q. Доступно: %s", strings.ToUpper(args[0]), countries))
+		return
+	}
+
+	added, err := database.AddUserHolidays(user.ID, days)
+	if err != nil {
+		log.Printf("Error adding holidays: %v", err)
+		h.sendMessage(chatID, "Ошибка при сохранении праздников")
+		return
+	}
+
+	response := fmt.Sprintf("🎉 Праздники %s на %d–%d: добавлено дней — %d", strings.ToUpper(args[0]), now.Year(), now.Year()+1, added)
+	h.promptRebuildOnCollision(chatID, user, days[0].StartDate, days[len(days)-1].EndDate, response)
+}
+
+// promptRebuildOnCollision sends the confirmation and, when saved task_schedules already use
+// newly blocked days, offers to rebuild the plan.
+func (h *BotHandler) promptRebuildOnCollision(chatID int64, user *models.User, start, end time.Time, response string) {
+	fresh, err := database.GetUserDaysOff(user.ID)
+	if err != nil {
+		log.Printf("Error loading days off: %v", err)
+		h.sendMessage(chatID, response)
+		return
+	}
+	user.DaysOff = fresh
+
+	schedules, err := database.GetScheduleForDateRange(user.ID, start, end)
+	if err != nil {
+		log.Printf("Error checking schedules for days off: %v", err)
+		h.sendMessage(chatID, response)
+		return
+	}
+
+	affected := make(map[int64]bool)
+	var hours float64
+	for _, day := range schedules {
+		if !scheduler.IsDayOff(user, day.Date) {
+			continue
+		}
+		for _, t := range day.Tasks {
+			affected[t.TaskID] = true
+			hours += t.HoursAllocated
+		}
+	}
+	if len(affected) == 0 {
+		h.sendMessage(chatID, response)
+		return
+	}
+
+	response += fmt.Sprintf("\n\n⚠️ На эти дни уже запланировано %.1f ч (задач: %d). Перепланировать расписание?", hours, len(affected))
+	h.offerRebuild(chatID, response)
+}
+
+// offerRebuild sends the response with the rebuild buttons (stable, full, later).
+func (h *BotHandler) offerRebuild(chatID int64, response string) {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧷 Перепланировать бережно", "plan_stable:0"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Перепланировать всё", "plan_rebuild:0"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⏭ Позже", "plan_skip:0"),
+		),
+	)
+	h.sendMessageWithReplyMarkup(chatID, response, &keyboard)
+}
+
+// parseDateRange parses "ДАТА" or "ДАТА..ДАТА" (any format accepted by parseDate).
+func parseDateRange(s string) (start, end time.Time, err error) {
+	from, to, isRange := strings.Cut(s, "..")
+	start, err = parseDate(strings.TrimSpace(from))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end = start
+	if isRange {
+		end, err = parseDate(strings.TrimSpace(to))
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("range end %s is before start %s", to, from)
+	}
+	return start, end, nil
+}
+
+func formatDateRange(start, end time.Time) string {
+	if start.Equal(end) {
+		return start.Format("02.01.2006")
+	}
+	return fmt.Sprintf("%s – %s", start.Format("02.01.2006"), end.Format("02.01.2006"))
+}
+
+func formatDaysOff(days []models.DayOff) string {
+	if len(days) == 0 {
+		return "Выходных и отпусков нет.\n\n" + dayOffUsage
+	}
+
+	response := "🏖 Выходные, отпуска и праздники:\n\n"
+	for _, d := range days {
+		icon := "🏖"
+		if d.Source == "holiday" {
+			icon = "🎉"
+		}
+		response += fmt.Sprintf("%s ID:%d | %s", icon, d.ID, formatDateRange(d.StartDate, d.EndDate))
+		if d.Reason != "" {
+			response += " — " + d.Reason
+		}
+		response += "\n"
+	}
+	return response + "\nУдалить: /dayoff remove ID"
+}
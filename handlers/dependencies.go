@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+// handleDepends handles /depends ID ID_ПРЕДШЕСТВЕННИКА (task is blocked by predecessor).
+// With a single ID it lists the task's predecessors.
+func (h *BotHandler) handleDepends(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	ids, err := parseTaskIDs(msg.CommandArguments())
+	if err != nil || len(ids) == 0 || len(ids) > 2 {
+		h.sendMessage(msg.Chat.ID, "Формат: /depends ID ID_ПРЕДШЕСТВЕННИКА\nПример: /depends 12 7 — задача 12 начнётся только после задачи 7\n\nСписок зависимостей: /depends ID\nУдалить: /undepend ID ID_ПРЕДШЕСТВЕННИКА")
+		return
+	}
+
+	task, err := database.GetTaskByIDForUser(ids[0], user.ID)
+	if err != nil || task == nil {
+		h.sendMessage(msg.Chat.ID, "Задача не найдена")
+		return
+	}
+
+	if len(ids) == 1 {
+		h.sendMessage(msg.Chat.ID, h.formatTaskDependencies(user.ID, task))
+		return
+	}
+
+	predecessor, err := database.GetTaskByIDForUser(ids[1], user.ID)
+	if err != nil || predecessor == nil {
+		h.sendMessage(msg.Chat.ID, "Задача-предшественник не найдена")
+		return
+	}
+
+	added, err := database.AddTaskDependency(user.ID, task.ID, predecessor.ID, func(deps map[int64][]int64) bool {
+		return scheduler.WouldCreateCycle(deps, task.ID, predecessor.ID)
+	})
+	if err != nil {
+		log.Printf("Error adding dependency: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при сохранении зависимости")
+		return
+	}
+	if !added {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🔁 Нельзя: задача «%s» уже (прямо или косвенно) зависит от «%s».\nЗависимости не должны образовывать цикл.", predecessor.Title, task.Title))
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("🔗 Готово: «%s» начнётся только после «%s».\nЧтобы применить к расписанию, выполните /schedule.", task.Title, predecessor.Title))
+}
+
+// handleUndepend handles /undepend ID ID_ПРЕДШЕСТВЕННИКА
+func (h *BotHandler) handleUndepend(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	ids, err := parseTaskIDs(msg.CommandArguments())
+	if err != nil || len(ids) != 2 {
+		h.sendMessage(msg.Chat.ID, "Формат: /undepend ID ID_ПРЕДШЕСТВЕННИКА")
+		return
+	}
+
+	task, err := database.GetTaskByIDForUser(ids[0], user.ID)
+	if err != nil || task == nil {
+		h.sendMessage(msg.Chat.ID, "Задача не найдена")
+		return
+	}
+
+	if err := database.RemoveTaskDependency(task.ID, ids[1]); err != nil {
+		log.Printf("Error removing dependency: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при удалении зависимости")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, "🔓 Зависимость удалена")
+}
+
+func (h *BotHandler) formatTaskDependencies(userID int64, task *models.Task) string {
+	if len(task.DependsOn) == 0 {
+		return fmt.Sprintf("Задача «%s» ни от чего не зависит.", task.Title)
+	}
+
+	response := fmt.Sprintf("🔗 «%s» ждёт завершения:\n", task.Title)
+	for _, id := range task.DependsOn {
+		pred, err := database.GetTaskByIDForUser(id, userID)
+		if err != nil || pred == nil {
+			continue
+		}
+		response += fmt.Sprintf("%s ID:%d | %s\n", getStatusEmoji(pred.Status), pred.ID, pred.Title)
+	}
+	return response
+}
+
+func parseTaskIDs(args string) ([]int64, error) {
+	fields := strings.Fields(args)
+	ids := make([]int64, 0, len(fields))
+	for _, f := range fields {
+		id, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func formatDependsOn(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(parts, ", ")
+}
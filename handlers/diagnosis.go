@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// maxTakenShown limits how many occupying tasks a diagnosis names.
+const maxTakenShown = 3
+
+// formatDiagnosis renders why an unscheduled task did not fit and what would make it fit.
+func formatDiagnosis(title string, d models.Diagnosis) string {
+	lines := []string{fmt.Sprintf("🔍 «%s»: нужно %.1f ч до %s, свободно %.1f ч",
+		title, d.NeededHours, formatDateTime(d.WindowEnd), d.FreeHours)}
+
+	used := fmt.Sprintf("   Рабочего времени в окне %.1f ч", d.WindowHours)
+	if d.TakenHours > 1e-9 {
+		used += fmt.Sprintf("; задачи выше по очереди — %.1f ч", d.TakenHours)
+		if names := formatTaskHoursList(d.TakenBy, maxTakenShown); names != "" {
+			used += " (" + names + ")"
+		}
+	}
+	if d.BusyHours > 1e-9 {
+		used += fmt.Sprintf("; события календаря — %.1f ч", d.BusyHours)
+	}
+	lines = append(lines, used)
+
+	if d.FreeHours >= d.NeededHours-1e-9 {
+		lines = append(lines, "   Времени хватает, но свободные отрезки короче блока задачи или упираются в лимит в день — уменьшите chunk/maxday через /edittask")
+		return strings.Join(lines, "\n")
+	}
+	if d.BudgetHours > 0 {
+		lines = append(lines, fmt.Sprintf("   📊 Лимит проекта %g ч в неделю исчерпан — поднимите его через /budget", d.BudgetHours))
+	}
+	if d.ExtraPerDay > 0 {
+		lines = append(lines, fmt.Sprintf("   💡 +%g ч в день до срока (/settings)", d.ExtraPerDay))
+	}
+	if d.FitDeadline != nil {
+		lines = append(lines, fmt.Sprintf("   💡 поместится с дедлайном %s", formatDateTime(*d.FitDeadline)))
+	}
+	if len(d.YieldTasks) > 0 {
+		lines = append(lines, "   💡 понизьте приоритет: "+formatTaskHoursList(d.YieldTasks, len(d.YieldTasks)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTaskHoursList renders up to limit tasks as «Title» (ID:n, h ч).
+func formatTaskHoursList(tasks []models.TaskHours, limit int) string {
+	var parts []string
+	for i, th := range tasks {
+		if i >= limit {
+			parts = append(parts, fmt.Sprintf("и ещё %d", len(tasks)-limit))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("«%s» (ID:%d, %.1f ч)", th.Title, th.TaskID, th.Hours))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -90,7 +90,7 @@ func handleMyTasks(bot *tgbotapi.BotAPI, chatID, userID int64) {
 	for i, t := range tasks {
 		due := "нет дедлайна"
 		if t.Deadline != nil {
-			due = t.Deadline.Format("02.01.2006")
+			due = formatDateTime(*t.Deadline)
 		}
 		msg += fmt.Sprintf("%d. [%s] %s (до %s)\n", i+1, t.Status, t.Title, due)
 	}
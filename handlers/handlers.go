@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -19,12 +20,13 @@ import (
 
 // BotHandler routes Telegram updates to command handlers.
 type BotHandler struct {
-	bot *tgbotapi.BotAPI
+	bot      *tgbotapi.BotAPI
+	previews *planPreviews // pending /schedule previews awaiting «Применить»
 }
 
 // NewBotHandler creates a new bot handler
 func NewBotHandler(bot *tgbotapi.BotAPI) *BotHandler {
-	return &BotHandler{bot: bot}
+	return &BotHandler{bot: bot, previews: newPlanPreviews()}
 }
 
 // HandleUpdate processes incoming updates
@@ -63,6 +65,8 @@ func (h *BotHandler) handleCommand(msg *tgbotapi.Message) {
 		h.handleAddTask(msg)
 	case "mytasks":
 		h.handleMyTasks(msg)
+	case "subtask":
+		h.handleSubtask(msg)
 	case "schedule":
 		h.handleSchedule(msg)
 	case "today":
@@ -75,8 +79,36 @@ func (h *BotHandler) handleCommand(msg *tgbotapi.Message) {
 		h.handleComplete(msg)
 	case "delete":
 		h.handleDelete(msg)
+	case "depends":
+		h.handleDepends(msg)
+	case "undepend":
+		h.handleUndepend(msg)
+	case "edittask":
+		h.handleEditTask(msg)
+	case "log":
+		h.handleLog(msg)
+	case "stop":
+		h.handleStop(msg)
+	case "stats":
+		h.handleStats(msg)
+	case "addrecurring":
+		h.handleAddRecurring(msg)
+	case "recurring":
+		h.handleRecurring(msg)
+	case "editrecurring":
+		h.handleEditRecurring(msg)
+	case "deleterecurring":
+		h.handleDeleteRecurring(msg)
 	case "settings":
 		h.handleSettings(msg)
+	case "dayoff":
+		h.handleDayOff(msg)
+	case "capacity":
+		h.handleCapacity(msg)
+	case "budget":
+		h.handleBudget(msg)
+	case "prefer":
+		h.handlePrefer(msg)
 	case "timezone":
 		h.handleTimezone(msg)
 	case "google_connect":
@@ -128,6 +160,43 @@ func (h *BotHandler) handleCallback(cb *tgbotapi.CallbackQuery) {
 		}
 		h.sendMessage(chatID, "🔄 Перепланирую все задачи с нуля...")
 		h.executeFullRebuild(chatID, user)
+	case strings.HasPrefix(cb.Data, "plan_stable:"):
+		if _, err := parseCallbackTaskID(cb.Data, "plan_stable:"); err != nil {
+			h.sendMessage(chatID, "Неверный запрос.")
+			return
+		}
+		h.sendMessage(chatID, "🔄 Перепланирую, сохраняя текущее расписание где возможно...")
+		h.executeStableRebuild(chatID, user)
+	case strings.HasPrefix(cb.Data, "plan_apply:"):
+		token, err := parseCallbackTaskID(cb.Data, "plan_apply:")
+		if err != nil {
+			h.sendMessage(chatID, "Неверный запрос.")
+			return
+		}
+		h.applyPreview(chatID, user, token)
+	case strings.HasPrefix(cb.Data, "plan_cancel:"):
+		token, err := parseCallbackTaskID(cb.Data, "plan_cancel:")
+		if err != nil {
+			h.sendMessage(chatID, "Неверный запрос.")
+			return
+		}
+		h.cancelPreview(chatID, user, token)
+	case strings.HasPrefix(cb.Data, "missed_ask:"):
+		answer, err := parseMissedCallback(cb.Data, "missed_ask:")
+		if err != nil {
+			h.sendMessage(chatID, "Неверный запрос.")
+			return
+		}
+		h.clearInlineKeyboard(cb.Message)
+		h.handleMissedPartial(chatID, user, answer)
+	case strings.HasPrefix(cb.Data, "missed_did:"):
+		answer, err := parseMissedCallback(cb.Data, "missed_did:")
+		if err != nil {
+			h.sendMessage(chatID, "Неверный запрос.")
+			return
+		}
+		h.clearInlineKeyboard(cb.Message)
+		h.handleMissedDone(chatID, user, answer)
 	case strings.HasPrefix(cb.Data, "plan_skip:"):
 		h.sendMessage(chatID, "Хорошо. Запланировать позже: /schedule или кнопки после следующей задачи.")
 	default:
@@ -135,8 +204,13 @@ func (h *BotHandler) handleCallback(cb *tgbotapi.CallbackQuery) {
 	}
 }
 
-// handleStart handles /start command
+// handleStart handles /start command; /start ID starts a timer on the task instead.
 func (h *BotHandler) handleStart(msg *tgbotapi.Message) {
+	if taskID, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64); err == nil {
+		h.handleStartTimer(msg, taskID)
+		return
+	}
+
 	user, err := database.GetOrCreateUser(
 		msg.From.ID,
 		msg.From.UserName,
@@ -169,20 +243,55 @@ func (h *BotHandler) handleHelp(msg *tgbotapi.Message) {
 	helpText := `📋 Доступные команды:
 
 /addtask - Добавить новую задачу
-Формат: /addtask Название | часы | приоритет | дедлайн
+Формат: /addtask Название | часы | приоритет | дедлайн | параметры
 Минимум: /addtask Задача | 2
 Примеры:
 /addtask Написать отчёт | 4 | 5 | 25.12.2025
+/addtask Презентация | 3 | 8 | 23.10.2026 12:00
 /addtask Прочитать статью | 1.5 | 3
-
-/mytasks - Показать все задачи
-/schedule - Перепланировать все активные задачи с нуля
+/addtask Архитектура | 6 | 8 | 30.12.2025 | chunk=90 maxday=3
+/addtask Созвон с клиентом | 1 | 7 | | at=16.10.2026 15:00-16:00
+/addtask Вёрстка по макетам | 6 | 6 | 25.10.2026 | after=20.10.2026
+/addtask Статья | 4 | 5 | | pref=утром не пн
+/addtask Макет лендинга #clienta | 3
+
+/edittask [ID] ключ=значение - Изменить задачу (hours, priority, deadline, chunk, maxday, at, after, pref, project, energy)
+
+/addrecurring - Повторяющаяся задача (/addrecurring Отчёт | 2 | weekly пт | 7)
+/recurring - Список повторяющихся задач
+/editrecurring [ID] | ... - Изменить шаблон и будущие повторения
+/deleterecurring [ID] - Удалить шаблон и будущие повторения
+/mytasks [#проект] - Показать все задачи или задачи проекта
+/subtask [ID] - Разбить задачу на подзадачи: по одной на строке «Название | часы»
+/schedule - Перепланировать все активные задачи с нуля (сначала предпросмотр изменений, затем «Применить»)
+/schedule stable - Перепланировать бережно: задачи остаются на своих днях, если ничто не мешает
 /today - Показать расписание на сегодня
 /week - Показать расписание на неделю
 /schedule_slots - Предпросмотр расписания по временным слотам (без записи в БД)
-/complete [ID] - Отметить задачу выполненной (по ID из /mytasks)
+/complete [ID] [часы] - Отметить задачу выполненной (по ID из /mytasks), можно указать фактическое время
 /delete [ID] - Удалить задачу
+/log [ID] [часы] - Записать потраченное время (/log 12 1.5)
+/start [ID] - Запустить таймер по задаче
+/stop - Остановить таймер
+/depends [ID] [ID] - Задача начнётся только после другой (/depends 12 7)
+/undepend [ID] [ID] - Удалить зависимость
 /settings - Настройки (часы в день, рабочие дни)
+/settings hours [дни] [окна] - Рабочие окна по дням недели (/settings hours 5 10:00-15:00)
+/settings chunk [минуты] [часов в день] - Минимальный блок и лимит часов задачи в день по умолчанию
+/settings estimates on|off - Учитывать точность оценок при планировании
+/settings strategy greedy|asap|balanced|optimal - Стратегия: ближе к дедлайну, как можно раньше, равномерно или с перебором
+/settings breaks 90/10|pomodoro|off - Короткие перерывы между блоками работы
+/settings lunch 13:00-14:00|off - Фиксированный обед
+/settings buffer [до] [после] - Свободные минуты до и после встреч из календаря
+/settings travel [минуты] - Время на дорогу к встречам с адресом
+/settings focus [on|off] [N] - Задачи проекта подряд, не больше N задач в день
+/settings energy [кривая|cap N|off] - Часы пика и спада сил, лимит тяжёлой работы в день
+/settings aging [дни|off] - Задачи без дедлайна поднимаются в очереди, пока ждут
+/stats estimates - Точность оценок: факт / оценка
+/dayoff [дата..дата] [причина] - Отпуск или выходной (/dayoff 2026-12-24..2027-01-08 Отпуск)
+/capacity [дата..дата] [часы] [причина] - Лимит часов задач на даты (/capacity 2026-10-20 3 Конференция)
+/budget [#проект] [часы|off] - Лимит часов проекта в неделю (/budget #clienta 10)
+/prefer [#проект] [время|off] - Предпочитаемое время задач проекта (/prefer #clienta после 14:00)
 /timezone [имя_таймзоны] - Установить таймзону (например, Europe/Moscow)
 /google_connect - Подключить Google Calendar (OAuth)
 /google_code [код] - Завершить подключение Google Calendar
@@ -192,6 +301,7 @@ func (h *BotHandler) handleHelp(msg *tgbotapi.Message) {
 💡 Советы:
 • Приоритет: целое число от 1 до 10 (10 = самый важный)
 • Дедлайн необязателен
+• Записанное время (/log, /start–/stop) вычитается из оценки: планируется только остаток
 • После /addtask можно вписать задачу в расписание или перепланировать всё
 • При подключённом Google Calendar учитываются все события в календаре (в т.ч. вручную и от PlanBot)
 • Google Calendar обновляется при планировании (старые события PlanBot заменяются)`
@@ -214,54 +324,12 @@ func (h *BotHandler) handleAddTask(msg *tgbotapi.Message) {
 		return
 	}
 
-	parts := strings.Split(args, "|")
-	if len(parts) < 2 {
-		h.sendMessage(msg.Chat.ID, "❗️ Минимум нужно указать название и количество часов.\nПример: /addtask Задача | 2")
-		return
-	}
-
-	title := strings.TrimSpace(parts[0])
-	hoursStr := strings.TrimSpace(parts[1])
-
-	hours, err := strconv.ParseFloat(hoursStr, 64)
-	if err != nil || hours <= 0 {
-		h.sendMessage(msg.Chat.ID, "⏱ Неверное количество часов.\nУкажите положительное число, например: 0.5, 1, 2.5")
+	task, err := parseTaskArgs(args, models.Task{UserID: user.ID, Priority: 5}, userLocation(user))
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, err.Error())
 		return
 	}
 
-	task := &models.Task{
-		UserID:        user.ID,
-		Title:         title,
-		HoursRequired: hours,
-		Priority:      5, // default priority
-	}
-
-	// Parse priority if provided
-	if len(parts) > 2 {
-		priorityStr := strings.TrimSpace(parts[2])
-		priority, err := strconv.Atoi(priorityStr)
-		if err != nil {
-			h.sendMessage(msg.Chat.ID, "⭐️ Неверный формат приоритета.\nИспользуйте целое число от 1 до 10 (10 = самый важный).")
-			return
-		}
-		if priority < 1 || priority > 10 {
-			h.sendMessage(msg.Chat.ID, "⭐️ Приоритет должен быть от 1 до 10.\nНапример: 3 (низкий), 5 (средний), 8–10 (высокий).")
-			return
-		}
-		task.Priority = priority
-	}
-
-	// Parse deadline if provided
-	if len(parts) > 3 {
-		deadlineStr := strings.TrimSpace(parts[3])
-		deadline, err := parseDate(deadlineStr)
-		if err != nil {
-			h.sendMessage(msg.Chat.ID, "📅 Неверный формат дедлайна.\nДопустимые форматы дат: 25.12.2025, 25.12.25 или 2025-12-25.")
-			return
-		}
-		task.Deadline = &deadline
-	}
-
 	// Save task
 	err = database.CreateTask(task)
 	if err != nil {
@@ -274,7 +342,25 @@ func (h *BotHandler) handleAddTask(msg *tgbotapi.Message) {
 		task.Title, task.HoursRequired, task.Priority)
 
 	if task.Deadline != nil {
-		response += fmt.Sprintf("\n📅 Дедлайн: %s", task.Deadline.Format("02.01.2006"))
+		response += fmt.Sprintf("\n📅 Дедлайн: %s", formatDateTime(*task.Deadline))
+	}
+	if project := formatProject(task); project != "" {
+		response += "\n" + project
+	}
+	if pinned := formatPinned(task); pinned != "" {
+		response += "\n" + pinned
+	}
+	if after := formatStartAfter(task); after != "" {
+		response += "\n" + after
+	}
+	if pref := formatTimePreference(user, task); pref != "" {
+		response += "\n" + pref
+	}
+	if energy := formatEnergy(task); energy != "" {
+		response += "\n" + energy
+	}
+	if opts := formatTaskOptions(task); opts != "" {
+		response += "\n" + opts
 	}
 
 	hasExisting, err := database.UserHasScheduledTasks(user.ID)
@@ -288,6 +374,62 @@ func (h *BotHandler) handleAddTask(msg *tgbotapi.Message) {
 	h.sendMessageWithReplyMarkup(msg.Chat.ID, response, &keyboard)
 }
 
+// parseTaskArgs parses "title | hours | priority | deadline | options" into a new task that starts
+// as a copy of base: the priority and project of base are kept unless the arguments set them.
+// Errors are ready to show to the user.
+func parseTaskArgs(args string, base models.Task, loc *time.Location) (*models.Task, error) {
+	parts := strings.Split(args, "|")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("❗️ Минимум нужно указать название и количество часов.\nПример: /addtask Задача | 2")
+	}
+
+	// "#clienta" in the title sets the project
+	title, project := extractProjectTag(strings.TrimSpace(parts[0]))
+	if title == "" {
+		return nil, fmt.Errorf("❗️ Не указано название задачи.\nПример: /addtask Задача | 2")
+	}
+	hours, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || hours <= 0 {
+		return nil, fmt.Errorf("⏱ Неверное количество часов.\nУкажите положительное число, например: 0.5, 1, 2.5")
+	}
+
+	task := base
+	task.Title = title
+	task.HoursRequired = hours
+	if project != "" {
+		task.Project = project
+	}
+
+	// Parse priority if provided
+	if len(parts) > 2 && strings.TrimSpace(parts[2]) != "" {
+		priority, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil {
+			return nil, fmt.Errorf("⭐️ Неверный формат приоритета.\nИспользуйте целое число от 1 до 10 (10 = самый важный).")
+		}
+		if priority < 1 || priority > 10 {
+			return nil, fmt.Errorf("⭐️ Приоритет должен быть от 1 до 10.\nНапример: 3 (низкий), 5 (средний), 8–10 (высокий).")
+		}
+		task.Priority = priority
+	}
+
+	// Parse deadline if provided
+	if len(parts) > 3 && strings.TrimSpace(parts[3]) != "" {
+		deadline, err := parseDateTime(strings.TrimSpace(parts[3]), loc)
+		if err != nil {
+			return nil, fmt.Errorf("📅 Неверный формат дедлайна.\nДопустимые форматы дат: 25.12.2025, 25.12.25 или 2025-12-25; время — через пробел: 25.12.2025 12:00.")
+		}
+		task.Deadline = &deadline
+	}
+
+	// Parse options if provided: chunk=90 maxday=3 at=16.10.2026 15:00
+	if len(parts) > 4 {
+		if err := applyTaskOptions(&task, strings.Join(parts[4:], " "), loc); err != nil {
+			return nil, fmt.Errorf("❗️ %v\n\n%s", err, taskOptionsUsage)
+		}
+	}
+	return &task, nil
+}
+
 // handleMyTasks handles /mytasks command
 func (h *BotHandler) handleMyTasks(msg *tgbotapi.Message) {
 	user, err := h.getUser(msg.From.ID)
@@ -308,15 +450,76 @@ func (h *BotHandler) handleMyTasks(msg *tgbotapi.Message) {
 		return
 	}
 
-	response := "📋 Ваши задачи:\n\n"
+	// Parents show the hours of their open subtasks; the dependencies shown are the user's own.
+	rolled := scheduler.RollUpSubtasks(tasks)
+	for i := range rolled {
+		rolled[i].DependsOn = tasks[i].DependsOn
+	}
+	tasks = rolled
+
+	header := "📋 Ваши задачи:\n\n"
+	if arg := strings.TrimSpace(msg.CommandArguments()); arg != "" {
+		project, err := parseProjectTag(arg)
+		if err != nil {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("❗️ %v\n\nФормат: /mytasks #проект", err))
+			return
+		}
+		tasks = filterTasksByProject(tasks, project)
+		if len(tasks) == 0 {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("В проекте #%s нет задач. Добавить: /addtask Название #%s | часы", project, project))
+			return
+		}
+		header = fmt.Sprintf("📋 Задачи #%s:\n\n", project)
+	}
+
+	// Only the nearest open instance of each recurring template is listed; the rest are counted.
+	tasks, moreRepeats := collapseRecurringInstances(tasks)
+	tasks = nestSubtasks(tasks)
+	agingAt := scheduleStartDate(user)
+
+	response := header
 	for i := range tasks {
 		task := tasks[i]
 		statusEmoji := getStatusEmoji(task.Status)
-		response += fmt.Sprintf("%s ID:%d | %s\n⏱ %g ч | ⭐️ %d",
-			statusEmoji, task.ID, task.Title, task.HoursRequired, task.Priority)
+		indent := ""
+		if task.ParentID != nil {
+			indent = "↳ "
+		}
+		response += fmt.Sprintf("%s%s ID:%d | %s\n%s | ⭐️ %d",
+			indent, statusEmoji, task.ID, task.Title, formatTaskHours(&task), task.Priority)
 
 		if task.Deadline != nil {
-			response += fmt.Sprintf(" | 📅 %s", task.Deadline.Format("02.01.2006"))
+			response += fmt.Sprintf(" | 📅 %s", formatDateTime(*task.Deadline))
+		}
+		if project := formatProject(&task); project != "" {
+			response += "\n" + project
+		}
+		if pinned := formatPinned(&task); pinned != "" {
+			response += "\n" + pinned
+		}
+		if after := formatStartAfter(&task); after != "" {
+			response += "\n" + after
+		}
+		if pref := formatTimePreference(user, &task); pref != "" {
+			response += "\n" + pref
+		}
+		if energy := formatEnergy(&task); energy != "" {
+			response += "\n" + energy
+		}
+		if aging := formatAging(user, &task, agingAt); aging != "" {
+			response += "\n" + aging
+		}
+		if opts := formatTaskOptions(&task); opts != "" {
+			response += "\n" + opts
+		}
+		if len(task.DependsOn) > 0 {
+			response += fmt.Sprintf("\n🔗 После: %s", formatDependsOn(task.DependsOn))
+		}
+		if task.OpenSubtasks > 0 {
+			response += fmt.Sprintf("\n🧩 Открытых подзадач: %d", task.OpenSubtasks)
+		}
+		if task.RecurringID != nil && moreRepeats[*task.RecurringID] > 0 && task.Status != "completed" && task.Status != "cancelled" {
+			response += fmt.Sprintf("\n🔁 Ещё повторений: %d (/recurring)", moreRepeats[*task.RecurringID])
 		}
 		response += "\n\n"
 	}
@@ -324,7 +527,8 @@ func (h *BotHandler) handleMyTasks(msg *tgbotapi.Message) {
 	h.sendMessage(msg.Chat.ID, response)
 }
 
-// handleSchedule handles /schedule command (full rebuild of all active tasks).
+// handleSchedule handles /schedule (full rebuild of all active tasks) and /schedule stable.
+// Both only preview the new plan; it is saved from the preview's «Применить» button.
 func (h *BotHandler) handleSchedule(msg *tgbotapi.Message) {
 	user, err := h.getUser(msg.From.ID)
 	if err != nil {
@@ -332,8 +536,13 @@ func (h *BotHandler) handleSchedule(msg *tgbotapi.Message) {
 		return
 	}
 
-	h.sendMessage(msg.Chat.ID, "🔄 Перепланирую все задачи с нуля...")
-	h.executeFullRebuild(msg.Chat.ID, user)
+	if strings.EqualFold(strings.TrimSpace(msg.CommandArguments()), "stable") {
+		h.sendMessage(msg.Chat.ID, "🔄 Считаю план, сохраняя текущее расписание где возможно...")
+		h.previewRebuild(msg.Chat.ID, user, true)
+		return
+	}
+	h.sendMessage(msg.Chat.ID, "🔄 Считаю новый план всех задач...")
+	h.previewRebuild(msg.Chat.ID, user, false)
 }
 
 // handleScheduleSlots handles /schedule_slots command (preview slot-based plan, no DB writes)
@@ -350,6 +559,8 @@ func (h *BotHandler) handleScheduleSlots(msg *tgbotapi.Message) {
 		h.sendMessage(msg.Chat.ID, "Ошибка получения задач из базы.\nПопробуйте позже.")
 		return
 	}
+	tasks = append(tasks, previewRecurringTasks(user)...)
+	tasks = h.applyEstimateBias(user, tasks)
 
 	if len(tasks) == 0 {
 		h.sendMessage(msg.Chat.ID, "Нет задач для планирования.\nДобавьте новую задачу через /addtask.")
@@ -381,7 +592,10 @@ func (h *BotHandler) handleScheduleSlots(msg *tgbotapi.Message) {
 			response += fmt.Sprintf("\n... и ещё %d дней", len(planResult.DaySchedules)-maxDays)
 			break
 		}
-		response += formatDayScheduleWithTimes(ds, user.DailyCapacity, timeAllocations)
+		response += formatDayScheduleWithTimes(ds, scheduler.DailyCapacityOn(user, ds.Date), timeAllocations)
+	}
+	if fallbacks := formatPreferenceFallbacks(timeAllocations); fallbacks != "" {
+		response += strings.TrimPrefix(fallbacks, "\n") + "\n"
 	}
 
 	response += "\n❗️ Это предварительный просмотр. Для записи в БД и Google Calendar используйте /schedule."
@@ -417,24 +631,60 @@ func (h *BotHandler) handleComplete(msg *tgbotapi.Message) {
 		return
 	}
 
-	args := msg.CommandArguments()
-	if args == "" {
-		h.sendMessage(msg.Chat.ID, "Укажите ID задачи: /complete [ID]")
+	fields := strings.Fields(msg.CommandArguments())
+	if len(fields) == 0 || len(fields) > 2 {
+		h.sendMessage(msg.Chat.ID, "Укажите ID задачи: /complete [ID]\nС фактическим временем: /complete [ID] [часы]")
 		return
 	}
 
-	taskID, err := strconv.ParseInt(args, 10, 64)
+	taskID, err := strconv.ParseInt(fields[0], 10, 64)
 	if err != nil {
 		h.sendMessage(msg.Chat.ID, "Неверный ID задачи")
 		return
 	}
 
+	actual := -1.0
+	if len(fields) == 2 {
+		actual, err = strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", "."), 64)
+		if err != nil || actual <= 0 {
+			h.sendMessage(msg.Chat.ID, "Неверное фактическое время. Пример: /complete 12 3.5")
+			return
+		}
+	}
+
 	task, err := database.GetTaskByIDForUser(taskID, user.ID)
 	if err != nil || task == nil {
 		h.sendMessage(msg.Chat.ID, "Задача не найдена")
 		return
 	}
 
+	if actual > 0 {
+		// The feedback is the total actual time: it cannot be below what is logged already,
+		// running timer included. Checked before anything is changed.
+		running, err := database.RunningTimerHours(user.ID, taskID)
+		if err != nil {
+			log.Printf("Error reading running timer: %v", err)
+		}
+		if logged := math.Round((task.HoursSpent+running)*100) / 100; actual < logged-1e-9 {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("По задаче уже записано %g ч — фактическое время не может быть меньше.\nУкажите не меньше %g ч или завершите без часов: /complete %d", logged, logged, taskID))
+			return
+		}
+	}
+
+	if h.stopTimerForTask(user.ID, taskID) {
+		if fresh, err := database.GetTaskByIDForUser(taskID, user.ID); err == nil && fresh != nil {
+			task = fresh
+		}
+	}
+	if actual > 0 {
+		// Only what is not recorded yet is logged.
+		if actual > task.HoursSpent {
+			if err := database.LogTime(user.ID, taskID, actual-task.HoursSpent); err != nil {
+				log.Printf("Error logging actual time: %v", err)
+			}
+		}
+		task.HoursSpent = actual
+	}
 	err = database.CompleteTask(taskID)
 	if err != nil {
 		log.Printf("Error completing task: %v", err)
@@ -445,7 +695,16 @@ func (h *BotHandler) handleComplete(msg *tgbotapi.Message) {
 	if err := h.syncTaskCompletionToCalendar(user.ID, taskID); err != nil {
 		log.Printf("sync task completion to calendar: %v", err)
 	}
-	h.sendMessage(msg.Chat.ID, "✅ Задача отмечена как выполненная!")
+	response := "✅ Задача отмечена как выполненная!"
+	closed := h.completeSubtasks(user.ID, taskID)
+	if closed > 0 {
+		response += fmt.Sprintf("\n🧩 Закрыто подзадач: %d", closed)
+	} else if task.HoursSpent > 0 && task.HoursRequired > 0 {
+		// A parent's own estimate is not its work, so only plain tasks report accuracy.
+		response += fmt.Sprintf("\n⏱ Факт %g ч при оценке %g ч (×%.2f)\nТочность оценок: /stats estimates", task.HoursSpent, task.HoursRequired, task.HoursSpent/task.HoursRequired)
+	}
+	response += completeParentIfDone(task, user.ID)
+	h.sendMessage(msg.Chat.ID, response)
 }
 
 // handleDelete handles /delete command
@@ -474,8 +733,17 @@ func (h *BotHandler) handleDelete(msg *tgbotapi.Message) {
 		return
 	}
 
-	if err := h.deleteTaskFromCalendar(user.ID, taskID); err != nil {
-		log.Printf("delete task from calendar: %v", err)
+	// Subtasks go with their parent (ON DELETE CASCADE); their events have to be removed first.
+	family := []models.Task{*task}
+	if task.ParentID == nil {
+		if f, err := database.GetTaskFamily(taskID, user.ID); err == nil {
+			family = f
+		}
+	}
+	for _, t := range family {
+		if err := h.deleteTaskFromCalendar(user.ID, t.ID); err != nil {
+			log.Printf("delete task from calendar: %v", err)
+		}
 	}
 
 	err = database.DeleteTask(taskID)
@@ -485,10 +753,16 @@ func (h *BotHandler) handleDelete(msg *tgbotapi.Message) {
 		return
 	}
 
-	if err := database.DeleteTaskCalendarLinks(user.ID, taskID); err != nil {
-		log.Printf("delete task calendar links: %v", err)
+	for _, t := range family {
+		if err := database.DeleteTaskCalendarLinks(user.ID, t.ID); err != nil {
+			log.Printf("delete task calendar links: %v", err)
+		}
+	}
+	response := "🗑 Задача удалена"
+	if len(family) > 1 {
+		response += fmt.Sprintf(" вместе с подзадачами (%d)", len(family)-1)
 	}
-	h.sendMessage(msg.Chat.ID, "🗑 Задача удалена")
+	h.sendMessage(msg.Chat.ID, response+completeParentIfDone(task, user.ID))
 }
 
 // handleSettings handles /settings command
@@ -515,17 +789,38 @@ func (h *BotHandler) handleSettings(msg *tgbotapi.Message) {
 📅 Рабочие дни: %s
 🕒 Рабочее время: %s-%s
 🌍 Таймзона: %s
-
+🧩 Блоки задач: %s
+🧭 Стратегия: %s
+☕ Перерывы: %s
+🚶 Буферы вокруг встреч: %s
+🎯 Фокус: %s
+⚡ Энергия: %s
+⏫ Старение приоритета: %s
+%s
 Для изменения используйте:
 /settings [часы] | [дни] | [HH:MM-HH:MM]
+/settings hours [дни] [HH:MM-HH:MM,...] — окна по дням недели
+/settings chunk [минуты] [часов в день] — блоки задач по умолчанию
+/settings strategy [greedy|asap|balanced|optimal] — как распределять задачи по дням
+/settings breaks [90/10|pomodoro|off] — короткие перерывы
+/settings lunch [HH:MM-HH:MM|off] — обед
+/settings buffer [до] [после], /settings travel [минуты] — зазоры вокруг встреч
+/settings focus [on|off] [задач в день] — меньше переключений между задачами
+/settings energy [high 09:00-12:00 low after 16:00|cap часы|off] — тяжёлые задачи в часы пика
+/settings aging [дни|off] — +1 к приоритету задачи без дедлайна за каждые N дней ожидания
 Примеры:
 /settings 6 | 1,2,3,4,5
-/settings 6 | 1,2,3,4,5 | 09:00-18:00`, user.DailyCapacity, workDaysStr, user.WorkStart, user.WorkEnd, user.TimeZone)
+/settings 6 | 1,2,3,4,5 | 09:00-18:00
+/settings hours 5 10:00-15:00`, user.DailyCapacity, workDaysStr, user.WorkStart, user.WorkEnd, user.TimeZone, formatChunkDefaults(user), formatStrategy(user), formatBreaks(user), formatBuffers(user), formatFocus(user), formatEnergySettings(user), formatAgingSettings(user), formatSettingsWindows(user.WorkWindows))
 
 		h.sendMessage(msg.Chat.ID, response)
 		return
 	}
 
+	if h.handleSettingsSubcommand(msg.Chat.ID, user, args) {
+		return
+	}
+
 	// Parse new settings
 	parts := strings.Split(args, "|")
 	if len(parts) < 2 || len(parts) > 3 {
@@ -766,6 +1061,19 @@ func (h *BotHandler) sendMessageWithReplyMarkup(chatID int64, text string, reply
 	}
 }
 
+// clearInlineKeyboard removes the buttons from a message once its answer has been taken.
+func (h *BotHandler) clearInlineKeyboard(msg *tgbotapi.Message) {
+	if msg == nil {
+		return
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(msg.Chat.ID, msg.MessageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	if _, err := h.bot.Request(edit); err != nil {
+		log.Printf("Error clearing inline keyboard: %v", err)
+	}
+}
+
 func (h *BotHandler) sendTodaySchedule(chatID int64, user *models.User) {
 	today := time.Now()
 	if user.TimeZone != "" {
@@ -787,7 +1095,7 @@ func (h *BotHandler) sendTodaySchedule(chatID int64, user *models.User) {
 	}
 
 	response := "📅 Сегодня:\n\n"
-	response += formatDaySchedule(schedules[0], user.DailyCapacity)
+	response += formatDaySchedule(schedules[0], scheduler.DailyCapacityOn(user, schedules[0].Date))
 	h.sendMessage(chatID, response)
 }
 
@@ -814,7 +1122,15 @@ func (h *BotHandler) sendWeekSchedule(chatID int64, user *models.User) {
 
 	response := "📅 Расписание на неделю:\n\n"
 	for _, daySchedule := range schedules {
-		response += formatDaySchedule(daySchedule, user.DailyCapacity)
+		response += formatDaySchedule(daySchedule, scheduler.DailyCapacityOn(user, daySchedule.Date))
+	}
+
+	monday := scheduler.WeekStart(today)
+	thisWeek, err := database.GetScheduleForDateRange(user.ID, monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		log.Printf("Error getting schedule for project budgets: %v", err)
+	} else if budgets := formatProjectBudgets(user, monday, thisWeek); budgets != "" {
+		response += "\n" + budgets
 	}
 	h.sendMessage(chatID, response)
 }
@@ -836,6 +1152,16 @@ func getStatusEmoji(status string) string {
 	}
 }
 
+// userLocation returns the user's time zone, or the server's when it is not set or unknown.
+func userLocation(user *models.User) *time.Location {
+	if user.TimeZone != "" {
+		if loc, err := time.LoadLocation(user.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
 func scheduleStartDate(user *models.User) time.Time {
 	now := time.Now()
 	if user.TimeZone != "" {
@@ -856,7 +1182,7 @@ func formatDayScheduleWithTimes(daySchedule models.DaySchedule, dailyCapacity fl
 	result += fmt.Sprintf("⏱ Нагрузка: %.1f / %.1f ч\n", daySchedule.TotalHours, dailyCapacity)
 
 	if dailyCapacity > 0 && daySchedule.TotalHours > dailyCapacity {
-		result += "⚠️ День перегружен: запланировано больше, чем в настройках.\n"
+		result += "⚠️ День перегружен: запланировано больше лимита на этот день (настройки или /capacity).\n"
 	}
 
 	result += "\n"
@@ -867,11 +1193,19 @@ func formatDayScheduleWithTimes(daySchedule models.DaySchedule, dailyCapacity fl
 	if len(dayAllocs) > 0 {
 		for _, alloc := range dayAllocs {
 			hours := alloc.End.Sub(alloc.Start).Hours()
+			title := alloc.Title
+			if alloc.Pinned {
+				title = "📌 " + title
+			}
 			result += fmt.Sprintf("• %s — %s–%s (%.1f ч) ⭐️ %d\n",
-				alloc.Title, alloc.Start.Format("15:04"), alloc.End.Format("15:04"), hours, alloc.Priority)
+				title, alloc.Start.Format("15:04"), alloc.End.Format("15:04"), hours, alloc.Priority)
 		}
 	} else {
 		for _, task := range daySchedule.Tasks {
+			if task.PinnedStart != nil && task.PinnedEnd != nil {
+				result += fmt.Sprintf("• 📌 %s — %s–%s ⭐️ %d\n", task.Title, task.PinnedStart.Format("15:04"), task.PinnedEnd.Format("15:04"), task.Priority)
+				continue
+			}
 			result += fmt.Sprintf("• %s (%.1f ч) ⭐️ %d\n", task.Title, task.HoursAllocated, task.Priority)
 		}
 	}
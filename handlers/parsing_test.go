@@ -117,3 +117,638 @@ func TestFormatDaySchedule(t *testing.T) {
 		}
 	}
 }
+
+func TestParseWeekdaySpec(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []int
+	}{
+		{"5", []int{5}},
+		{"1-5", []int{1, 2, 3, 4, 5}},
+		{"5,1,1", []int{1, 5}},
+		{"пн-ср", []int{1, 2, 3}},
+		{"Пт", []int{5}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseWeekdaySpec(tc.input)
+			if err != nil {
+				t.Fatalf("parseWeekdaySpec(%q) error: %v", tc.input, err)
+			}
+			if formatWorkDays(got) != formatWorkDays(tc.want) {
+				t.Errorf("parseWeekdaySpec(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "0", "8", "5-1", "xx"} {
+		if _, err := parseWeekdaySpec(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseWorkWindows(t *testing.T) {
+	windows, err := parseWorkWindows("14:00-18:00,09:00-13:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if formatWindowList(windows) != "09:00-13:00, 14:00-18:00" {
+		t.Errorf("expected sorted windows, got %s", formatWindowList(windows))
+	}
+
+	for _, bad := range []string{"09:00-13:00,12:00-15:00", "13:00-09:00", "9-13", "09:00"} {
+		if _, err := parseWorkWindows(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	start, end, err := parseDateRange("2026-12-24..2027-01-08")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !start.Equal(time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2027, 1, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected range %v..%v", start, end)
+	}
+
+	start, end, err = parseDateRange("20.10.2026")
+	if err != nil || !start.Equal(end) {
+		t.Errorf("expected single day, got %v..%v (%v)", start, end, err)
+	}
+
+	for _, bad := range []string{"2027-01-08..2026-12-24", "2026-12-24..", "завтра"} {
+		if _, _, err := parseDateRange(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseRecurrenceSpec(t *testing.T) {
+	start := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	rule, err := parseRecurrenceSpec("weekly пн,пт every 2 until 31.12.2026", start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.Freq != "weekly" || rule.Interval != 2 || formatWorkDays(rule.Weekdays) != "Пн, Пт" || rule.Until == nil {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+	if !rule.Start.Equal(start) {
+		t.Errorf("expected start %v, got %v", start, rule.Start)
+	}
+
+	rule, err = parseRecurrenceSpec("monthly 15 count 3", start)
+	if err != nil || rule.MonthDay != 15 || rule.Count != 3 {
+		t.Errorf("unexpected monthly rule %+v (%v)", rule, err)
+	}
+
+	rule, err = parseRecurrenceSpec("FREQ=DAILY;BYDAY=MO,TU", start)
+	if err != nil || rule.Freq != "daily" || !rule.Start.Equal(start) {
+		t.Errorf("unexpected RRULE rule %+v (%v)", rule, err)
+	}
+
+	for _, bad := range []string{"", "yearly", "weekly xx", "monthly 40", "daily every", "daily until завтра"} {
+		if _, err := parseRecurrenceSpec(bad, start); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCollapseRecurringInstances(t *testing.T) {
+	tmpl := int64(3)
+	d1 := time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)
+	d2 := d1.AddDate(0, 0, 7)
+	d0 := d1.AddDate(0, 0, -7)
+	tasks := []models.Task{
+		{ID: 1, Title: "Отчёт", Status: "completed", RecurringID: &tmpl, Occurrence: &d0},
+		{ID: 2, Title: "Отчёт", Status: "pending", RecurringID: &tmpl, Occurrence: &d2},
+		{ID: 3, Title: "Отчёт", Status: "pending", RecurringID: &tmpl, Occurrence: &d1},
+		{ID: 4, Title: "Разовая", Status: "pending"},
+	}
+
+	got, hidden := collapseRecurringInstances(tasks)
+	if len(got) != 3 || got[1].ID != 3 {
+		t.Fatalf("expected completed, nearest instance and one-off task, got %+v", got)
+	}
+	if hidden[tmpl] != 1 {
+		t.Errorf("expected 1 hidden repeat, got %d", hidden[tmpl])
+	}
+}
+
+func TestFormatTaskHours(t *testing.T) {
+	task := &models.Task{HoursRequired: 4}
+	if got := formatTaskHours(task); got != "⏱ 4 ч" {
+		t.Errorf("got %q", got)
+	}
+	task.HoursSpent = 1.5
+	if got := formatTaskHours(task); got != "⏱ 1.5 / 4 ч" {
+		t.Errorf("got %q", got)
+	}
+	if got := formatTaskProgressLine(task); got != "⏱ 1.5 / 4 ч, осталось 2.5 ч" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDescribeBias(t *testing.T) {
+	if got := describeBias(1.25); !strings.Contains(got, "25% больше") {
+		t.Errorf("got %q", got)
+	}
+	if got := describeBias(0.8); !strings.Contains(got, "20% меньше") {
+		t.Errorf("got %q", got)
+	}
+	if got := describeBias(1.05); got != "👍 Оценки точные" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestApplyTaskOptions(t *testing.T) {
+	task := &models.Task{HoursRequired: 2, Priority: 5}
+	if err := applyTaskOptions(task, "hours=4.5 chunk=1.5h; maxday=3, priority=8 deadline=25.12.2026", time.UTC); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.HoursRequired != 4.5 || task.MinChunkMinutes != 90 || task.MaxHoursPerDay != 3 || task.Priority != 8 {
+		t.Errorf("options not applied: %+v", task)
+	}
+	if task.Deadline == nil || task.Deadline.Day() != 25 {
+		t.Errorf("deadline not applied: %v", task.Deadline)
+	}
+	if got := formatTaskOptions(task); got != "🧩 блок от 90 мин, до 3 ч/день" {
+		t.Errorf("got %q", got)
+	}
+
+	if err := applyTaskOptions(task, "deadline=none", time.UTC); err != nil || task.Deadline != nil {
+		t.Errorf("expected deadline removed, err=%v", err)
+	}
+	for _, bad := range []string{"chunk", "color=red", "priority=11", "maxday=25"} {
+		if err := applyTaskOptions(task, bad, time.UTC); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseChunkMinutes(t *testing.T) {
+	for input, want := range map[string]int{"90": 90, "90m": 90, "45мин": 45, "1.5h": 90, "2ч": 120, "0": 0} {
+		got, err := parseChunkMinutes(input)
+		if err != nil || got != want {
+			t.Errorf("parseChunkMinutes(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	if _, err := parseChunkMinutes("долго"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestApplyTaskOptions_Pinned(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	task := &models.Task{HoursRequired: 2}
+	if err := applyTaskOptions(task, "at=16.10.2026 15:00-16:30 priority=7", loc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.PinnedStart == nil || task.PinnedStart.Hour() != 15 || task.PinnedStart.Location() != loc {
+		t.Fatalf("pinned start not applied: %v", task.PinnedStart)
+	}
+	if task.HoursRequired != 1.5 || task.Priority != 7 {
+		t.Errorf("expected the estimate to follow the block, got %+v", task)
+	}
+	if got := formatPinned(task); got != "📌 16.10.2026 15:00–16:30" {
+		t.Errorf("got %q", got)
+	}
+
+	if err := applyTaskOptions(task, "hours=1", loc); err != nil || task.PinnedEnd.Format("15:04") != "16:00" {
+		t.Errorf("expected a new estimate to move the end, got %v (err=%v)", task.PinnedEnd, err)
+	}
+	if err := applyTaskOptions(task, "at=2026-10-17 09:30", loc); err != nil || task.PinnedEnd.Format("02.01 15:04") != "17.10 10:30" {
+		t.Errorf("expected the block to last the estimate, got %v (err=%v)", task.PinnedEnd, err)
+	}
+	if err := applyTaskOptions(task, "at=none", loc); err != nil || task.PinnedStart != nil || formatPinned(task) != "" {
+		t.Errorf("expected the task to be unpinned, err=%v", err)
+	}
+	for _, bad := range []string{"at=16.10.2026", "at=16.10.2026 16:00-15:00", "at=завтра 10:00"} {
+		if err := applyTaskOptions(task, bad, loc); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestApplyTaskOptions_StartAfter(t *testing.T) {
+	task := &models.Task{Title: "Вёрстка", HoursRequired: 14}
+	if err := applyTaskOptions(task, "after=20.10.2026 14:00 deadline=21.10.2026", time.UTC); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := formatStartAfter(task); got != "⏳ Не раньше 20.10.2026 14:00" {
+		t.Errorf("got %q", got)
+	}
+
+	// 20.10.2026 is a Tuesday: 4h after 14:00 plus 8h on Wednesday.
+	user := &models.User{DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}}
+	if note := startWindowNote(user, task); !strings.Contains(note, "всего 12.0 ч") {
+		t.Errorf("got %q", note)
+	}
+
+	if err := applyTaskOptions(task, "after=22.10.2026", time.UTC); err != nil || formatStartAfter(task) != "⏳ Не раньше 22.10.2026" {
+		t.Fatalf("expected a date-only start, got %q (err=%v)", formatStartAfter(task), err)
+	}
+	if note := startWindowNote(user, task); !strings.Contains(note, "позже дедлайна") {
+		t.Errorf("got %q", note)
+	}
+	if err := applyTaskOptions(task, "after=none", time.UTC); err != nil || task.StartAfter != nil {
+		t.Errorf("expected start_after removed, err=%v", err)
+	}
+	if err := applyTaskOptions(task, "after=20.10.2026 25:00", time.UTC); err == nil {
+		t.Error("expected error for an invalid time")
+	}
+}
+
+func TestApplyTaskOptions_DeadlineTime(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*3600)
+	task := &models.Task{Title: "Презентация", HoursRequired: 3}
+	if err := applyTaskOptions(task, "deadline=23.10.2026 12:00", loc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2026, 10, 23, 12, 0, 0, 0, loc)
+	if task.Deadline == nil || !task.Deadline.Equal(want) || formatDateTime(*task.Deadline) != "23.10.2026 12:00" {
+		t.Fatalf("got %v", task.Deadline)
+	}
+
+	// Starting at noon of the deadline day leaves no time before it.
+	after := want
+	task.StartAfter = &after
+	if note := startWindowNote(&models.User{DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}}, task); !strings.Contains(note, "позже дедлайна") {
+		t.Errorf("got %q", note)
+	}
+
+	if err := applyTaskOptions(task, "deadline=23.10.2026", loc); err != nil || formatDateTime(*task.Deadline) != "23.10.2026" {
+		t.Errorf("expected a date-only deadline, got %v (err=%v)", task.Deadline, err)
+	}
+	if err := applyTaskOptions(task, "deadline=23.10.2026 12", loc); err == nil {
+		t.Error("expected error for an invalid time")
+	}
+}
+
+func TestFormatDiagnosis(t *testing.T) {
+	due := time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	fit := due.AddDate(0, 0, 1)
+	d := models.Diagnosis{
+		NeededHours: 6, WindowEnd: due, WindowHours: 8, BusyHours: 1, TakenHours: 5, FreeHours: 2,
+		TakenBy:     []models.TaskHours{{TaskID: 1, Title: "Important", Hours: 5}},
+		ExtraPerDay: 2, FitDeadline: &fit,
+		YieldTasks: []models.TaskHours{{TaskID: 1, Title: "Important", Hours: 5}},
+	}
+	got := formatDiagnosis("Report", d)
+	for _, want := range []string{"нужно 6.0 ч до 07.01.2025, свободно 2.0 ч", "«Important» (ID:1, 5.0 ч)", "события календаря — 1.0 ч", "+2 ч в день", "дедлайном 08.01.2025", "понизьте приоритет"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	d.FreeHours = 6
+	if got := formatDiagnosis("Report", d); !strings.Contains(got, "Времени хватает") || strings.Contains(got, "💡") {
+		t.Errorf("expected a fragmentation note without remedies, got:\n%s", got)
+	}
+}
+
+func TestChangedCalendarEvents(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*3600)
+	at := func(hour int) time.Time { return time.Date(2025, 1, 6, hour, 0, 0, 0, loc) }
+	// Stored times come back from the database as wall clock labelled UTC.
+	stored := []models.GoogleCalendarEvent{
+		{GoogleEventID: "a", TaskID: 1, StartTime: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC), EndTime: time.Date(2025, 1, 6, 11, 0, 0, 0, time.UTC)},
+		{GoogleEventID: "b", TaskID: 2, StartTime: time.Date(2025, 1, 6, 11, 0, 0, 0, time.UTC), EndTime: time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)},
+		{GoogleEventID: "c", TaskID: 9, StartTime: time.Date(2025, 1, 6, 15, 0, 0, 0, time.UTC), EndTime: time.Date(2025, 1, 6, 16, 0, 0, 0, time.UTC)},
+	}
+	allocations := []models.SlotAllocation{
+		{TaskID: 1, Start: at(9), End: at(11)},
+		{TaskID: 2, Start: at(12), End: at(13)},
+		{TaskID: 3, Start: at(11), End: at(12)},
+	}
+
+	stale, fresh := changedCalendarEvents([]int64{1, 2, 3}, stored, allocations)
+	if len(stale) != 1 || stale[0] != "b" {
+		t.Errorf("expected only the moved task's event to go, got %v", stale)
+	}
+	if len(fresh) != 2 || fresh[0].TaskID != 2 || fresh[1].TaskID != 3 {
+		t.Errorf("expected the moved and the new task exported, got %+v", fresh)
+	}
+}
+
+func TestFormatTaskMoves(t *testing.T) {
+	if got := formatTaskMoves(nil); !strings.Contains(got, "остались на своих днях") {
+		t.Errorf("got %q", got)
+	}
+	got := formatTaskMoves([]models.TaskMove{{TaskID: 4, Title: "Отчёт", MovedHours: 2, ShiftDays: 1}})
+	if !strings.Contains(got, "Сдвинуто задач: 1") || !strings.Contains(got, "«Отчёт» (ID:4): 2.0 ч на другие дни, начало на 1 дн. позже") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPlanPreviews_TakeOnlyLatestUnexpired(t *testing.T) {
+	now := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	p := newPlanPreviews()
+	first := p.put(1, &rebuildPlan{}, now)
+	second := p.put(1, &rebuildPlan{stable: true}, now)
+
+	if _, ok := p.take(1, first, now); ok {
+		t.Error("a replaced preview must not be applied")
+	}
+	if plan, ok := p.take(1, second, now); !ok || !plan.stable {
+		t.Errorf("expected the latest preview, got %+v, %v", plan, ok)
+	}
+	if _, ok := p.take(1, second, now); ok {
+		t.Error("a preview can be taken only once")
+	}
+
+	token := p.put(2, &rebuildPlan{}, now)
+	if _, ok := p.take(2, token, now.Add(planPreviewTTL+time.Second)); ok {
+		t.Error("an expired preview must not be applied")
+	}
+}
+
+func TestRemapPlanTaskIDs(t *testing.T) {
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	plan := &rebuildPlan{
+		result: &models.ScheduleResult{
+			DaySchedules: []models.DaySchedule{{Date: day, Tasks: []models.ScheduledTaskInfo{
+				{TaskID: 7, HoursAllocated: 1},
+				{TaskID: -1, HoursAllocated: 2},
+				{TaskID: -2, HoursAllocated: 1},
+			}}},
+			UnscheduledTasks: []int64{-2, 9},
+			Diagnoses:        map[int64]models.Diagnosis{-1: {NeededHours: 1}},
+		},
+		timeAllocations: []models.SlotAllocation{{TaskID: -1}, {Break: true}, {TaskID: -2}, {TaskID: 7}},
+	}
+
+	remapPlanTaskIDs(plan, map[int64]int64{-1: 42, -2: 0})
+
+	var got []int64
+	for _, info := range plan.result.DaySchedules[0].Tasks {
+		got = append(got, info.TaskID)
+	}
+	if len(got) != 2 || got[0] != 7 || got[1] != 42 {
+		t.Errorf("day tasks: got %v, want [7 42]", got)
+	}
+	if len(plan.result.UnscheduledTasks) != 1 || plan.result.UnscheduledTasks[0] != 9 {
+		t.Errorf("unscheduled: got %v, want [9]", plan.result.UnscheduledTasks)
+	}
+	if _, ok := plan.result.Diagnoses[42]; !ok || len(plan.result.Diagnoses) != 1 {
+		t.Errorf("diagnoses not moved to the saved ID: %v", plan.result.Diagnoses)
+	}
+	if len(plan.timeAllocations) != 3 || plan.timeAllocations[0].TaskID != 42 || !plan.timeAllocations[1].Break {
+		t.Errorf("allocations: got %+v", plan.timeAllocations)
+	}
+}
+
+func TestFormatPlanPreview(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	block := func(hour, hours int) models.SlotAllocation {
+		start := monday.Add(time.Duration(hour) * time.Hour)
+		return models.SlotAllocation{Start: start, End: start.Add(time.Duration(hours) * time.Hour)}
+	}
+	deadline := monday.AddDate(0, 0, 1)
+	days := []models.DayDiff{{Date: monday, Changes: []models.BlockChange{
+		{TaskID: 1, Title: "Отчёт", Kind: models.BlockMoved, Before: []models.SlotAllocation{block(9, 2)}, After: []models.SlotAllocation{block(13, 2)}},
+		{TaskID: 2, Title: "Код", Kind: models.BlockAdded, After: []models.SlotAllocation{block(11, 1)}},
+	}}}
+
+	got := formatPlanPreview("полное перепланирование", 2, 3, days, []models.Task{{ID: 3, Title: "Ревью", Deadline: &deadline}})
+	for _, want := range []string{
+		"Поместится задач: 2 из 3",
+		"Понедельник, 06.01.2025",
+		"🔀 «Отчёт» 09:00–11:00 → 13:00–15:00",
+		"➕ «Код» 11:00–12:00",
+		"• «Ревью» (ID:3) — дедлайн 07.01.2025",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if got := formatPlanPreview("полное перепланирование", 1, 1, nil, nil); !strings.Contains(got, "не меняется") {
+		t.Errorf("expected a no-change note, got:\n%s", got)
+	}
+}
+
+func TestApplyTaskOptions_TimePreference(t *testing.T) {
+	task := &models.Task{Title: "Статья", HoursRequired: 4}
+	if err := applyTaskOptions(task, "pref=после 14:00 не пн,ср hours=5", time.UTC); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.TimePreference != "after 14:00 not mon,wed" || task.HoursRequired != 5 {
+		t.Errorf("got preference %q, hours %v", task.TimePreference, task.HoursRequired)
+	}
+	user := &models.User{ProjectTimePrefs: []models.ProjectTimePref{{Project: "clienta", TimePreference: "before 12:00"}}}
+	task.Project = "clienta"
+	if got := formatTimePreference(user, task); got != "🕘 Лучше после 14:00, не Пн, Ср" {
+		t.Errorf("own preference must win over the project's, got %q", got)
+	}
+	if err := applyTaskOptions(task, "pref=когда-нибудь", time.UTC); err == nil {
+		t.Error("expected error for an unknown preference")
+	}
+	if err := applyTaskOptions(task, "pref=none", time.UTC); err != nil || task.TimePreference != "" {
+		t.Errorf("expected preference removed, err=%v", err)
+	}
+	if got := formatTimePreference(user, task); got != "🕘 Лучше до 12:00 (как у #clienta)" {
+		t.Errorf("expected the project's preference, got %q", got)
+	}
+}
+
+func TestApplyTaskOptions_Energy(t *testing.T) {
+	task := &models.Task{Title: "Архитектура", HoursRequired: 3}
+	if err := applyTaskOptions(task, "energy=высокая", time.UTC); err != nil || task.Energy != "high" {
+		t.Fatalf("got energy %q, err=%v", task.Energy, err)
+	}
+	if got := formatEnergy(task); got != "⚡ Энергия: высокая" {
+		t.Errorf("got %q", got)
+	}
+	if err := applyTaskOptions(task, "energy=max", time.UTC); err == nil {
+		t.Error("expected error for an unknown level")
+	}
+	if err := applyTaskOptions(task, "energy=none", time.UTC); err != nil || task.Energy != "" || formatEnergy(task) != "" {
+		t.Errorf("expected energy removed, err=%v", err)
+	}
+	user := &models.User{EnergyCurve: "high before 12:00 low after 16:00", MaxHighEnergyHours: 3}
+	if got := formatEnergySettings(user); got != "высокая до 12:00, низкая после 16:00, не больше 3 ч тяжёлой работы в день" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFormatAging(t *testing.T) {
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	user := &models.User{PriorityAgingDays: 7}
+	task := &models.Task{Priority: 3, CreatedAt: now.AddDate(0, 0, -28)}
+	if got := formatAging(user, task, now); got != "⏫ Давно ждёт: 28 дн., приоритет 3 → 7" {
+		t.Errorf("got %q", got)
+	}
+	task.CreatedAt = now.AddDate(0, 0, -60)
+	if got := formatAging(user, task, now); got != "⏫ Давно ждёт: 60 дн., приоритет 3 → 10, с 13.10.2026 в очереди наравне с дедлайнами" {
+		t.Errorf("got %q", got)
+	}
+	task.Status = "completed"
+	if got := formatAging(user, task, now); got != "" {
+		t.Errorf("expected no aging for a closed task, got %q", got)
+	}
+	if got := formatAging(&models.User{}, &models.Task{Priority: 3, CreatedAt: now.AddDate(0, 0, -60)}, now); got != "" {
+		t.Errorf("expected no aging when it is off, got %q", got)
+	}
+}
+
+func TestParseBreakRule(t *testing.T) {
+	cases := map[string][2]int{"90/10": {90, 10}, "pomodoro": {25, 5}, "off": {0, 0}}
+	for spec, want := range cases {
+		every, length, err := parseBreakRule(spec)
+		if err != nil || every != want[0] || length != want[1] {
+			t.Errorf("%q: got %d/%d, err=%v", spec, every, length, err)
+		}
+	}
+	for _, bad := range []string{"90", "5/10", "90/0", "abc/10"} {
+		if _, _, err := parseBreakRule(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+
+	user := &models.User{BreakEveryMinutes: 90, BreakMinutes: 10, LunchStart: "13:00", LunchEnd: "14:00", ExportBreaks: true}
+	if got := formatBreaks(user); got != "10 мин каждые 90 мин работы, обед 13:00-14:00, в Google Calendar" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFormatBuffers(t *testing.T) {
+	cases := []struct {
+		user models.User
+		want string
+	}{
+		{models.User{}, "нет"},
+		{models.User{BufferBefore: 10, BufferAfter: 10}, "10 мин до и после встреч"},
+		{models.User{BufferBefore: 5, BufferAfter: 15, TravelMinutes: 30}, "5 мин до, 15 мин после встреч, дорога 30 мин"},
+	}
+	for _, c := range cases {
+		if got := formatBuffers(&c.user); got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+	}
+	if _, err := parseBufferMinutes("300"); err == nil {
+		t.Error("expected an error for 300 minutes")
+	}
+}
+
+func TestHoursOverCapacity(t *testing.T) {
+	day := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	user := &models.User{
+		DailyCapacity:     8,
+		CapacityOverrides: []models.CapacityOverride{{StartDate: day, EndDate: day, Hours: 3}},
+	}
+	schedules := []models.DaySchedule{
+		{Date: day, TotalHours: 5},
+		{Date: day.AddDate(0, 0, 1), TotalHours: 6},
+	}
+	if got := hoursOverCapacity(user, schedules); got != 2 {
+		t.Errorf("expected 2 hours over the cap, got %v", got)
+	}
+}
+
+func TestParseMissedCallback(t *testing.T) {
+	answer, err := parseMissedCallback("missed_did:42:1.5:20260601:20260603", "missed_did:")
+	if err != nil || answer.taskID != 42 || answer.hours != 1.5 {
+		t.Errorf("got %+v %v, want task 42 and 1.5 hours", answer, err)
+	}
+	if !answer.firstDay.Equal(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)) || !answer.lastDay.Equal(time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the prompt to cover 01.06–03.06, got %v–%v", answer.firstDay, answer.lastDay)
+	}
+	for _, bad := range []string{
+		"missed_did:42:1.5", "missed_did:x:1:20260601:20260601", "missed_did:42:-1:20260601:20260601",
+		"missed_did:42:NaN:20260601:20260601", "missed_did:42:1:20260603:20260601", "missed_did:42:1:0601:20260601",
+	} {
+		if _, err := parseMissedCallback(bad, "missed_did:"); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+
+	got := partialHours(2)
+	if len(got) != 3 || got[0] != 0.5 || got[1] != 1 || got[2] != 1.5 {
+		t.Errorf("expected 0.5, 1 and 1.5 of 2 hours, got %v", got)
+	}
+	if got := partialHours(0.25); len(got) != 0 {
+		t.Errorf("expected no split of a quarter hour, got %v", got)
+	}
+}
+
+func TestProjectTags(t *testing.T) {
+	title, project := extractProjectTag("Макет лендинга #ClientA")
+	if title != "Макет лендинга" || project != "clienta" {
+		t.Errorf("got %q, %q", title, project)
+	}
+	if title, project := extractProjectTag("Разобрать # заметки"); title != "Разобрать # заметки" || project != "" {
+		t.Errorf("expected a bare # kept in the title, got %q, %q", title, project)
+	}
+
+	task := &models.Task{Title: "Макет", HoursRequired: 3}
+	if err := applyTaskOptions(task, "chunk=60 #Дом", time.UTC); err != nil || task.Project != "дом" || task.MinChunkMinutes != 60 {
+		t.Errorf("expected project and chunk set, got %q %d err=%v", task.Project, task.MinChunkMinutes, err)
+	}
+	if err := applyTaskOptions(task, "project=a.b", time.UTC); err == nil {
+		t.Error("expected error for an invalid project")
+	}
+	if err := applyTaskOptions(task, "project=none", time.UTC); err != nil || task.Project != "" {
+		t.Errorf("expected project removed, err=%v", err)
+	}
+}
+
+func TestFormatProjectBudgets(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	user := &models.User{ProjectBudgets: []models.ProjectBudget{
+		{Project: "clienta", WeeklyHours: 10},
+		{Project: "clientb", WeeklyHours: 4},
+	}}
+	schedules := []models.DaySchedule{
+		{Date: monday, Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, HoursAllocated: 4, Project: "clienta"},
+			{TaskID: 2, HoursAllocated: 2, Project: "home"},
+			{TaskID: 3, HoursAllocated: 1},
+		}},
+		{Date: monday.AddDate(0, 0, 1), Tasks: []models.ScheduledTaskInfo{{TaskID: 1, HoursAllocated: 2.5, Project: "clienta"}}},
+	}
+
+	want := "📊 Проекты на неделе 19.10–25.10:\n• #clienta — 6.5 / 10 ч\n• #clientb — 0.0 / 4 ч\n• #home — 2.0 ч"
+	if got := formatProjectBudgets(user, monday, schedules); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := hoursOverBudget("clienta", 5, schedules); got != 1.5 {
+		t.Errorf("expected 1.5 h over the budget, got %v", got)
+	}
+}
+
+func TestSubtaskParsing(t *testing.T) {
+	lines := subtaskLines(" Собрать данные | 2\n\n- Черновик #дом | 3 | 8\n[ ] Вычитать | 1\n")
+	if len(lines) != 3 || lines[1] != "Черновик #дом | 3 | 8" || lines[2] != "Вычитать | 1" {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+
+	parentID := int64(7)
+	base := models.Task{UserID: 1, Priority: 6, Project: "clienta", ParentID: &parentID}
+	task, err := parseTaskArgs(lines[0], base, time.UTC)
+	if err != nil || task.Priority != 6 || task.Project != "clienta" || task.ParentID == nil || *task.ParentID != 7 || task.HoursRequired != 2 {
+		t.Errorf("expected the parent's priority and project kept, got %+v err=%v", task, err)
+	}
+	task, err = parseTaskArgs(lines[1], base, time.UTC)
+	if err != nil || task.Title != "Черновик" || task.Priority != 8 || task.Project != "дом" {
+		t.Errorf("expected priority and project overridden, got %+v err=%v", task, err)
+	}
+	if _, err := parseTaskArgs("Без часов", base, time.UTC); err == nil {
+		t.Error("expected error without hours")
+	}
+
+	tasks := []models.Task{
+		{ID: 7, Title: "Отчёт"},
+		{ID: 8, Title: "Другое"},
+		{ID: 10, ParentID: &parentID, Position: 2},
+		{ID: 9, ParentID: &parentID, Position: 1},
+	}
+	var order []int64
+	for _, task := range nestSubtasks(tasks) {
+		order = append(order, task.ID)
+	}
+	if len(order) != 4 || order[0] != 7 || order[1] != 9 || order[2] != 10 || order[3] != 8 {
+		t.Errorf("expected subtasks right after their parent in order, got %v", order)
+	}
+}
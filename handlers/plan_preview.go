@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+// planPreviewTTL is how long a /schedule preview can still be applied.
+const planPreviewTTL = 15 * time.Minute
+
+// maxPreviewDays limits how many changed days a preview lists.
+const maxPreviewDays = 7
+
+type planPreview struct {
+	token   int64
+	expires time.Time
+	plan    *rebuildPlan
+}
+
+// planPreviews keeps the latest unapplied rebuild of each user. A new preview replaces the
+// previous one, so only the buttons of the last preview work.
+type planPreviews struct {
+	mu     sync.Mutex
+	next   int64
+	byUser map[int64]planPreview
+}
+
+func newPlanPreviews() *planPreviews {
+	return &planPreviews{byUser: make(map[int64]planPreview)}
+}
+
+// put stores plan as the user's pending preview and returns its token.
+func (p *planPreviews) put(userID int64, plan *rebuildPlan, now time.Time) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, pending := range p.byUser {
+		if now.After(pending.expires) {
+			delete(p.byUser, id)
+		}
+	}
+	p.next++
+	p.byUser[userID] = planPreview{token: p.next, expires: now.Add(planPreviewTTL), plan: plan}
+	return p.next
+}
+
+// take removes and returns the user's pending preview when token is the latest one and it has
+// not expired.
+func (p *planPreviews) take(userID, token int64, now time.Time) (*rebuildPlan, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending, ok := p.byUser[userID]
+	if !ok || pending.token != token {
+		return nil, false
+	}
+	delete(p.byUser, userID)
+	if now.After(pending.expires) {
+		return nil, false
+	}
+	return pending.plan, true
+}
+
+// previewRebuild plans all active tasks and shows how the result differs from the saved plan.
+// Nothing is written until the user presses «Применить».
+func (h *BotHandler) previewRebuild(chatID int64, user *models.User, stable bool) {
+	plan, ok := h.buildRebuild(chatID, user, stable)
+	if !ok {
+		return
+	}
+	h.sendPlanPreview(chatID, user, plan, "")
+}
+
+func (h *BotHandler) sendPlanPreview(chatID int64, user *models.User, plan *rebuildPlan, header string) {
+	token := h.previews.put(user.ID, plan, time.Now())
+
+	planned := make(map[int64]bool, len(plan.tasks))
+	for i := range plan.tasks {
+		planned[plan.tasks[i].ID] = true
+	}
+	var before []models.SlotAllocation
+	for _, a := range scheduler.PlanTimeAllocations(user, plan.baseline, plan.startDate, plan.busy) {
+		if planned[a.TaskID] {
+			before = append(before, a)
+		}
+	}
+
+	var late []models.Task
+	for _, id := range scheduler.NewlyLate(plan.tasks, plan.baseline, plan.result) {
+		for i := range plan.tasks {
+			if plan.tasks[i].ID == id {
+				late = append(late, plan.tasks[i])
+			}
+		}
+	}
+
+	// Pinned tasks that already took place are neither planned nor missing
+	past := len(plan.result.PastTasks)
+	text := header + formatPlanPreview(rebuildLabel(plan.stable), len(plan.tasks)-len(plan.result.UnscheduledTasks)-past, len(plan.tasks)-past,
+		scheduler.DiffBlocks(before, plan.timeAllocations), late)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Применить", fmt.Sprintf("plan_apply:%d", token)),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Отмена", fmt.Sprintf("plan_cancel:%d", token)),
+		),
+	)
+	h.sendMessageWithReplyMarkup(chatID, text, &keyboard)
+}
+
+// applyPreview saves a previewed rebuild. The plan is computed again first: if tasks, the saved
+// plan or the calendar changed since the preview, the fresh plan is previewed instead of saved.
+func (h *BotHandler) applyPreview(chatID int64, user *models.User, token int64) {
+	previewed, ok := h.previews.take(user.ID, token, time.Now())
+	if !ok {
+		h.sendMessage(chatID, "⌛ Предпросмотр устарел или уже применён — ничего не изменено.\nВыполните /schedule ещё раз.")
+		return
+	}
+	plan, ok := h.buildRebuild(chatID, user, previewed.stable)
+	if !ok {
+		return
+	}
+	if !samePlan(previewed, plan) {
+		h.sendPlanPreview(chatID, user, plan, "♻️ С момента предпросмотра изменились задачи, расписание или календарь — вот актуальный план.\n\n")
+		return
+	}
+	h.applyRebuild(chatID, user, plan)
+}
+
+func (h *BotHandler) cancelPreview(chatID int64, user *models.User, token int64) {
+	if _, ok := h.previews.take(user.ID, token, time.Now()); !ok {
+		h.sendMessage(chatID, "Предпросмотр уже неактуален — ничего не изменено.")
+		return
+	}
+	h.sendMessage(chatID, "✖️ Перепланирование отменено, текущее расписание не тронуто.")
+}
+
+// samePlan reports whether two computations of a rebuild start from the same saved plan and
+// produce the same result.
+func samePlan(a, b *rebuildPlan) bool {
+	if a.stable != b.stable || !a.startDate.Equal(b.startDate) {
+		return false
+	}
+	if len(scheduler.DiffBlocks(a.timeAllocations, b.timeAllocations)) > 0 {
+		return false
+	}
+	return dayHoursKey(a.baseline) == dayHoursKey(b.baseline) &&
+		dayHoursKey(a.result.DaySchedules) == dayHoursKey(b.result.DaySchedules) &&
+		fmt.Sprint(sortedIDs(a.result.UnscheduledTasks)) == fmt.Sprint(sortedIDs(b.result.UnscheduledTasks))
+}
+
+// dayHoursKey renders a plan's hours per day and task in a canonical order.
+func dayHoursKey(days []models.DaySchedule) string {
+	var parts []string
+	for _, day := range days {
+		for _, info := range day.Tasks {
+			parts = append(parts, fmt.Sprintf("%s/%d/%.4f", day.Date.Format("2006-01-02"), info.TaskID, info.HoursAllocated))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func sortedIDs(ids []int64) []int64 {
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// formatPlanPreview renders a rebuild preview: how many tasks fit, the changed blocks per day
+// and the tasks that would stop meeting their deadline.
+func formatPlanPreview(modeLabel string, scheduled, total int, days []models.DayDiff, late []models.Task) string {
+	response := fmt.Sprintf("👀 Предпросмотр (%s)\n\n📌 Поместится задач: %d из %d\n", modeLabel, scheduled, total)
+
+	if len(days) == 0 {
+		response += "\n🧷 Время задач в расписании не меняется.\n"
+	} else {
+		response += "\n🔁 Изменения по дням:\n"
+		for i, day := range days {
+			if i >= maxPreviewDays {
+				response += fmt.Sprintf("\n... и ещё %d дней с изменениями\n", len(days)-maxPreviewDays)
+				break
+			}
+			response += fmt.Sprintf("\n📆 %s, %s\n", getWeekdayRu(day.Date.Weekday()), day.Date.Format("02.01.2006"))
+			for _, c := range day.Changes {
+				switch c.Kind {
+				case models.BlockAdded:
+					response += fmt.Sprintf("➕ «%s» %s\n", c.Title, formatBlocks(c.After))
+				case models.BlockRemoved:
+					response += fmt.Sprintf("➖ «%s» %s\n", c.Title, formatBlocks(c.Before))
+				case models.BlockMoved:
+					response += fmt.Sprintf("🔀 «%s» %s → %s\n", c.Title, formatBlocks(c.Before), formatBlocks(c.After))
+				}
+			}
+		}
+	}
+
+	if len(late) > 0 {
+		response += "\n⏰ Перестанут успевать к дедлайну:\n"
+		for _, task := range late {
+			response += fmt.Sprintf("• «%s» (ID:%d) — дедлайн %s\n", task.Title, task.ID, formatDateTime(*task.Deadline))
+		}
+	}
+
+	response += fmt.Sprintf("\nПока ничего не записано. «Применить» сохранит план и обновит Google Calendar; предпросмотр действует %d мин.", int(planPreviewTTL.Minutes()))
+	return response
+}
+
+// formatBlocks renders blocks as 09:00–11:00, 13:00–14:00.
+func formatBlocks(blocks []models.SlotAllocation) string {
+	parts := make([]string, len(blocks))
+	for i, b := range blocks {
+		parts[i] = b.Start.Format("15:04") + "–" + b.End.Format("15:04")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+// maxProjectLength matches tasks.project and user_project_budgets.project.
+const maxProjectLength = 64
+
+const budgetUsage = `Формат: /budget #проект ЧАСЫ — не больше N часов задач проекта в неделю (пн–вс)
+Примеры:
+/budget #clienta 10
+/budget #clienta off — убрать лимит
+
+/budget — список лимитов и загрузка текущей недели`
+
+// handleBudget handles /budget: list, set or remove a project's weekly hour budget.
+func (h *BotHandler) handleBudget(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	fields := strings.Fields(msg.CommandArguments())
+	if len(fields) == 0 {
+		h.sendBudgetList(msg.Chat.ID, user)
+		return
+	}
+	if len(fields) != 2 {
+		h.sendMessage(msg.Chat.ID, budgetUsage)
+		return
+	}
+	project, err := parseProjectTag(fields[0])
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❗️ %v\n\n%s", err, budgetUsage))
+		return
+	}
+
+	if strings.EqualFold(fields[1], "off") {
+		removed, err := database.DeleteUserProjectBudget(user.ID, project)
+		if err != nil {
+			log.Printf("Error deleting project budget: %v", err)
+			h.sendMessage(msg.Chat.ID, "Ошибка при удалении лимита")
+			return
+		}
+		if !removed {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("У #%s нет лимита", project))
+			return
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🗑 Лимит #%s убран. Чтобы задачи проекта заняли освободившееся время, выполните /schedule.", project))
+		return
+	}
+
+	hours, err := strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", "."), 64)
+	if err != nil || hours <= 0 || hours > 168 {
+		h.sendMessage(msg.Chat.ID, "Часы в неделю — число больше 0 и не больше 168.\n\n"+budgetUsage)
+		return
+	}
+	if err := database.SetUserProjectBudget(user.ID, project, hours); err != nil {
+		log.Printf("Error saving project budget: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при сохранении лимита")
+		return
+	}
+
+	response := fmt.Sprintf("📊 #%s: не больше %g ч в неделю", project, hours)
+	schedules, err := database.GetAllUserSchedulesFrom(user.ID, scheduleStartDate(user))
+	if err != nil {
+		log.Printf("Error checking schedules for project budget: %v", err)
+		h.sendMessage(msg.Chat.ID, response)
+		return
+	}
+	if over := hoursOverBudget(project, hours, schedules); over > 1e-9 {
+		response += fmt.Sprintf("\n\n⚠️ В расписании уже на %.1f ч больше лимита. Перепланировать расписание?", over)
+		h.offerRebuild(msg.Chat.ID, response)
+		return
+	}
+	h.sendMessage(msg.Chat.ID, response)
+}
+
+func (h *BotHandler) sendBudgetList(chatID int64, user *models.User) {
+	monday := scheduler.WeekStart(time.Now().In(userLocation(user)))
+	schedules, err := database.GetScheduleForDateRange(user.ID, monday, monday.AddDate(0, 0, 6))
+	if err != nil {
+		log.Printf("Error getting schedule for project budgets: %v", err)
+		h.sendMessage(chatID, "Ошибка получения расписания")
+		return
+	}
+	usage := formatProjectBudgets(user, monday, schedules)
+	if usage == "" {
+		h.sendMessage(chatID, "Лимитов по проектам нет и на этой неделе нет задач с проектом.\n\n"+budgetUsage)
+		return
+	}
+	h.sendMessage(chatID, usage+"\n\n"+budgetUsage)
+}
+
+const preferUsage = `Формат: /prefer #проект ВРЕМЯ — когда лучше работать над задачами проекта
+Время — как в pref=: утром, днём, вечером, после 14:00, до 12:00, 10:00-12:00, не пн,пт
+Своё pref= у задачи важнее предпочтения проекта.
+Примеры:
+/prefer #clienta после 14:00
+/prefer #deepwork утром не пт
+/prefer #clienta off — убрать
+
+/prefer — список предпочтений`
+
+// handlePrefer handles /prefer: list, set or remove the preferred time of a project's tasks.
+func (h *BotHandler) handlePrefer(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	fields := strings.Fields(msg.CommandArguments())
+	if len(fields) == 0 {
+		if list := formatProjectTimePrefs(user); list != "" {
+			h.sendMessage(msg.Chat.ID, list+"\n\n"+preferUsage)
+			return
+		}
+		h.sendMessage(msg.Chat.ID, "Предпочтений по проектам нет.\n\n"+preferUsage)
+		return
+	}
+	if len(fields) < 2 {
+		h.sendMessage(msg.Chat.ID, preferUsage)
+		return
+	}
+	project, err := parseProjectTag(fields[0])
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❗️ %v\n\n%s", err, preferUsage))
+		return
+	}
+
+	spec := strings.Join(fields[1:], " ")
+	if strings.EqualFold(spec, "off") {
+		removed, err := database.DeleteUserProjectTimePref(user.ID, project)
+		if err != nil {
+			log.Printf("Error deleting project time preference: %v", err)
+			h.sendMessage(msg.Chat.ID, "Ошибка при удалении предпочтения")
+			return
+		}
+		if !removed {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("У #%s нет предпочтения по времени", project))
+			return
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🗑 Предпочтение #%s убрано. Применится при следующем /schedule.", project))
+		return
+	}
+
+	pref, err := scheduler.ParseTimePreference(spec)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❗️ Не понял время: %v\n\n%s", err, preferUsage))
+		return
+	}
+	if err := database.SetUserProjectTimePref(user.ID, project, scheduler.FormatTimePreference(pref)); err != nil {
+		log.Printf("Error saving project time preference: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при сохранении предпочтения")
+		return
+	}
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("🕘 #%s: лучше %s — для задач проекта без своего pref=. Применится при следующем /schedule.",
+		project, describeTimePreference(pref)))
+}
+
+// formatProjectTimePrefs lists the preferred times of the user's projects, or "" when there are none.
+func formatProjectTimePrefs(user *models.User) string {
+	if len(user.ProjectTimePrefs) == 0 {
+		return ""
+	}
+	lines := []string{"🕘 Время по проектам:"}
+	for _, p := range user.ProjectTimePrefs {
+		pref, err := scheduler.ParseTimePreference(p.TimePreference)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("• #%s — лучше %s", p.Project, describeTimePreference(pref)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hoursOverBudget sums, per week, how far the project's planned hours exceed a weekly budget.
+func hoursOverBudget(project string, budget float64, schedules []models.DaySchedule) float64 {
+	weeks := make(map[string][]models.DaySchedule)
+	for _, day := range schedules {
+		key := scheduler.WeekStart(day.Date).Format("2006-01-02")
+		weeks[key] = append(weeks[key], day)
+	}
+	var over float64
+	for _, days := range weeks {
+		if excess := scheduler.ProjectHours(days)[project] - budget; excess > 0 {
+			over += excess
+		}
+	}
+	return over
+}
+
+// formatProjectBudgets renders the planned hours of each project in the week starting on monday,
+// against its budget; projects with a budget are listed even when nothing is planned.
+// It returns "" when there is nothing to show.
+func formatProjectBudgets(user *models.User, monday time.Time, schedules []models.DaySchedule) string {
+	hours := scheduler.ProjectHours(schedules)
+	for _, b := range user.ProjectBudgets {
+		if _, ok := hours[b.Project]; !ok {
+			hours[b.Project] = 0
+		}
+	}
+	if len(hours) == 0 {
+		return ""
+	}
+	projects := make([]string, 0, len(hours))
+	for project := range hours {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+
+	lines := []string{fmt.Sprintf("📊 Проекты на неделе %s–%s:", monday.Format("02.01"), monday.AddDate(0, 0, 6).Format("02.01"))}
+	for _, project := range projects {
+		line := fmt.Sprintf("• #%s — %.1f ч", project, hours[project])
+		if budget := scheduler.ProjectBudget(user, project); budget > 0 {
+			line = fmt.Sprintf("• #%s — %.1f / %g ч", project, hours[project], budget)
+			if hours[project] > budget+1e-9 {
+				line += " ⚠️"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseProjectTag reads a project tag such as "#ClientA" or "clienta" into its stored form "clienta".
+func parseProjectTag(s string) (string, error) {
+	project := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if project == "" || len(project) > maxProjectLength {
+		return "", fmt.Errorf("неверный проект %q: укажите #имя (до %d символов)", s, maxProjectLength)
+	}
+	for _, r := range project {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return "", fmt.Errorf("неверный проект %q: только буквы, цифры, _ и -", s)
+		}
+	}
+	return project, nil
+}
+
+// extractProjectTag removes "#tag" words from a task title and returns the last valid tag as the
+// project ("" when there is none). Words that are not valid tags stay in the title.
+func extractProjectTag(title string) (string, string) {
+	var words []string
+	project := ""
+	for _, word := range strings.Fields(title) {
+		if strings.HasPrefix(word, "#") {
+			if p, err := parseProjectTag(word); err == nil {
+				project = p
+				continue
+			}
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), project
+}
+
+// filterTasksByProject keeps the tasks of one project.
+func filterTasksByProject(tasks []models.Task, project string) []models.Task {
+	var kept []models.Task
+	for _, task := range tasks {
+		if task.Project == project {
+			kept = append(kept, task)
+		}
+	}
+	return kept
+}
+
+// formatProject renders a task's project tag, or "" without a project.
+func formatProject(task *models.Task) string {
+	if task.Project == "" {
+		return ""
+	}
+	return "🏷 #" + task.Project
+}
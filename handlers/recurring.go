@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+const recurringUsage = `Формат: /addrecurring Название | часы | правило | приоритет
+Правило:
+• daily — каждый день
+• weekly пт — каждую пятницу (дни: пн,ср или 1-5)
+• monthly 15 — 15-го числа каждого месяца
+Дополнительно: every 2 (раз в 2 периода), until 31.12.2026, count 10
+Или RRULE: FREQ=WEEKLY;BYDAY=MO,FR;COUNT=10
+
+Примеры:
+/addrecurring Недельный отчёт | 2 | weekly пт | 7
+/addrecurring Ревью задач | 0.5 | daily 1-5`
+
+// handleAddRecurring handles /addrecurring Название | часы | правило | приоритет
+func (h *BotHandler) handleAddRecurring(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	parts := strings.Split(msg.CommandArguments(), "|")
+	if len(parts) < 3 || len(parts) > 4 {
+		h.sendMessage(msg.Chat.ID, recurringUsage)
+		return
+	}
+
+	rt := &models.RecurringTask{UserID: user.ID, Priority: 5}
+	startDate := scheduleStartDate(user)
+	if errMsg := applyRecurringParts(rt, parts, startDate); errMsg != "" {
+		h.sendMessage(msg.Chat.ID, errMsg)
+		return
+	}
+	if rt.Title == "" {
+		h.sendMessage(msg.Chat.ID, recurringUsage)
+		return
+	}
+
+	if err := database.CreateRecurringTask(rt); err != nil {
+		log.Printf("Error creating recurring task: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при создании повторяющейся задачи")
+		return
+	}
+
+	created := h.materializeRecurringTasks(user)
+	rule, _ := scheduler.ParseRRULE(rt.RRule)
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("🔁 Повторяющаяся задача создана (шаблон ID:%d)\n\n📝 %s\n⏱ %g ч | ⭐️ %d\n🗓 %s\n\nСоздано задач в горизонте планирования: %d.\nЧтобы включить их в расписание, выполните /schedule.",
+		rt.ID, rt.Title, rt.HoursRequired, rt.Priority, describeRule(rule), created))
+}
+
+// handleRecurring handles /recurring — lists templates.
+func (h *BotHandler) handleRecurring(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	templates, err := database.GetRecurringTasks(user.ID)
+	if err != nil {
+		log.Printf("Error getting recurring tasks: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка получения повторяющихся задач")
+		return
+	}
+	if len(templates) == 0 {
+		h.sendMessage(msg.Chat.ID, "Повторяющихся задач нет.\n\n"+recurringUsage)
+		return
+	}
+
+	startDate := scheduleStartDate(user)
+	response := "🔁 Повторяющиеся задачи:\n\n"
+	for _, rt := range templates {
+		rule, err := scheduler.ParseRRULE(rt.RRule)
+		if err != nil {
+			continue
+		}
+		response += fmt.Sprintf("ID:%d | %s\n⏱ %g ч | ⭐️ %d | 🗓 %s", rt.ID, rt.Title, rt.HoursRequired, rt.Priority, describeRule(rule))
+		if next := scheduler.Occurrences(rule, startDate, scheduler.HorizonEndDate(startDate)); len(next) > 0 {
+			response += fmt.Sprintf("\n➡️ Следующая: %s", next[0].Format("02.01.2006"))
+		}
+		response += "\n\n"
+	}
+	response += "Изменить: /editrecurring ID | Название | часы | правило | приоритет (пустое поле — без изменений)\nУдалить: /deleterecurring ID"
+	h.sendMessage(msg.Chat.ID, response)
+}
+
+// handleEditRecurring handles /editrecurring ID | Название | часы | правило | приоритет.
+// Empty fields keep their value; future uncompleted instances follow the template.
+func (h *BotHandler) handleEditRecurring(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	parts := strings.Split(msg.CommandArguments(), "|")
+	id, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil || len(parts) < 2 || len(parts) > 5 {
+		h.sendMessage(msg.Chat.ID, "Формат: /editrecurring ID | Название | часы | правило | приоритет\nПустое поле оставляет значение без изменений.\nПример: /editrecurring 3 | | 3")
+		return
+	}
+
+	rt, err := database.GetRecurringTaskForUser(id, user.ID)
+	if err != nil || rt == nil {
+		h.sendMessage(msg.Chat.ID, "Повторяющаяся задача не найдена")
+		return
+	}
+
+	oldRule := rt.RRule
+	startDate := scheduleStartDate(user)
+	if errMsg := applyRecurringParts(rt, parts[1:], startDate); errMsg != "" {
+		h.sendMessage(msg.Chat.ID, errMsg)
+		return
+	}
+
+	if err := database.UpdateRecurringTask(rt, dateOnly(startDate), rt.RRule != oldRule); err != nil {
+		log.Printf("Error updating recurring task: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при обновлении повторяющейся задачи")
+		return
+	}
+	h.materializeRecurringTasks(user)
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Шаблон «%s» обновлён. Будущие невыполненные повторения изменены.\nЧтобы применить к расписанию, выполните /schedule.", rt.Title))
+}
+
+// handleDeleteRecurring handles /deleterecurring ID
+func (h *BotHandler) handleDeleteRecurring(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Формат: /deleterecurring ID (ID шаблона из /recurring)")
+		return
+	}
+
+	rt, err := database.GetRecurringTaskForUser(id, user.ID)
+	if err != nil || rt == nil {
+		h.sendMessage(msg.Chat.ID, "Повторяющаяся задача не найдена")
+		return
+	}
+
+	if err := database.DeleteRecurringTask(rt.ID, user.ID, dateOnly(scheduleStartDate(user))); err != nil {
+		log.Printf("Error deleting recurring task: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при удалении повторяющейся задачи")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("🗑 Шаблон «%s» удалён вместе с будущими невыполненными повторениями.\nВыполненные задачи остались в истории.", rt.Title))
+}
+
+// applyRecurringParts fills the template from "Название | часы | правило | приоритет".
+// Empty parts keep the current value. It returns a user-facing error message.
+func applyRecurringParts(rt *models.RecurringTask, parts []string, startDate time.Time) string {
+	if len(parts) > 0 && strings.TrimSpace(parts[0]) != "" {
+		rt.Title = strings.TrimSpace(parts[0])
+	}
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		hours, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || hours <= 0 {
+			return "⏱ Неверное количество часов.\nУкажите положительное число, например: 0.5, 1, 2.5"
+		}
+		rt.HoursRequired = hours
+	}
+	if len(parts) > 2 && strings.TrimSpace(parts[2]) != "" {
+		rule, err := parseRecurrenceSpec(strings.TrimSpace(parts[2]), startDate)
+		if err != nil {
+			return fmt.Sprintf("🗓 Не удалось разобрать правило: %v\n\n%s", err, recurringUsage)
+		}
+		rt.RRule = scheduler.FormatRRULE(rule)
+	}
+	if len(parts) > 3 && strings.TrimSpace(parts[3]) != "" {
+		priority, err := strconv.Atoi(strings.TrimSpace(parts[3]))
+		if err != nil || priority < 1 || priority > 10 {
+			return "⭐️ Приоритет должен быть целым числом от 1 до 10."
+		}
+		rt.Priority = priority
+	}
+	if rt.HoursRequired <= 0 || rt.RRule == "" {
+		return recurringUsage
+	}
+	return ""
+}
+
+// recurringBatch is the part of a template's occurrences that has no task rows yet.
+type recurringBatch struct {
+	template models.RecurringTask
+	dates    []time.Time
+	through  time.Time // horizon the template is materialized through once the dates are saved
+}
+
+// pendingOccurrences computes, per template, the occurrences up to the planning horizon that
+// have not been materialized yet. Nothing is written.
+func pendingOccurrences(user *models.User) []recurringBatch {
+	templates, err := database.GetRecurringTasks(user.ID)
+	if err != nil {
+		log.Printf("Error getting recurring tasks: %v", err)
+		return nil
+	}
+
+	startDate := scheduleStartDate(user)
+	horizonEnd := scheduler.HorizonEndDate(startDate)
+	startKey := startDate.Format("2006-01-02")
+
+	var batches []recurringBatch
+	for i := range templates {
+		rt := templates[i]
+		rule, err := scheduler.ParseRRULE(rt.RRule)
+		if err != nil {
+			log.Printf("recurring task %d: %v", rt.ID, err)
+			continue
+		}
+
+		from := startDate
+		if rt.MaterializedThru != nil && rt.MaterializedThru.Format("2006-01-02") >= startKey {
+			from = rt.MaterializedThru.AddDate(0, 0, 1)
+		}
+		batches = append(batches, recurringBatch{
+			template: rt,
+			dates:    scheduler.Occurrences(rule, from, horizonEnd),
+			through:  dateOnly(horizonEnd),
+		})
+	}
+	return batches
+}
+
+// materializeRecurringTasks creates task rows for all template occurrences up to the planning horizon.
+// It returns how many tasks were created.
+func (h *BotHandler) materializeRecurringTasks(user *models.User) int {
+	created := 0
+	for _, b := range pendingOccurrences(user) {
+		n, err := database.MaterializeRecurringTask(&b.template, b.dates, b.through)
+		if err != nil {
+			log.Printf("materialize recurring task %d: %v", b.template.ID, err)
+			continue
+		}
+		created += n
+	}
+	return created
+}
+
+// previewRecurringTasks returns the not yet materialized occurrences as unsaved tasks so that a
+// plan preview includes them without writing anything. They get negative IDs, assigned in a
+// stable order, so two previews of the same state agree.
+func previewRecurringTasks(user *models.User) []models.Task {
+	var tasks []models.Task
+	for _, b := range pendingOccurrences(user) {
+		for _, d := range b.dates {
+			day := d
+			templateID := b.template.ID
+			tasks = append(tasks, models.Task{
+				ID:            -int64(len(tasks) + 1),
+				UserID:        user.ID,
+				Title:         b.template.Title,
+				Description:   b.template.Description,
+				HoursRequired: b.template.HoursRequired,
+				Priority:      b.template.Priority,
+				Status:        "pending",
+				Deadline:      &day,
+				RecurringID:   &templateID,
+				Occurrence:    &day,
+			})
+		}
+	}
+	return tasks
+}
+
+// parseRecurrenceSpec parses "weekly пт until 31.12.2026", "daily 1-5", "monthly 15 every 2"
+// or an RRULE string. The rule starts at startDate unless DTSTART is given.
+func parseRecurrenceSpec(spec string, startDate time.Time) (models.RecurrenceRule, error) {
+	if strings.Contains(strings.ToUpper(spec), "FREQ=") {
+		rule, err := scheduler.ParseRRULE(spec)
+		if err != nil {
+			return rule, err
+		}
+		if rule.Start.IsZero() {
+			rule.Start = dateOnly(startDate)
+		}
+		return rule, nil
+	}
+
+	fields := strings.Fields(strings.ToLower(spec))
+	if len(fields) == 0 {
+		return models.RecurrenceRule{}, fmt.Errorf("пустое правило")
+	}
+
+	rule := models.RecurrenceRule{Interval: 1, Start: dateOnly(startDate)}
+	switch fields[0] {
+	case "daily", "ежедневно":
+		rule.Freq = "daily"
+	case "weekly", "еженедельно":
+		rule.Freq = "weekly"
+	case "monthly", "ежемесячно":
+		rule.Freq = "monthly"
+	default:
+		return rule, fmt.Errorf("неизвестная периодичность %q (daily, weekly, monthly)", fields[0])
+	}
+
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "every", "каждые":
+			n, err := fieldInt(fields, i+1)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("после every нужно число")
+			}
+			rule.Interval = n
+			i++
+		case "count", "раз":
+			n, err := fieldInt(fields, i+1)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("после count нужно число")
+			}
+			rule.Count = n
+			i++
+		case "until", "до":
+			if i+1 >= len(fields) {
+				return rule, fmt.Errorf("после until нужна дата")
+			}
+			until, err := parseDate(fields[i+1])
+			if err != nil {
+				return rule, fmt.Errorf("неверная дата %q", fields[i+1])
+			}
+			rule.Until = &until
+			i++
+		default:
+			if n, err := strconv.Atoi(fields[i]); err == nil && rule.Freq == "monthly" {
+				if n < 1 || n > 31 {
+					return rule, fmt.Errorf("день месяца должен быть от 1 до 31")
+				}
+				rule.MonthDay = n
+				continue
+			}
+			if rule.Freq == "monthly" {
+				return rule, fmt.Errorf("непонятный параметр %q", fields[i])
+			}
+			weekdays, err := parseWeekdaySpec(fields[i])
+			if err != nil {
+				return rule, fmt.Errorf("непонятный параметр %q", fields[i])
+			}
+			rule.Weekdays = weekdays
+		}
+	}
+	return rule, nil
+}
+
+// describeRule renders a rule in Russian, e.g. "каждые 2 недели: Пн, Пт, до 31.12.2026".
+func describeRule(rule models.RecurrenceRule) string {
+	units := map[string][2]string{
+		"daily":   {"каждый день", "дня"},
+		"weekly":  {"каждую неделю", "недели"},
+		"monthly": {"каждый месяц", "месяца"},
+	}
+	text := units[rule.Freq][0]
+	if rule.Interval > 1 {
+		text = fmt.Sprintf("раз в %d %s", rule.Interval, units[rule.Freq][1])
+	}
+	if len(rule.Weekdays) > 0 {
+		text += ": " + formatWorkDays(rule.Weekdays)
+	}
+	if rule.Freq == "monthly" {
+		day := rule.MonthDay
+		if day == 0 {
+			day = rule.Start.Day()
+		}
+		text += fmt.Sprintf(", %d-го числа", day)
+	}
+	if rule.Until != nil {
+		text += ", до " + rule.Until.Format("02.01.2006")
+	}
+	if rule.Count > 0 {
+		text += fmt.Sprintf(", всего %d раз", rule.Count)
+	}
+	return text
+}
+
+// collapseRecurringInstances keeps only the earliest open instance of every recurring template
+// and returns how many later open instances were hidden per template.
+func collapseRecurringInstances(tasks []models.Task) ([]models.Task, map[int64]int) {
+	earliest := make(map[int64]int)
+	for i := range tasks {
+		t := &tasks[i]
+		if t.RecurringID == nil || t.Occurrence == nil || t.Status == "completed" || t.Status == "cancelled" {
+			continue
+		}
+		if j, ok := earliest[*t.RecurringID]; !ok || t.Occurrence.Before(*tasks[j].Occurrence) {
+			earliest[*t.RecurringID] = i
+		}
+	}
+
+	hidden := make(map[int64]int)
+	out := make([]models.Task, 0, len(tasks))
+	for i := range tasks {
+		t := tasks[i]
+		if t.RecurringID != nil && t.Occurrence != nil && t.Status != "completed" && t.Status != "cancelled" && earliest[*t.RecurringID] != i {
+			hidden[*t.RecurringID]++
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, hidden
+}
+
+func fieldInt(fields []string, i int) (int, error) {
+	if i >= len(fields) {
+		return 0, fmt.Errorf("missing number")
+	}
+	return strconv.Atoi(fields[i])
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
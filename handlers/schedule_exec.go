@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
@@ -19,34 +22,104 @@ type scheduleOutcome struct {
 	timeAllocations  []models.SlotAllocation
 	scheduledCount   int
 	totalTasks       int
+	taskTitles       map[int64]string
+	unscheduledNotes map[int64]string  // why an unscheduled task did not fit, when known
+	stable           bool              // replanned against the saved plan
+	moves            []models.TaskMove // stable mode: tasks that moved off their days
+	aging            []string          // why long-waiting tasks moved up the planning queue
 	calendarSynced   bool
 	calendarSyncFail bool
 	syncErrorDetail  string
 }
 
 func (h *BotHandler) executeFullRebuild(chatID int64, user *models.User) {
+	h.executeRebuild(chatID, user, false)
+}
+
+// executeStableRebuild replans everything against the saved plan: tasks keep their days unless
+// constraints force a move, and only events of tasks whose times changed are re-exported.
+func (h *BotHandler) executeStableRebuild(chatID int64, user *models.User) {
+	h.executeRebuild(chatID, user, true)
+}
+
+// rebuildPlan is a computed rebuild of all active tasks that is not saved yet.
+type rebuildPlan struct {
+	stable          bool
+	startDate       time.Time
+	tasks           []models.Task
+	baseline        []models.DaySchedule // saved plan from startDate on
+	busy            []models.BusyInterval
+	result          *models.ScheduleResult
+	timeAllocations []models.SlotAllocation
+}
+
+func (h *BotHandler) executeRebuild(chatID int64, user *models.User, stable bool) {
+	plan, ok := h.buildRebuild(chatID, user, stable)
+	if !ok {
+		return
+	}
+	h.applyRebuild(chatID, user, plan)
+}
+
+// buildRebuild plans all active tasks without writing anything. Recurring occurrences that have
+// no task rows yet are planned as unsaved tasks; applyRebuild creates them. ok is false when the
+// user has already been told why there is nothing to plan.
+func (h *BotHandler) buildRebuild(chatID int64, user *models.User, stable bool) (*rebuildPlan, bool) {
 	tasks, err := database.GetActiveTasks(user.ID)
 	if err != nil {
 		log.Printf("Error getting active tasks: %v", err)
 		h.sendMessage(chatID, "Ошибка получения задач из базы.\nПопробуйте позже.")
-		return
+		return nil, false
 	}
+	tasks = append(tasks, previewRecurringTasks(user)...)
 	if len(tasks) == 0 {
 		h.sendMessage(chatID, "Нет активных задач для планирования.\nДобавьте задачу через /addtask.")
-		return
+		return nil, false
 	}
 
+	tasks = h.applyEstimateBias(user, tasks)
+
 	startDate := scheduleStartDate(user)
-	h.clearPlanBotCalendar(user)
+	baseline, err := database.GetAllUserSchedulesFrom(user.ID, startDate)
+	if err != nil {
+		log.Printf("Error loading existing schedules: %v", err)
+		h.sendMessage(chatID, "Ошибка чтения текущего расписания.")
+		return nil, false
+	}
 	busy := h.fetchCalendarBusy(user, startDate, true)
 	workSlots := scheduler.BuildWorkSlots(user, startDate, busy)
-	s := scheduler.NewSchedulerWithSlots(user, tasks, workSlots)
-	result := s.Schedule(startDate)
-	timeAllocations := scheduler.PlanTimeAllocations(user, result.DaySchedules, startDate, busy)
+	var result *models.ScheduleResult
+	if stable {
+		result = scheduler.ScheduleStable(user, tasks, workSlots, baseline, startDate)
+	} else {
+		result = scheduler.NewSchedulerWithSlots(user, tasks, workSlots).Schedule(startDate)
+	}
+
+	return &rebuildPlan{
+		stable:          stable,
+		startDate:       startDate,
+		tasks:           tasks,
+		baseline:        baseline,
+		busy:            busy,
+		result:          result,
+		timeAllocations: scheduler.PlanTimeAllocations(user, result.DaySchedules, startDate, busy),
+	}, true
+}
+
+// applyRebuild saves plan, replacing the schedules of its tasks, and exports it to Google Calendar.
+// Recurring occurrences the plan includes are materialized first.
+func (h *BotHandler) applyRebuild(chatID int64, user *models.User, plan *rebuildPlan) {
+	h.materializePlannedOccurrences(user, plan)
+	if !plan.stable {
+		h.clearPlanBotCalendar(user)
+	}
+	tasks, result := plan.tasks, plan.result
 
 	taskIDs := make([]int64, len(tasks))
+	taskTitles := make(map[int64]string, len(tasks))
 	for i := range tasks {
 		taskIDs[i] = tasks[i].ID
+		taskTitles[tasks[i].ID] = tasks[i].Title
 	}
 	if err := database.ClearTaskSchedules(taskIDs); err != nil {
 		log.Printf("clear task schedules: %v", err)
@@ -66,17 +139,146 @@ func (h *BotHandler) executeFullRebuild(chatID int64, user *models.User) {
 		}
 	}
 
+	notes := make(map[int64]string)
+	for _, unscheduledID := range result.UnscheduledTasks {
+		for i := range tasks {
+			if tasks[i].ID != unscheduledID {
+				continue
+			}
+			if note := startWindowNote(user, &tasks[i]); note != "" {
+				notes[unscheduledID] = note
+			}
+		}
+	}
+
 	outcome := scheduleOutcome{
-		modeLabel:       "полное перепланирование",
-		result:          result,
-		timeAllocations: timeAllocations,
-		scheduledCount:  len(tasks) - len(result.UnscheduledTasks),
-		totalTasks:      len(tasks),
+		modeLabel:        rebuildLabel(plan.stable),
+		result:           result,
+		timeAllocations:  plan.timeAllocations,
+		scheduledCount:   len(tasks) - len(result.UnscheduledTasks) - len(result.PastTasks),
+		totalTasks:       len(tasks) - len(result.PastTasks),
+		taskTitles:       taskTitles,
+		unscheduledNotes: notes,
+		aging:            agingNotes(user, tasks, plan.startDate),
+	}
+	if plan.stable {
+		outcome.stable = true
+		outcome.moves = scheduler.DiffPlans(plan.baseline, result.DaySchedules)
+		outcome.calendarSynced, outcome.calendarSyncFail, outcome.syncErrorDetail = h.syncGoogleCalendarChanged(user, taskIDs, plan.startDate, plan.timeAllocations)
+	} else {
+		outcome.calendarSynced, outcome.calendarSyncFail, outcome.syncErrorDetail = h.syncGoogleCalendar(user, plan.timeAllocations)
 	}
-	outcome.calendarSynced, outcome.calendarSyncFail, outcome.syncErrorDetail = h.syncGoogleCalendar(user, timeAllocations)
 	h.sendScheduleOutcome(chatID, user, &outcome)
 }
 
+// materializePlannedOccurrences creates the task rows of recurring occurrences the plan was built
+// with and moves the plan from their temporary negative IDs to the saved ones. An occurrence
+// whose row could not be found (its template was deleted meanwhile) is dropped from the plan.
+func (h *BotHandler) materializePlannedOccurrences(user *models.User, plan *rebuildPlan) {
+	unsaved := false
+	for i := range plan.tasks {
+		if plan.tasks[i].ID < 0 {
+			unsaved = true
+			break
+		}
+	}
+	if !unsaved {
+		return
+	}
+	h.materializeRecurringTasks(user)
+
+	ids := make(map[int64]int64)
+	kept := plan.tasks[:0]
+	for _, task := range plan.tasks {
+		if task.ID < 0 {
+			id, err := database.GetRecurringInstanceID(*task.RecurringID, *task.Occurrence)
+			if err != nil {
+				log.Printf("find recurring instance: %v", err)
+			}
+			ids[task.ID] = id
+			if id == 0 {
+				continue
+			}
+			task.ID = id
+		}
+		kept = append(kept, task)
+	}
+	plan.tasks = kept
+	remapPlanTaskIDs(plan, ids)
+}
+
+// remapPlanTaskIDs renames task IDs throughout a plan; an ID mapped to 0 is removed.
+func remapPlanTaskIDs(plan *rebuildPlan, ids map[int64]int64) {
+	rename := func(id int64) int64 {
+		if to, ok := ids[id]; ok {
+			return to
+		}
+		return id
+	}
+
+	result := plan.result
+	days := result.DaySchedules[:0]
+	for _, day := range result.DaySchedules {
+		infos := day.Tasks[:0]
+		for _, info := range day.Tasks {
+			if info.TaskID = rename(info.TaskID); info.TaskID == 0 {
+				continue
+			}
+			for j := range info.DependsOn {
+				info.DependsOn[j] = rename(info.DependsOn[j])
+			}
+			infos = append(infos, info)
+		}
+		if len(infos) == 0 {
+			continue
+		}
+		day.Tasks = infos
+		days = append(days, day)
+	}
+	result.DaySchedules = days
+
+	unscheduled := result.UnscheduledTasks[:0]
+	for _, id := range result.UnscheduledTasks {
+		if id = rename(id); id != 0 {
+			unscheduled = append(unscheduled, id)
+		}
+	}
+	result.UnscheduledTasks = unscheduled
+
+	allocations := plan.timeAllocations[:0]
+	for _, a := range plan.timeAllocations {
+		if a.TaskID < 0 {
+			if a.TaskID = rename(a.TaskID); a.TaskID == 0 {
+				continue
+			}
+		}
+		allocations = append(allocations, a)
+	}
+	plan.timeAllocations = allocations
+
+	for from, to := range ids {
+		if d, ok := result.Diagnoses[from]; ok {
+			delete(result.Diagnoses, from)
+			if to != 0 {
+				result.Diagnoses[to] = d
+			}
+		}
+		if blocker, ok := result.BlockedBy[from]; ok {
+			delete(result.BlockedBy, from)
+			if to != 0 {
+				result.BlockedBy[to] = blocker
+			}
+		}
+	}
+}
+
+func rebuildLabel(stable bool) string {
+	if stable {
+		return "бережное перепланирование"
+	}
+	return "полное перепланирование"
+}
+
 func (h *BotHandler) executeInsertTask(chatID int64, user *models.User, taskID int64) {
 	task, err := database.GetTaskByIDForUser(taskID, user.ID)
 	if err != nil || task == nil {
@@ -87,6 +289,11 @@ func (h *BotHandler) executeInsertTask(chatID int64, user *models.User, taskID i
 		h.sendMessage(chatID, "Эту задачу нельзя запланировать (уже завершена или отменена).")
 		return
 	}
+	task = rolledUpTask(task, user.ID)
+	if task.OpenSubtasks > 0 {
+		h.sendMessage(chatID, fmt.Sprintf("🧩 «%s» разбита на подзадачи (%d открыто) — планируются они, а не сама задача.\n\nВыполните «Перепланировать всё» или впишите подзадачи по одной.", task.Title, task.OpenSubtasks))
+		return
+	}
 
 	startDate := scheduleStartDate(user)
 	existing, err := database.GetAllUserSchedulesFrom(user.ID, startDate)
@@ -96,10 +303,25 @@ func (h *BotHandler) executeInsertTask(chatID int64, user *models.User, taskID i
 		return
 	}
 
+	if blocker := unplannedPredecessor(task, user.ID, existing); blocker != nil {
+		h.sendMessage(chatID, fmt.Sprintf("⛓ Задача «%s» ждёт «%s» (ID:%d), которая ещё не в расписании.\n\nСначала запланируйте её или выполните «Перепланировать всё».", task.Title, blocker.Title, blocker.ID))
+		return
+	}
+
+	task = &h.applyEstimateBias(user, []models.Task{*task})[0]
 	busy := h.fetchCalendarBusy(user, startDate, false)
 	newDays, ok := scheduler.ScheduleTaskIntoExisting(user, task, existing, startDate, busy)
+	if (!ok || len(newDays) == 0) && scheduler.IsPinned(task) {
+		h.sendMessage(chatID, fmt.Sprintf("⚠️ Не удалось закрепить «%s» на %s: это время уже занято другими задачами плана или раньше начала планирования (%s).\n\nВыполните «Перепланировать всё» — закреплённая задача встанет на своё время, остальные подвинутся.",
+			task.Title, task.PinnedStart.Format("02.01.2006 15:04"), startDate.Format("02.01.2006")))
+		return
+	}
 	if !ok || len(newDays) == 0 {
-		h.sendMessage(chatID, "⚠️ Не удалось вписать задачу в текущее расписание.\nСвободных слотов не хватает (дедлайн, загрузка или события в Google Calendar).\n\nПопробуйте «Перепланировать всё» — расписание будет пересобрано с нуля.")
+		response := "⚠️ Не удалось вписать задачу в текущее расписание.\nСвободных слотов не хватает (дедлайн, загрузка или события в Google Calendar)."
+		if note := startWindowNote(user, task); note != "" {
+			response += "\n" + note
+		}
+		h.sendMessage(chatID, response+"\n\nПопробуйте «Перепланировать всё» — расписание будет пересобрано с нуля.")
 		return
 	}
 
@@ -129,6 +351,39 @@ func (h *BotHandler) executeInsertTask(chatID int64, user *models.User, taskID i
 	h.sendScheduleOutcome(chatID, user, &outcome)
 }
 
+// startWindowNote explains a task that did not fit between its start_after and its deadline,
+// or returns "" when the task has no such window.
+func startWindowNote(user *models.User, task *models.Task) string {
+	if task.StartAfter == nil || task.Deadline == nil {
+		return ""
+	}
+	from := formatDateTime(*task.StartAfter)
+	deadline := formatDateTime(*task.Deadline)
+
+	if !task.StartAfter.Before(scheduler.DeadlineEnd(*task.Deadline)) {
+		return fmt.Sprintf("⏳ «%s»: начало (%s) позже дедлайна (%s)", task.Title, from, deadline)
+	}
+	window := scheduler.WindowHours(user, *task.StartAfter, *task.Deadline)
+	need := scheduler.RemainingHours(task)
+	if window < need {
+		return fmt.Sprintf("⏳ «%s»: между началом (%s) и дедлайном (%s) всего %.1f ч рабочего времени, а нужно %.1f ч — сдвиньте начало или дедлайн", task.Title, from, deadline, window, need)
+	}
+	return fmt.Sprintf("⏳ «%s»: окно с %s по %s (%.1f ч) занято другими задачами и событиями", task.Title, from, deadline, window)
+}
+
+// applyEstimateBias scales estimates by the user's learned bias when the setting is on.
+func (h *BotHandler) applyEstimateBias(user *models.User, tasks []models.Task) []models.Task {
+	if !user.InflateEstimates {
+		return tasks
+	}
+	samples, err := database.GetEstimateSamples(user.ID)
+	if err != nil {
+		log.Printf("Error loading estimate samples: %v", err)
+		return tasks
+	}
+	return scheduler.InflateEstimates(tasks, scheduler.ComputeEstimateBias(samples))
+}
+
 func shortenCalendarError(err error) string {
 	if err == nil {
 		return ""
@@ -155,13 +410,95 @@ func (h *BotHandler) syncGoogleCalendar(user *models.User, allocations []models.
 		return false, false, ""
 	}
 
-	if err := googlecal.SyncUserSchedule(ctx, client, user, allocations); err != nil {
+	if err := googlecal.SyncUserSchedule(ctx, client, user, withCalendarBreaks(user, allocations)); err != nil {
 		log.Printf("google calendar sync (full): %v", err)
 		return true, true, shortenCalendarError(err)
 	}
 	return true, false, ""
 }
 
+// syncGoogleCalendarChanged re-exports only the events of tasks whose blocks from startDate on
+// differ from the stored PlanBot events; taskIDs limits the check to the replanned tasks.
+func (h *BotHandler) syncGoogleCalendarChanged(user *models.User, taskIDs []int64, startDate time.Time, allocations []models.SlotAllocation) (synced, failed bool, detail string) {
+	ctx := context.Background()
+	client, err := googlecal.ClientForUser(ctx, user.ID)
+	if err != nil {
+		log.Printf("google calendar client: %v", err)
+		return false, true, shortenCalendarError(err)
+	}
+	if client == nil {
+		return false, false, ""
+	}
+
+	stored, err := database.GetPlanBotEventsFrom(user.ID, startDate)
+	if err != nil {
+		log.Printf("google calendar stored events: %v", err)
+		return false, true, shortenCalendarError(err)
+	}
+	// Break events have no task (ID 0): compare them too, so they follow the plan or go away
+	// once export is turned off
+	taskIDs = append(append([]int64(nil), taskIDs...), 0)
+	stale, fresh := changedCalendarEvents(taskIDs, stored, withCalendarBreaks(user, allocations))
+	if len(stale) == 0 && len(fresh) == 0 {
+		return true, false, ""
+	}
+	if err := googlecal.ReplaceEvents(ctx, client, user, stale, fresh); err != nil {
+		log.Printf("google calendar sync (changed): %v", err)
+		return true, true, shortenCalendarError(err)
+	}
+	return true, false, ""
+}
+
+// withCalendarBreaks adds the breaks between work blocks when the user exports them.
+func withCalendarBreaks(user *models.User, allocations []models.SlotAllocation) []models.SlotAllocation {
+	if !user.ExportBreaks {
+		return allocations
+	}
+	breaks := scheduler.BreakAllocations(user, allocations)
+	return append(append(make([]models.SlotAllocation, 0, len(allocations)+len(breaks)), allocations...), breaks...)
+}
+
+// changedCalendarEvents compares each task's stored events with its new blocks (by wall clock)
+// and returns the events to delete and the blocks to export for tasks that differ.
+func changedCalendarEvents(taskIDs []int64, stored []models.GoogleCalendarEvent, allocations []models.SlotAllocation) (stale []string, fresh []models.SlotAllocation) {
+	const layout = "2006-01-02 15:04"
+	replanned := make(map[int64]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		replanned[id] = true
+	}
+	was := make(map[int64][]string)
+	for _, ev := range stored {
+		if replanned[ev.TaskID] {
+			was[ev.TaskID] = append(was[ev.TaskID], ev.StartTime.Format(layout)+"-"+ev.EndTime.Format(layout))
+		}
+	}
+	now := make(map[int64][]string)
+	for _, a := range allocations {
+		now[a.TaskID] = append(now[a.TaskID], a.Start.Format(layout)+"-"+a.End.Format(layout))
+	}
+
+	changed := make(map[int64]bool)
+	for id := range replanned {
+		before, after := was[id], now[id]
+		sort.Strings(before)
+		sort.Strings(after)
+		if strings.Join(before, ",") != strings.Join(after, ",") {
+			changed[id] = true
+		}
+	}
+	for _, ev := range stored {
+		if changed[ev.TaskID] {
+			stale = append(stale, ev.GoogleEventID)
+		}
+	}
+	for _, a := range allocations {
+		if changed[a.TaskID] {
+			fresh = append(fresh, a)
+		}
+	}
+	return stale, fresh
+}
+
 func (h *BotHandler) syncGoogleCalendarAppend(user *models.User, allocations []models.SlotAllocation) (synced, failed bool, detail string) {
 	if len(allocations) == 0 {
 		return false, false, ""
@@ -190,7 +527,7 @@ func (h *BotHandler) sendScheduleOutcome(chatID int64, user *models.User, o *sch
 		response += fmt.Sprintf("📊 %s\n", o.result.Message)
 	}
 	response += fmt.Sprintf("📌 Запланировано задач: %d", o.scheduledCount)
-	if o.totalTasks > 1 || o.modeLabel == "полное перепланирование" {
+	if o.totalTasks > 1 || o.modeLabel == "полное перепланирование" || o.stable {
 		response += fmt.Sprintf(" из %d", o.totalTasks)
 	}
 	response += "\n"
@@ -198,6 +535,8 @@ func (h *BotHandler) sendScheduleOutcome(chatID int64, user *models.User, o *sch
 	if o.calendarSynced && !o.calendarSyncFail {
 		if o.modeLabel == "вписывание в текущее расписание" {
 			response += "📆 В Google Calendar добавлены события новой задачи (старые не тронуты).\n"
+		} else if o.stable {
+			response += "📆 В Google Calendar обновлены только события сдвинутых и новых задач.\n"
 		} else {
 			response += "📆 Google Calendar обновлён (учтены ваши события, расписание PlanBot перезаписано).\n"
 		}
@@ -217,12 +556,30 @@ func (h *BotHandler) sendScheduleOutcome(chatID int64, user *models.User, o *sch
 				response += fmt.Sprintf("\n... и ещё %d дней", len(daySchedules)-7)
 				break
 			}
-			response += formatDayScheduleWithTimes(daySchedule, user.DailyCapacity, o.timeAllocations)
+			response += formatDayScheduleWithTimes(daySchedule, scheduler.DailyCapacityOn(user, daySchedule.Date), o.timeAllocations)
 		}
 	}
 
+	if o.stable {
+		response += formatTaskMoves(o.moves)
+	}
+	response += formatPreferenceFallbacks(o.timeAllocations)
+	if len(o.aging) > 0 {
+		response += "\n\n⏫ Подняты в очереди, потому что давно ждут:\n" + strings.Join(o.aging, "\n")
+	}
+
 	if o.result != nil && len(o.result.UnscheduledTasks) > 0 {
 		response += fmt.Sprintf("\n\n⚠️ Не удалось запланировать %d задач(и)", len(o.result.UnscheduledTasks))
+		for _, taskID := range o.result.UnscheduledTasks {
+			if note, ok := o.unscheduledNotes[taskID]; ok {
+				response += "\n" + note
+			} else if blocker, ok := o.result.BlockedBy[taskID]; ok {
+				response += fmt.Sprintf("\n⛓ «%s» заблокирована задачей «%s» (ID:%d)", o.titleOf(taskID), o.titleOf(blocker), blocker)
+			}
+			if d, ok := o.result.Diagnoses[taskID]; ok {
+				response += "\n" + formatDiagnosis(o.titleOf(taskID), d)
+			}
+		}
 	}
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
@@ -234,15 +591,98 @@ func (h *BotHandler) sendScheduleOutcome(chatID int64, user *models.User, o *sch
 	h.sendMessageWithReplyMarkup(chatID, response, &keyboard)
 }
 
+// formatTaskMoves lists tasks that moved against the previous plan.
+func formatTaskMoves(moves []models.TaskMove) string {
+	if len(moves) == 0 {
+		return "\n\n🧷 Задачи прежнего плана остались на своих днях."
+	}
+	response := fmt.Sprintf("\n\n🔀 Сдвинуто задач: %d", len(moves))
+	for _, m := range moves {
+		shift := "начало в тот же день"
+		switch {
+		case m.ShiftDays > 0:
+			shift = fmt.Sprintf("начало на %d дн. позже", m.ShiftDays)
+		case m.ShiftDays < 0:
+			shift = fmt.Sprintf("начало на %d дн. раньше", -m.ShiftDays)
+		}
+		response += fmt.Sprintf("\n• «%s» (ID:%d): %.1f ч на другие дни, %s", m.Title, m.TaskID, m.MovedHours, shift)
+	}
+	return response
+}
+
+// formatPreferenceFallbacks lists blocks placed outside their task's preferred time because it was full.
+func formatPreferenceFallbacks(allocations []models.SlotAllocation) string {
+	var lines []string
+	for _, a := range allocations {
+		if a.OffPreference {
+			lines = append(lines, fmt.Sprintf("• «%s» %s %s–%s", a.Title, a.Start.Format("02.01"), a.Start.Format("15:04"), a.End.Format("15:04")))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n🕘 Не в предпочитаемое время (оно уже занято):\n" + strings.Join(lines, "\n")
+}
+
+// agingNotes explains, for each task whose planning order rose with waiting, how far it rose.
+func agingNotes(user *models.User, tasks []models.Task, now time.Time) []string {
+	var notes []string
+	rolled := scheduler.RollUpSubtasks(tasks)
+	for _, task := range scheduler.AgedTasks(user, rolled, now) {
+		if task.OpenSubtasks > 0 {
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("• «%s» (ID:%d): ждёт %s", task.Title, task.ID, describeAging(user, &task, now)))
+	}
+	return notes
+}
+
+func (o *scheduleOutcome) titleOf(taskID int64) string {
+	if title, ok := o.taskTitles[taskID]; ok {
+		return title
+	}
+	return fmt.Sprintf("Задача #%d", taskID)
+}
+
+// unplannedPredecessor returns an active predecessor of task that has no hours in the saved plan.
+func unplannedPredecessor(task *models.Task, userID int64, existing []models.DaySchedule) *models.Task {
+	planned := make(map[int64]bool)
+	for _, day := range existing {
+		for _, info := range day.Tasks {
+			planned[info.TaskID] = true
+		}
+	}
+	for _, id := range task.DependsOn {
+		if planned[id] {
+			continue
+		}
+		pred, err := database.GetTaskByIDForUser(id, userID)
+		if err != nil || pred == nil {
+			continue
+		}
+		if pred.Status != "completed" && pred.Status != "cancelled" {
+			return pred
+		}
+	}
+	return nil
+}
+
 func planChoiceKeyboard(taskID int64, hasExisting bool) tgbotapi.InlineKeyboardMarkup {
 	insertLabel := "📎 Вписать в расписание"
 	if !hasExisting {
 		insertLabel = "📎 Запланировать задачу"
 	}
-	return tgbotapi.NewInlineKeyboardMarkup(
+	rows := [][]tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(insertLabel, fmt.Sprintf("plan_insert:%d", taskID)),
 		),
+	}
+	if hasExisting {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧷 Перепланировать бережно", fmt.Sprintf("plan_stable:%d", taskID)),
+		))
+	}
+	rows = append(rows,
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🔄 Перепланировать всё", fmt.Sprintf("plan_rebuild:%d", taskID)),
 		),
@@ -250,4 +690,5 @@ func planChoiceKeyboard(taskID int64, hasExisting bool) tgbotapi.InlineKeyboardM
 			tgbotapi.NewInlineKeyboardButtonData("⏭ Позже", fmt.Sprintf("plan_skip:%d", taskID)),
 		),
 	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
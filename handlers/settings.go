@@ -0,0 +1,712 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+// handleSettingsSubcommand routes "/settings <keyword> ..." forms.
+// It returns false when the arguments are the classic "часы | дни | время" format.
+func (h *BotHandler) handleSettingsSubcommand(chatID int64, user *models.User, args string) bool {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), fields[0]))
+
+	switch strings.ToLower(fields[0]) {
+	case "hours":
+		h.handleSettingsHours(chatID, user, rest)
+	case "chunk":
+		h.handleSettingsChunk(chatID, user, rest)
+	case "estimates":
+		h.handleSettingsEstimates(chatID, user, rest)
+	case "strategy":
+		h.handleSettingsStrategy(chatID, user, rest)
+	case "breaks":
+		h.handleSettingsBreaks(chatID, user, rest)
+	case "lunch":
+		h.handleSettingsLunch(chatID, user, rest)
+	case "buffer":
+		h.handleSettingsBuffer(chatID, user, rest)
+	case "travel":
+		h.handleSettingsTravel(chatID, user, rest)
+	case "focus":
+		h.handleSettingsFocus(chatID, user, rest)
+	case "energy":
+		h.handleSettingsEnergy(chatID, user, rest)
+	case "aging":
+		h.handleSettingsAging(chatID, user, rest)
+	default:
+		return false
+	}
+	return true
+}
+
+// handleSettingsChunk sets the default minimum session length and per-day cap for tasks.
+// Формат: /settings chunk МИНУТЫ [ЧАСОВ_В_ДЕНЬ]; 0 снимает ограничение.
+func (h *BotHandler) handleSettingsChunk(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings chunk МИНУТЫ [ЧАСОВ_В_ДЕНЬ]\nПримеры:\n/settings chunk 60 — задачи блоками не короче часа\n/settings chunk 90 3 — блоки от 90 минут, не больше 3 ч одной задачи в день\n/settings chunk 0 0 — без ограничений\n\nДля отдельной задачи: /edittask ID chunk=120 maxday=2"
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		h.sendMessage(chatID, usage)
+		return
+	}
+	minutes, err := parseChunkMinutes(fields[0])
+	if err != nil {
+		h.sendMessage(chatID, err.Error()+"\n\n"+usage)
+		return
+	}
+	maxPerDay := user.MaxTaskHoursPerDay
+	if len(fields) == 2 {
+		maxPerDay, err = strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", "."), 64)
+		if err != nil || maxPerDay < 0 || maxPerDay > 24 {
+			h.sendMessage(chatID, "Часов в день — число от 0 до 24.\n\n"+usage)
+			return
+		}
+	}
+
+	if err := database.UpdateUserChunkDefaults(user.ID, minutes, maxPerDay); err != nil {
+		log.Printf("Error updating chunk defaults: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.MinChunkMinutes, user.MaxTaskHoursPerDay = minutes, maxPerDay
+	h.sendMessage(chatID, fmt.Sprintf("✅ Блоки задач: %s\nПерепланировать: /schedule", formatChunkDefaults(user)))
+}
+
+func formatChunkDefaults(user *models.User) string {
+	text := "любой длины"
+	if user.MinChunkMinutes > 0 {
+		text = fmt.Sprintf("от %d мин", user.MinChunkMinutes)
+	}
+	if user.MaxTaskHoursPerDay > 0 {
+		text += fmt.Sprintf(", до %g ч одной задачи в день", user.MaxTaskHoursPerDay)
+	}
+	return text
+}
+
+// handleSettingsEstimates turns scaling of estimates by the learned bias on or off.
+func (h *BotHandler) handleSettingsEstimates(chatID int64, user *models.User, args string) {
+	var enabled bool
+	switch strings.ToLower(args) {
+	case "on", "вкл":
+		enabled = true
+	case "off", "выкл":
+		enabled = false
+	default:
+		h.sendMessage(chatID, "Формат: /settings estimates on|off\nПри включении оценки задач умножаются на ваш коэффициент точности (/stats estimates).")
+		return
+	}
+
+	if err := database.UpdateUserInflateEstimates(user.ID, enabled); err != nil {
+		log.Printf("Error updating estimate inflation: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	if enabled {
+		h.sendMessage(chatID, "✅ Оценки будут корректироваться по фактическому времени. Перепланировать: /schedule")
+	} else {
+		h.sendMessage(chatID, "✅ Коррекция оценок выключена. Перепланировать: /schedule")
+	}
+}
+
+// strategyDescriptions explains each scheduling strategy in /settings.
+var strategyDescriptions = map[string]string{
+	scheduler.StrategyGreedy:   "задачи с дедлайном — ближе к сроку, остальные — как можно раньше",
+	scheduler.StrategyASAP:     "все задачи как можно раньше",
+	scheduler.StrategyBalanced: "равномерная нагрузка по дням",
+	scheduler.StrategyOptimal:  "перебор порядка задач, чтобы успеть к как можно большему числу дедлайнов",
+}
+
+// handleSettingsStrategy selects how tasks are spread over days.
+// Формат: /settings strategy greedy|asap|balanced|optimal
+func (h *BotHandler) handleSettingsStrategy(chatID int64, user *models.User, args string) {
+	name := strings.ToLower(strings.TrimSpace(args))
+	if _, ok := strategyDescriptions[name]; !ok {
+		usage := fmt.Sprintf("Формат: /settings strategy НАЗВАНИЕ\nСейчас: %s\n", formatStrategy(user))
+		for _, n := range scheduler.StrategyNames {
+			usage += fmt.Sprintf("\n%s — %s", n, strategyDescriptions[n])
+		}
+		h.sendMessage(chatID, usage)
+		return
+	}
+
+	if err := database.UpdateUserSchedulingStrategy(user.ID, name); err != nil {
+		log.Printf("Error updating scheduling strategy: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.SchedulingStrategy = name
+	h.sendMessage(chatID, fmt.Sprintf("✅ Стратегия планирования: %s\nПерепланировать: /schedule", formatStrategy(user)))
+}
+
+// formatStrategy renders the user's strategy with its description.
+func formatStrategy(user *models.User) string {
+	name := scheduler.StrategyByName(user.SchedulingStrategy).Name()
+	return fmt.Sprintf("%s (%s)", name, strategyDescriptions[name])
+}
+
+const breaksUsage = `Формат: /settings breaks РАБОТА/ПЕРЕРЫВ | pomodoro | off
+Примеры:
+/settings breaks 90/10 — 10 минут перерыва после каждых 90 минут работы
+/settings breaks pomodoro — 25/5
+/settings breaks off — без коротких перерывов
+/settings breaks export on|off — выгружать перерывы в Google Calendar
+Обед: /settings lunch 13:00-14:00`
+
+// handleSettingsBreaks sets the short-break rule or toggles break export to Google Calendar.
+// Формат: /settings breaks 90/10 | pomodoro | off | export on|off
+func (h *BotHandler) handleSettingsBreaks(chatID int64, user *models.User, args string) {
+	every, length, export := user.BreakEveryMinutes, user.BreakMinutes, user.ExportBreaks
+	fields := strings.Fields(strings.ToLower(args))
+	switch {
+	case len(fields) == 2 && fields[0] == "export":
+		switch fields[1] {
+		case "on", "вкл":
+			export = true
+		case "off", "выкл":
+			export = false
+		default:
+			h.sendMessage(chatID, breaksUsage)
+			return
+		}
+	case len(fields) == 1:
+		var err error
+		every, length, err = parseBreakRule(fields[0])
+		if err != nil {
+			h.sendMessage(chatID, err.Error()+"\n\n"+breaksUsage)
+			return
+		}
+	default:
+		h.sendMessage(chatID, fmt.Sprintf("Сейчас: %s\n\n%s", formatBreaks(user), breaksUsage))
+		return
+	}
+
+	if err := database.UpdateUserBreaks(user.ID, every, length, user.LunchStart, user.LunchEnd, export); err != nil {
+		log.Printf("Error updating breaks: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.BreakEveryMinutes, user.BreakMinutes, user.ExportBreaks = every, length, export
+	h.sendMessage(chatID, fmt.Sprintf("✅ Перерывы: %s\nПерепланировать: /schedule", formatBreaks(user)))
+}
+
+// handleSettingsLunch sets or clears the fixed lunch window.
+// Формат: /settings lunch HH:MM-HH:MM | off
+func (h *BotHandler) handleSettingsLunch(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings lunch HH:MM-HH:MM | off\nПример: /settings lunch 13:00-14:00"
+
+	var start, end string
+	spec := strings.ToLower(strings.TrimSpace(args))
+	switch spec {
+	case "":
+		h.sendMessage(chatID, usage)
+		return
+	case "off", "выкл":
+	default:
+		windows, err := parseWorkWindows(spec)
+		if err != nil || len(windows) != 1 {
+			h.sendMessage(chatID, "Укажите одно окно обеда.\n\n"+usage)
+			return
+		}
+		start, end = windows[0].Start, windows[0].End
+	}
+
+	if err := database.UpdateUserBreaks(user.ID, user.BreakEveryMinutes, user.BreakMinutes, start, end, user.ExportBreaks); err != nil {
+		log.Printf("Error updating lunch window: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.LunchStart, user.LunchEnd = start, end
+	h.sendMessage(chatID, fmt.Sprintf("✅ Перерывы: %s\nПерепланировать: /schedule", formatBreaks(user)))
+}
+
+// parseBreakRule parses "90/10" (minutes of work / minutes of break), "pomodoro" or "off".
+func parseBreakRule(spec string) (every, length int, err error) {
+	switch strings.ToLower(spec) {
+	case "off", "выкл", "0":
+		return 0, 0, nil
+	case "pomodoro", "помодоро":
+		return scheduler.PomodoroWorkMinutes, scheduler.PomodoroBreakMinutes, nil
+	}
+	workStr, breakStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("не понял правило перерывов %q", spec)
+	}
+	every, errWork := strconv.Atoi(workStr)
+	length, errBreak := strconv.Atoi(breakStr)
+	if errWork != nil || errBreak != nil || every < 10 || every > 480 || length < 1 || length > 120 {
+		return 0, 0, fmt.Errorf("работа — от 10 до 480 минут, перерыв — от 1 до 120 минут")
+	}
+	return every, length, nil
+}
+
+// formatBreaks renders the short-break rule, the lunch window and the export flag.
+func formatBreaks(user *models.User) string {
+	parts := []string{"без коротких перерывов"}
+	if user.BreakEveryMinutes > 0 && user.BreakMinutes > 0 {
+		parts[0] = fmt.Sprintf("%d мин каждые %d мин работы", user.BreakMinutes, user.BreakEveryMinutes)
+	}
+	if user.LunchStart != "" && user.LunchEnd != "" {
+		parts = append(parts, fmt.Sprintf("обед %s-%s", user.LunchStart, user.LunchEnd))
+	}
+	if user.ExportBreaks {
+		parts = append(parts, "в Google Calendar")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleSettingsBuffer sets the free minutes kept before and after calendar meetings.
+// Формат: /settings buffer ДО [ПОСЛЕ]; 0 убирает буфер.
+func (h *BotHandler) handleSettingsBuffer(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings buffer МИНУТ_ДО [МИНУТ_ПОСЛЕ]\nПримеры:\n/settings buffer 10 — 10 минут до и после встреч\n/settings buffer 5 15 — 5 минут до, 15 после\n/settings buffer 0 — без буферов\n\nДорога к встречам с адресом: /settings travel 30"
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		h.sendMessage(chatID, fmt.Sprintf("Сейчас: %s\n\n%s", formatBuffers(user), usage))
+		return
+	}
+	before, err := parseBufferMinutes(fields[0])
+	if err != nil {
+		h.sendMessage(chatID, err.Error()+"\n\n"+usage)
+		return
+	}
+	after := before
+	if len(fields) == 2 {
+		if after, err = parseBufferMinutes(fields[1]); err != nil {
+			h.sendMessage(chatID, err.Error()+"\n\n"+usage)
+			return
+		}
+	}
+
+	if err := database.UpdateUserBuffers(user.ID, before, after, user.TravelMinutes); err != nil {
+		log.Printf("Error updating meeting buffers: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.BufferBefore, user.BufferAfter = before, after
+	h.sendMessage(chatID, fmt.Sprintf("✅ Буферы вокруг встреч: %s\nПерепланировать: /schedule", formatBuffers(user)))
+}
+
+// handleSettingsTravel sets the travel time kept around meetings that have a place as location.
+// Формат: /settings travel МИНУТЫ; 0 убирает.
+func (h *BotHandler) handleSettingsTravel(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings travel МИНУТЫ\nВремя на дорогу до и после встреч, у которых в календаре указано место (ссылки на созвоны не считаются).\nПример: /settings travel 30; 0 — без учёта дороги"
+
+	fields := strings.Fields(args)
+	if len(fields) != 1 {
+		h.sendMessage(chatID, usage)
+		return
+	}
+	travel, err := parseBufferMinutes(fields[0])
+	if err != nil {
+		h.sendMessage(chatID, err.Error()+"\n\n"+usage)
+		return
+	}
+
+	if err := database.UpdateUserBuffers(user.ID, user.BufferBefore, user.BufferAfter, travel); err != nil {
+		log.Printf("Error updating travel time: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.TravelMinutes = travel
+	h.sendMessage(chatID, fmt.Sprintf("✅ Буферы вокруг встреч: %s\nПерепланировать: /schedule", formatBuffers(user)))
+}
+
+// handleSettingsFocus sets the context-switch options: grouping a day's tasks by project and
+// the limit of distinct tasks per day.
+// Формат: /settings focus [on|off] [N]; off без числа выключает и группировку, и лимит.
+func (h *BotHandler) handleSettingsFocus(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings focus [on|off] [ЗАДАЧ_В_ДЕНЬ]\nПримеры:\n/settings focus on — задачи одного проекта идут в дне подряд\n/settings focus 3 — не больше 3 разных задач в день\n/settings focus on 2 — и то и другое\n/settings focus off — без группировки и лимита\n\nЗадачи с дедлайном могут превысить лимит, если иначе не успеть; встречи (at=) не считаются."
+
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 || len(fields) > 2 {
+		h.sendMessage(chatID, fmt.Sprintf("Сейчас: %s\n\n%s", formatFocus(user), usage))
+		return
+	}
+	cluster, maxTasks := user.ClusterProjects, user.MaxTasksPerDay
+	limitSet := false
+	for _, field := range fields {
+		switch field {
+		case "on", "вкл":
+			cluster = true
+		case "off", "выкл":
+			cluster = false
+		default:
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 || n > 24 {
+				h.sendMessage(chatID, "Задач в день — целое число от 0 до 24 (0 — без лимита).\n\n"+usage)
+				return
+			}
+			maxTasks, limitSet = n, true
+		}
+	}
+	if len(fields) == 1 && (fields[0] == "off" || fields[0] == "выкл") && !limitSet {
+		maxTasks = 0
+	}
+
+	if err := database.UpdateUserFocus(user.ID, cluster, maxTasks); err != nil {
+		log.Printf("Error updating focus settings: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.ClusterProjects, user.MaxTasksPerDay = cluster, maxTasks
+	h.sendMessage(chatID, fmt.Sprintf("✅ Фокус: %s\nПерепланировать: /schedule", formatFocus(user)))
+}
+
+func formatFocus(user *models.User) string {
+	parts := []string{"задачи проектов вперемешку"}
+	if user.ClusterProjects {
+		parts[0] = "задачи одного проекта подряд"
+	}
+	if user.MaxTasksPerDay > 0 {
+		parts = append(parts, fmt.Sprintf("не больше %d задач в день", user.MaxTasksPerDay))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleSettingsEnergy sets the user's energy curve and the daily cap on high-energy hours.
+// Формат: /settings energy [КРИВАЯ] [cap ЧАСЫ]; off убирает и кривую, и лимит.
+func (h *BotHandler) handleSettingsEnergy(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings energy [КРИВАЯ] [cap ЧАСЫ]\nПримеры:\n/settings energy high 09:00-12:00 low after 16:00 — пик утром, спад после 16:00 (остальное — средняя)\n/settings energy cap 3 — не больше 3 ч задач с energy=high в день\n/settings energy high before 12:00 cap 4 — и то и другое\n/settings energy off — без кривой и лимита\n\nЭнергию задачи задают так: /edittask ID energy=high|medium|low"
+
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 {
+		h.sendMessage(chatID, fmt.Sprintf("Сейчас: %s\n\n%s", formatEnergySettings(user), usage))
+		return
+	}
+	curve, maxHigh := user.EnergyCurve, user.MaxHighEnergyHours
+	if len(fields) == 1 && (fields[0] == "off" || fields[0] == "выкл") {
+		curve, maxHigh = "", 0
+		fields = nil
+	}
+	if n := len(fields); n >= 2 && fields[n-2] == "cap" {
+		hours, err := strconv.ParseFloat(strings.ReplaceAll(fields[n-1], ",", "."), 64)
+		if fields[n-1] == "off" {
+			hours, err = 0, nil
+		}
+		if err != nil || hours < 0 || hours > 24 {
+			h.sendMessage(chatID, "Лимит — часы в день от 0 до 24 (0 или off — без лимита).\n\n"+usage)
+			return
+		}
+		maxHigh = hours
+		fields = fields[:n-2]
+	}
+	if len(fields) > 0 {
+		windows, err := scheduler.ParseEnergyCurve(strings.Join(fields, " "))
+		if err != nil {
+			h.sendMessage(chatID, "Неверная кривая энергии: укажите уровень (high, medium, low) и окна ЧЧ:ММ-ЧЧ:ММ, after ЧЧ:ММ или before ЧЧ:ММ; окна не должны пересекаться.\n\n"+usage)
+			return
+		}
+		curve = scheduler.FormatEnergyCurve(windows)
+	}
+
+	if err := database.UpdateUserEnergy(user.ID, curve, maxHigh); err != nil {
+		log.Printf("Error updating energy settings: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.EnergyCurve, user.MaxHighEnergyHours = curve, maxHigh
+	h.sendMessage(chatID, fmt.Sprintf("✅ Энергия: %s\nПерепланировать: /schedule", formatEnergySettings(user)))
+}
+
+func formatEnergySettings(user *models.User) string {
+	parts := []string{"кривая не задана"}
+	if windows, err := scheduler.ParseEnergyCurve(user.EnergyCurve); user.EnergyCurve != "" && err == nil {
+		parts[0] = describeEnergyCurve(windows)
+	}
+	if user.MaxHighEnergyHours > 0 {
+		parts = append(parts, fmt.Sprintf("не больше %g ч тяжёлой работы в день", user.MaxHighEnergyHours))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeEnergyCurve renders a curve in words: "высокая 09:00–12:00, низкая после 16:00".
+func describeEnergyCurve(windows []models.EnergyWindow) string {
+	clock := func(minute int) string { return fmt.Sprintf("%02d:%02d", minute/60, minute%60) }
+	var parts []string
+	for _, w := range windows {
+		switch {
+		case w.From > 0 && w.Until > 0:
+			parts = append(parts, fmt.Sprintf("%s %s–%s", energyNames[w.Level], clock(w.From), clock(w.Until)))
+		case w.Until > 0:
+			parts = append(parts, fmt.Sprintf("%s до %s", energyNames[w.Level], clock(w.Until)))
+		default:
+			parts = append(parts, fmt.Sprintf("%s после %s", energyNames[w.Level], clock(w.From)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleSettingsAging sets priority aging for tasks without a deadline.
+// Формат: /settings aging ДНИ; 0 или off выключает.
+func (h *BotHandler) handleSettingsAging(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings aging ДНИ\nЗадача без дедлайна получает +1 к приоритету за каждые N дней ожидания (до 10). Если она ждёт ещё N дней после 10, то встаёт в очередь наравне с задачами с дедлайном, чтобы не откладываться бесконечно.\nПример: /settings aging 7; off — выключить\n\nТекущий приоритет с учётом ожидания виден в /mytasks."
+
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) != 1 {
+		h.sendMessage(chatID, fmt.Sprintf("Сейчас: %s\n\n%s", formatAgingSettings(user), usage))
+		return
+	}
+	days := 0
+	if fields[0] != "off" && fields[0] != "выкл" {
+		n, err := strconv.Atoi(strings.TrimSuffix(fields[0], "д"))
+		if err != nil || n < 0 || n > 365 {
+			h.sendMessage(chatID, "Дни — целое число от 0 до 365 (0 или off — выключить).\n\n"+usage)
+			return
+		}
+		days = n
+	}
+
+	if err := database.UpdateUserAging(user.ID, days); err != nil {
+		log.Printf("Error updating priority aging: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+	user.PriorityAgingDays = days
+	h.sendMessage(chatID, fmt.Sprintf("✅ Старение приоритета: %s\nПерепланировать: /schedule", formatAgingSettings(user)))
+}
+
+func formatAgingSettings(user *models.User) string {
+	if user.PriorityAgingDays <= 0 {
+		return "выключено"
+	}
+	return fmt.Sprintf("+1 к приоритету задачи без дедлайна каждые %d дн. ожидания", user.PriorityAgingDays)
+}
+
+func parseBufferMinutes(value string) (int, error) {
+	minutes, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(value), "мин"))
+	if err != nil || minutes < 0 || minutes > 240 {
+		return 0, fmt.Errorf("неверное число минут %q: укажите от 0 до 240", value)
+	}
+	return minutes, nil
+}
+
+// formatBuffers renders the meeting buffers and the travel time.
+func formatBuffers(user *models.User) string {
+	var parts []string
+	switch {
+	case user.BufferBefore > 0 && user.BufferBefore == user.BufferAfter:
+		parts = append(parts, fmt.Sprintf("%d мин до и после встреч", user.BufferBefore))
+	case user.BufferBefore > 0 || user.BufferAfter > 0:
+		parts = append(parts, fmt.Sprintf("%d мин до, %d мин после встреч", user.BufferBefore, user.BufferAfter))
+	}
+	if user.TravelMinutes > 0 {
+		parts = append(parts, fmt.Sprintf("дорога %d мин", user.TravelMinutes))
+	}
+	if len(parts) == 0 {
+		return "нет"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleSettingsHours edits the weekly availability template.
+// Формат: /settings hours ДНИ HH:MM-HH:MM[,HH:MM-HH:MM] | /settings hours [ДНИ] reset
+func (h *BotHandler) handleSettingsHours(chatID int64, user *models.User, args string) {
+	usage := "Формат: /settings hours ДНИ HH:MM-HH:MM[,HH:MM-HH:MM]\nПримеры:\n/settings hours 5 10:00-15:00\n/settings hours 1-4 09:00-13:00,14:00-18:00\n/settings hours пт reset — вернуть рабочее время по умолчанию\n/settings hours reset — сбросить весь шаблон"
+
+	fields := strings.Fields(args)
+	if len(fields) == 1 && strings.EqualFold(fields[0], "reset") {
+		if err := database.ClearUserWorkWindows(user.ID); err != nil {
+			log.Printf("Error clearing work windows: %v", err)
+			h.sendMessage(chatID, "Ошибка при обновлении настроек")
+			return
+		}
+		h.sendMessage(chatID, fmt.Sprintf("✅ Шаблон по дням сброшен. Для всех рабочих дней используется %s-%s.", defaultClock(user.WorkStart, "09:00"), defaultClock(user.WorkEnd, "18:00")))
+		return
+	}
+	if len(fields) != 2 {
+		h.sendMessage(chatID, usage)
+		return
+	}
+
+	weekdays, err := parseWeekdaySpec(fields[0])
+	if err != nil {
+		h.sendMessage(chatID, "Неверные дни недели. Используйте 1-7 (1=Пн), диапазоны 1-5 или сокращения пн,пт.\n\n"+usage)
+		return
+	}
+
+	var windows []models.WorkWindow
+	if !strings.EqualFold(fields[1], "reset") {
+		windows, err = parseWorkWindows(fields[1])
+		if err != nil {
+			h.sendMessage(chatID, fmt.Sprintf("Неверные интервалы: %v\n\n%s", err, usage))
+			return
+		}
+	}
+
+	if err := database.SetUserWorkWindows(user.ID, weekdays, windows); err != nil {
+		log.Printf("Error updating work windows: %v", err)
+		h.sendMessage(chatID, "Ошибка при обновлении настроек")
+		return
+	}
+
+	response := fmt.Sprintf("✅ Рабочее время обновлено: %s", formatWorkDays(weekdays))
+	if len(windows) > 0 {
+		response += " " + formatWindowList(windows)
+	} else {
+		response += fmt.Sprintf(" %s-%s (по умолчанию)", defaultClock(user.WorkStart, "09:00"), defaultClock(user.WorkEnd, "18:00"))
+	}
+
+	var nonWorking []int
+	for _, d := range weekdays {
+		if !containsInt(user.WorkDays, d) {
+			nonWorking = append(nonWorking, d)
+		}
+	}
+	if len(windows) > 0 && len(nonWorking) > 0 {
+		response += fmt.Sprintf("\n⚠️ %s не входят в рабочие дни — добавьте их через /settings [часы] | [дни].", formatWorkDays(nonWorking))
+	}
+
+	h.sendMessage(chatID, response+"\nЧтобы применить к расписанию, выполните /schedule.")
+}
+
+// parseWeekdaySpec parses "1-5", "1,3,5", "пт", "пн-пт" into ISO weekdays (1=Пн … 7=Вс).
+func parseWeekdaySpec(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var days []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to := part, part
+		if bounds := strings.SplitN(part, "-", 2); len(bounds) == 2 {
+			from, to = bounds[0], bounds[1]
+		}
+		start, err := parseWeekday(from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseWeekday(to)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("empty weekday range %q", part)
+		}
+		for d := start; d <= end; d++ {
+			if !seen[d] {
+				seen[d] = true
+				days = append(days, d)
+			}
+		}
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no weekdays in %q", spec)
+	}
+	sort.Ints(days)
+	return days, nil
+}
+
+func parseWeekday(s string) (int, error) {
+	names := map[string]int{
+		"пн": 1, "вт": 2, "ср": 3, "чт": 4, "пт": 5, "сб": 6, "вс": 7,
+		"mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6, "sun": 7,
+	}
+	s = strings.ToLower(strings.TrimSpace(s))
+	if d, ok := names[s]; ok {
+		return d, nil
+	}
+	d, err := strconv.Atoi(s)
+	if err != nil || d < 1 || d > 7 {
+		return 0, fmt.Errorf("invalid weekday %q", s)
+	}
+	return d, nil
+}
+
+// parseWorkWindows parses "09:00-13:00,14:00-18:00" into sorted, non-overlapping windows.
+func parseWorkWindows(spec string) ([]models.WorkWindow, error) {
+	type span struct {
+		start, end time.Time
+		w          models.WorkWindow
+	}
+	var spans []span
+	for _, part := range strings.Split(spec, ",") {
+		segments := strings.Split(strings.TrimSpace(part), "-")
+		if len(segments) != 2 {
+			return nil, fmt.Errorf("ожидается HH:MM-HH:MM, получено %q", part)
+		}
+		startStr, endStr := strings.TrimSpace(segments[0]), strings.TrimSpace(segments[1])
+		start, err := time.Parse("15:04", startStr)
+		if err != nil {
+			return nil, fmt.Errorf("неверное время %q", startStr)
+		}
+		end, err := time.Parse("15:04", endStr)
+		if err != nil {
+			return nil, fmt.Errorf("неверное время %q", endStr)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("конец раньше начала в %q", part)
+		}
+		spans = append(spans, span{start: start, end: end, w: models.WorkWindow{Start: startStr, End: endStr}})
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+	windows := make([]models.WorkWindow, 0, len(spans))
+	for i, sp := range spans {
+		if i > 0 && sp.start.Before(spans[i-1].end) {
+			return nil, fmt.Errorf("интервалы %s-%s и %s-%s пересекаются", spans[i-1].w.Start, spans[i-1].w.End, sp.w.Start, sp.w.End)
+		}
+		windows = append(windows, sp.w)
+	}
+	return windows, nil
+}
+
+// formatWorkWindows renders the weekly template as one line per weekday.
+func formatWorkWindows(windows []models.WorkWindow) string {
+	byDay := make(map[int][]models.WorkWindow)
+	for _, w := range windows {
+		byDay[w.Weekday] = append(byDay[w.Weekday], w)
+	}
+	var lines []string
+	for d := 1; d <= 7; d++ {
+		if len(byDay[d]) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", formatWorkDays([]int{d}), formatWindowList(byDay[d])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatSettingsWindows renders the template block of the /settings overview.
+func formatSettingsWindows(windows []models.WorkWindow) string {
+	if len(windows) == 0 {
+		return ""
+	}
+	return "\n🗓 Окна по дням:\n" + formatWorkWindows(windows) + "\n"
+}
+
+func formatWindowList(windows []models.WorkWindow) string {
+	parts := make([]string, len(windows))
+	for i, w := range windows {
+		parts[i] = w.Start + "-" + w.End
+	}
+	return strings.Join(parts, ", ")
+}
+
+func defaultClock(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
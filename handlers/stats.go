@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+const statsUsage = `Формат: /stats estimates — точность оценок: факт / оценка по выполненным задачам`
+
+// handleStats handles /stats subcommands.
+func (h *BotHandler) handleStats(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "estimates", "оценки":
+		h.handleStatsEstimates(msg.Chat.ID, user)
+	default:
+		h.sendMessage(msg.Chat.ID, statsUsage)
+	}
+}
+
+func (h *BotHandler) handleStatsEstimates(chatID int64, user *models.User) {
+	samples, err := database.GetEstimateSamples(user.ID)
+	if err != nil {
+		log.Printf("Error loading estimate samples: %v", err)
+		h.sendMessage(chatID, "Ошибка получения статистики")
+		return
+	}
+	if len(samples) == 0 {
+		h.sendMessage(chatID, "Пока нет данных: записывайте время через /log или /start–/stop, либо укажите факт при завершении (/complete ID часы).")
+		return
+	}
+	h.sendMessage(chatID, formatEstimateStats(scheduler.ComputeEstimateBias(samples), scheduler.EstimateRatioHistory(samples), user.InflateEstimates))
+}
+
+var bandNames = map[string]string{
+	scheduler.BandHigh:   "Высокий приоритет (8–10)",
+	scheduler.BandMedium: "Средний приоритет (4–7)",
+	scheduler.BandLow:    "Низкий приоритет (1–3)",
+}
+
+func formatEstimateStats(bias scheduler.EstimateBias, history []scheduler.RatioPoint, inflate bool) string {
+	response := "📐 Точность оценок (факт / оценка):\n\n"
+	response += fmt.Sprintf("Всего: %s\n%s\n\n", formatBiasStat(bias.Overall), describeBias(bias.Overall.Ratio))
+
+	for _, band := range []string{scheduler.BandHigh, scheduler.BandMedium, scheduler.BandLow} {
+		if stat, ok := bias.ByBand[band]; ok {
+			response += fmt.Sprintf("%s: %s\n", bandNames[band], formatBiasStat(stat))
+		}
+	}
+
+	if len(history) > 0 {
+		response += "\n📈 По месяцам:\n"
+		if len(history) > 6 {
+			history = history[len(history)-6:]
+		}
+		for _, p := range history {
+			response += fmt.Sprintf("%s: %s\n", p.Month.Format("01.2006"), formatBiasStat(p.BiasStat))
+		}
+	}
+
+	if inflate {
+		response += "\n✅ Коррекция включена: оценки умножаются на коэффициент при планировании (/settings estimates off)."
+	} else {
+		response += "\nУчитывать коэффициент при планировании: /settings estimates on"
+	}
+	return response
+}
+
+func formatBiasStat(stat scheduler.BiasStat) string {
+	return fmt.Sprintf("×%.2f (задач: %d)", stat.Ratio, stat.Samples)
+}
+
+func describeBias(ratio float64) string {
+	switch {
+	case ratio > 1.1:
+		return fmt.Sprintf("⚠️ Задачи занимают на %.0f%% больше, чем оценено", (ratio-1)*100)
+	case ratio < 0.9:
+		return fmt.Sprintf("Задачи занимают на %.0f%% меньше, чем оценено", (1-ratio)*100)
+	default:
+		return "👍 Оценки точные"
+	}
+}
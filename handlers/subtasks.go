@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+const subtaskUsage = `Формат: /subtask ID, затем по подзадаче на строке: Название | часы [| приоритет | дедлайн | опции]
+Пример:
+/subtask 12
+Собрать данные | 2
+Написать черновик | 3
+Вычитать | 1
+
+Оценка задачи — сумма открытых подзадач, подзадачи без дедлайна берут дедлайн задачи и планируются по порядку. Когда все подзадачи выполнены, задача закрывается сама.`
+
+// handleSubtask handles /subtask: adds one or more subtasks (a checklist) to a task.
+func (h *BotHandler) handleSubtask(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	idStr, rest, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), "\n")
+	idStr, firstLine, _ := strings.Cut(strings.TrimSpace(idStr), " ")
+	parentID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, subtaskUsage)
+		return
+	}
+	lines := subtaskLines(firstLine + "\n" + rest)
+	if len(lines) == 0 {
+		h.sendMessage(msg.Chat.ID, subtaskUsage)
+		return
+	}
+
+	parent, err := database.GetTaskByIDForUser(parentID, user.ID)
+	if err != nil || parent == nil {
+		h.sendMessage(msg.Chat.ID, "Задача не найдена")
+		return
+	}
+	switch {
+	case parent.ParentID != nil:
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❗️ «%s» — уже подзадача. Подзадачи бывают только у задач верхнего уровня.", parent.Title))
+		return
+	case parent.Status == "completed" || parent.Status == "cancelled":
+		h.sendMessage(msg.Chat.ID, "❗️ Задача уже закрыта.")
+		return
+	case scheduler.IsPinned(parent):
+		h.sendMessage(msg.Chat.ID, "❗️ Задача закреплена на время (at=) — её нельзя разбить на подзадачи.")
+		return
+	}
+
+	var subtasks []*models.Task
+	base := models.Task{UserID: user.ID, Priority: parent.Priority, Project: parent.Project, ParentID: &parent.ID}
+	for i, line := range lines {
+		task, err := parseTaskArgs(line, base, userLocation(user))
+		if err != nil {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("Строка %d: %v", i+1, err))
+			return
+		}
+		subtasks = append(subtasks, task)
+	}
+
+	family, err := database.GetTaskFamily(parent.ID, user.ID)
+	if err != nil {
+		log.Printf("Error loading subtasks: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка получения подзадач")
+		return
+	}
+	wasPlanned := false
+	if rolled := scheduler.RollUpSubtasks(family); len(rolled) > 0 && rolled[0].OpenSubtasks == 0 {
+		if schedules, err := database.GetAllUserSchedulesFrom(user.ID, scheduleStartDate(user)); err == nil {
+			wasPlanned = plannedHoursOf(parent.ID, schedules) > 0
+		}
+	}
+
+	for _, task := range subtasks {
+		if err := database.CreateTask(task); err != nil {
+			log.Printf("Error creating subtask: %v", err)
+			h.sendMessage(msg.Chat.ID, "Ошибка при создании подзадачи")
+			return
+		}
+	}
+
+	family, err = database.GetTaskFamily(parent.ID, user.ID)
+	if err != nil || len(family) == 0 {
+		log.Printf("Error loading subtasks: %v", err)
+		h.sendMessage(msg.Chat.ID, "Подзадачи добавлены.")
+		return
+	}
+	rolled := scheduler.RollUpSubtasks(family)
+	response := fmt.Sprintf("✅ Подзадачи добавлены: %d\n\n%s", len(subtasks), formatTaskFamily(rolled))
+
+	if wasPlanned {
+		h.offerRebuild(msg.Chat.ID, response+"\n\nЗадача уже в расписании целиком — перепланируйте, чтобы вместо неё встали подзадачи.")
+		return
+	}
+	if len(subtasks) > 1 {
+		h.offerRebuild(msg.Chat.ID, response+"\n\nПерепланировать, чтобы подзадачи попали в расписание?")
+		return
+	}
+	hasExisting, err := database.UserHasScheduledTasks(user.ID)
+	if err != nil {
+		log.Printf("Error checking existing schedule: %v", err)
+		hasExisting = false
+	}
+	keyboard := planChoiceKeyboard(subtasks[0].ID, hasExisting)
+	h.sendMessageWithReplyMarkup(msg.Chat.ID, response+"\n\nКак запланировать подзадачу?", &keyboard)
+}
+
+// subtaskLines splits a checklist into its non-empty lines; a leading "-", "•" or "[ ]" is dropped.
+func subtaskLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		for _, marker := range []string{"[ ]", "-", "•", "*"} {
+			line = strings.TrimSpace(strings.TrimPrefix(line, marker))
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// formatTaskFamily renders a rolled-up parent (family[0]) with its subtasks in order.
+func formatTaskFamily(family []models.Task) string {
+	parent := family[0]
+	lines := []string{fmt.Sprintf("📝 ID:%d | %s\n%s", parent.ID, parent.Title, formatTaskHours(&parent))}
+	for i := range family[1:] {
+		child := &family[i+1]
+		lines = append(lines, fmt.Sprintf("  ↳ %s ID:%d | %s | %s", getStatusEmoji(child.Status), child.ID, child.Title, formatTaskHours(child)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nestSubtasks orders tasks so that each parent is followed by its subtasks in position order.
+// Subtasks whose parent is not in the list keep their place.
+func nestSubtasks(tasks []models.Task) []models.Task {
+	present := make(map[int64]bool, len(tasks))
+	children := make(map[int64][]models.Task)
+	for _, task := range tasks {
+		present[task.ID] = true
+	}
+	for _, task := range tasks {
+		if task.ParentID != nil && present[*task.ParentID] {
+			children[*task.ParentID] = append(children[*task.ParentID], task)
+		}
+	}
+
+	nested := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if task.ParentID != nil && present[*task.ParentID] {
+			continue
+		}
+		nested = append(nested, task)
+		kids := children[task.ID]
+		sort.SliceStable(kids, func(i, j int) bool { return kids[i].Position < kids[j].Position })
+		nested = append(nested, kids...)
+	}
+	return nested
+}
+
+// rolledUpTask returns the task as the scheduler sees it: a subtask with the deadline and order
+// it takes from its parent, a parent with the hours of its open subtasks.
+func rolledUpTask(task *models.Task, userID int64) *models.Task {
+	parentID := task.ID
+	if task.ParentID != nil {
+		parentID = *task.ParentID
+	}
+	family, err := database.GetTaskFamily(parentID, userID)
+	if err != nil {
+		log.Printf("Error loading subtasks: %v", err)
+		return task
+	}
+	for _, t := range scheduler.RollUpSubtasks(family) {
+		if t.ID == task.ID {
+			return &t
+		}
+	}
+	return task
+}
+
+// completeSubtasks closes the open subtasks of a completed parent and marks their calendar events.
+func (h *BotHandler) completeSubtasks(userID, parentID int64) int {
+	ids, err := database.CompleteSubtasks(parentID)
+	if err != nil {
+		log.Printf("Error completing subtasks: %v", err)
+		return 0
+	}
+	for _, id := range ids {
+		if err := h.syncTaskCompletionToCalendar(userID, id); err != nil {
+			log.Printf("sync subtask completion to calendar: %v", err)
+		}
+	}
+	return len(ids)
+}
+
+// completeParentIfDone closes the parent of a finished subtask when no open subtasks are left
+// and returns a note for the user, or "" when the parent stays open.
+func completeParentIfDone(task *models.Task, userID int64) string {
+	if task.ParentID == nil {
+		return ""
+	}
+	done, err := database.CompleteParentIfDone(*task.ParentID)
+	if err != nil {
+		log.Printf("Error completing parent task: %v", err)
+		return ""
+	}
+	if !done {
+		return ""
+	}
+	parent, err := database.GetTaskByIDForUser(*task.ParentID, userID)
+	if err != nil || parent == nil {
+		return "\n🏁 Все подзадачи выполнены — задача закрыта."
+	}
+	return fmt.Sprintf("\n🏁 Все подзадачи выполнены — «%s» закрыта.", parent.Title)
+}
@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+const taskOptionsUsage = `Параметры задачи (ключ=значение через пробел):
+hours=4 — оценка в часах
+priority=8 — приоритет 1–10
+deadline=25.12.2026 12:00 — дедлайн (время можно не указывать — тогда весь день); none — убрать
+chunk=90 — минимальный непрерывный блок: минуты (90, 90m) или часы (1.5h); 0 — по умолчанию
+maxday=3 — не больше N часов задачи в день; 0 — по умолчанию
+at=16.10.2026 15:00-16:00 — встреча в точное время (без конца — на hours часов); none — открепить
+after=20.10.2026 14:00 — начинать не раньше (время можно не указывать); none — убрать
+pref=утром не пн — когда лучше работать: утром, днём, вечером, после 14:00, до 12:00, 10:00-12:00, не пн,ср; none — убрать
+project=clienta или #clienta — проект задачи; none — убрать
+energy=high — сколько сил требует задача: high, medium, low (высокая, средняя, низкая); none — по умолчанию`
+
+// handleEditTask handles /edittask ID key=value ...
+func (h *BotHandler) handleEditTask(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	fields := strings.Fields(msg.CommandArguments())
+	if len(fields) < 2 {
+		h.sendMessage(msg.Chat.ID, "Формат: /edittask ID ключ=значение ...\nПример: /edittask 12 chunk=90 maxday=3\n\n"+taskOptionsUsage)
+		return
+	}
+	taskID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Неверный ID задачи")
+		return
+	}
+
+	task, err := database.GetTaskByIDForUser(taskID, user.ID)
+	if err != nil || task == nil {
+		h.sendMessage(msg.Chat.ID, "Задача не найдена")
+		return
+	}
+
+	if err := applyTaskOptions(task, strings.Join(fields[1:], " "), userLocation(user)); err != nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("❗️ %v\n\n%s", err, taskOptionsUsage))
+		return
+	}
+
+	if err := database.UpdateTaskDetails(task); err != nil {
+		log.Printf("Error updating task: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при сохранении задачи")
+		return
+	}
+
+	response := fmt.Sprintf("✅ Задача обновлена\n\n📝 %s\n%s | ⭐️ %d", task.Title, formatTaskHours(task), task.Priority)
+	if project := formatProject(task); project != "" {
+		response += "\n" + project
+	}
+	if pinned := formatPinned(task); pinned != "" {
+		response += "\n" + pinned
+	}
+	if after := formatStartAfter(task); after != "" {
+		response += "\n" + after
+	}
+	if pref := formatTimePreference(user, task); pref != "" {
+		response += "\n" + pref
+	}
+	if energy := formatEnergy(task); energy != "" {
+		response += "\n" + energy
+	}
+	if task.Deadline != nil {
+		response += fmt.Sprintf(" | 📅 %s", formatDateTime(*task.Deadline))
+	}
+	if opts := formatTaskOptions(task); opts != "" {
+		response += "\n" + opts
+	}
+	h.sendMessage(msg.Chat.ID, response+"\n\nПерепланировать: /schedule")
+}
+
+// applyTaskOptions applies "key=value" pairs from /addtask or /edittask to the task.
+// Dates and times are read in loc.
+func applyTaskOptions(task *models.Task, spec string, loc *time.Location) error {
+	var pinStart, pinEnd *time.Time
+	pinSet, hoursSet := false, false
+
+	for _, pair := range splitTaskOptions(spec) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || value == "" {
+			return fmt.Errorf("неверный параметр %q: ожидается ключ=значение", pair)
+		}
+
+		switch strings.ToLower(key) {
+		case "hours":
+			hours, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+			if err != nil || hours <= 0 {
+				return fmt.Errorf("неверное количество часов %q", value)
+			}
+			task.HoursRequired = hours
+			hoursSet = true
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil || priority < 1 || priority > 10 {
+				return fmt.Errorf("приоритет должен быть от 1 до 10")
+			}
+			task.Priority = priority
+		case "deadline":
+			if strings.EqualFold(value, "none") {
+				task.Deadline = nil
+				continue
+			}
+			deadline, err := parseDateTime(value, loc)
+			if err != nil {
+				return err
+			}
+			task.Deadline = &deadline
+		case "chunk":
+			minutes, err := parseChunkMinutes(value)
+			if err != nil {
+				return err
+			}
+			task.MinChunkMinutes = minutes
+		case "maxday":
+			hours, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+			if err != nil || hours < 0 || hours > 24 {
+				return fmt.Errorf("maxday — часы в день от 0 до 24")
+			}
+			task.MaxHoursPerDay = hours
+		case "at":
+			pinSet = true
+			if strings.EqualFold(value, "none") {
+				pinStart, pinEnd = nil, nil
+				continue
+			}
+			start, end, err := parsePinnedTime(value, loc)
+			if err != nil {
+				return err
+			}
+			pinStart, pinEnd = &start, end
+		case "after":
+			if strings.EqualFold(value, "none") {
+				task.StartAfter = nil
+				continue
+			}
+			start, err := parseDateTime(value, loc)
+			if err != nil {
+				return err
+			}
+			task.StartAfter = &start
+		case "project":
+			if strings.EqualFold(value, "none") {
+				task.Project = ""
+				continue
+			}
+			project, err := parseProjectTag(value)
+			if err != nil {
+				return err
+			}
+			task.Project = project
+		case "pref":
+			if strings.EqualFold(value, "none") {
+				task.TimePreference = ""
+				continue
+			}
+			pref, err := scheduler.ParseTimePreference(value)
+			if err != nil {
+				return fmt.Errorf("неверное предпочтение %q: укажите утром, днём, вечером, после ЧЧ:ММ, до ЧЧ:ММ, ЧЧ:ММ-ЧЧ:ММ и/или не ДНИ", value)
+			}
+			task.TimePreference = scheduler.FormatTimePreference(pref)
+		case "energy":
+			if strings.EqualFold(value, "none") {
+				task.Energy = ""
+				continue
+			}
+			level, err := scheduler.ParseEnergyLevel(value)
+			if err != nil {
+				return fmt.Errorf("неверная энергия %q: укажите high, medium или low", value)
+			}
+			task.Energy = level
+		default:
+			return fmt.Errorf("неизвестный параметр %q", key)
+		}
+	}
+
+	if !pinSet && hoursSet && task.PinnedStart != nil {
+		// A new estimate of a pinned task moves its end.
+		pinSet, pinStart = true, task.PinnedStart
+	}
+	if !pinSet {
+		return nil
+	}
+
+	// A pinned block without an end lasts the estimate; with an end, the block is the estimate.
+	switch {
+	case pinStart == nil:
+	case pinEnd == nil:
+		end := pinStart.Add(time.Duration(task.HoursRequired * float64(time.Hour)))
+		pinEnd = &end
+	default:
+		task.HoursRequired = pinEnd.Sub(*pinStart).Hours()
+	}
+	task.PinnedStart, task.PinnedEnd = pinStart, pinEnd
+	return nil
+}
+
+// splitTaskOptions splits a spec into "key=value" pairs. A word without "=" continues the previous
+// value, so "at=16.10.2026 15:00" stays one pair; "#tag" is short for "project=tag".
+func splitTaskOptions(spec string) []string {
+	var pairs []string
+	for _, token := range strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' || r == ';' }) {
+		if strings.HasPrefix(token, "#") {
+			pairs = append(pairs, "project="+token)
+			continue
+		}
+		if !strings.Contains(token, "=") && len(pairs) > 0 {
+			pairs[len(pairs)-1] += " " + token
+			continue
+		}
+		pairs = append(pairs, token)
+	}
+	return pairs
+}
+
+// parseDateTime parses "ДАТА" or "ДАТА ЧЧ:ММ" in loc; a bare date means the start of that day.
+func parseDateTime(value string, loc *time.Location) (time.Time, error) {
+	invalid := fmt.Errorf("неверная дата %q: укажите ДАТА или ДАТА ЧЧ:ММ", value)
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, invalid
+	}
+	date, err := parseDate(fields[0])
+	if err != nil {
+		return time.Time{}, invalid
+	}
+	hour, minute := 0, 0
+	if len(fields) == 2 {
+		clock, err := time.Parse("15:04", fields[1])
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		hour, minute = clock.Hour(), clock.Minute()
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc), nil
+}
+
+// parsePinnedTime parses "ДАТА ЧЧ:ММ" or "ДАТА ЧЧ:ММ-ЧЧ:ММ" in loc; end is nil without an end time.
+func parsePinnedTime(value string, loc *time.Location) (time.Time, *time.Time, error) {
+	invalid := fmt.Errorf("неверное время %q: укажите at=ДАТА ЧЧ:ММ или at=ДАТА ЧЧ:ММ-ЧЧ:ММ", value)
+
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return time.Time{}, nil, invalid
+	}
+	date, err := parseDate(fields[0])
+	if err != nil {
+		return time.Time{}, nil, invalid
+	}
+	startClock, endClock, hasEnd := strings.Cut(fields[1], "-")
+
+	at := func(clock string) (time.Time, bool) {
+		c, err := time.Parse("15:04", clock)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Date(date.Year(), date.Month(), date.Day(), c.Hour(), c.Minute(), 0, 0, loc), true
+	}
+	start, ok := at(startClock)
+	if !ok {
+		return time.Time{}, nil, invalid
+	}
+	if !hasEnd {
+		return start, nil, nil
+	}
+	end, ok := at(endClock)
+	if !ok || !end.After(start) {
+		return time.Time{}, nil, invalid
+	}
+	return start, &end, nil
+}
+
+// parseChunkMinutes accepts minutes ("90", "90m", "90мин") or hours ("1.5h", "1.5ч").
+func parseChunkMinutes(value string) (int, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	scale := 1.0
+	for _, suffix := range []string{"мин", "min", "m", "м"} {
+		if strings.HasSuffix(v, suffix) {
+			v = strings.TrimSuffix(v, suffix)
+			break
+		}
+	}
+	for _, suffix := range []string{"h", "ч"} {
+		if strings.HasSuffix(v, suffix) {
+			v = strings.TrimSuffix(v, suffix)
+			scale = 60
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+	if err != nil || n < 0 || n*scale > 24*60 {
+		return 0, fmt.Errorf("неверная длина блока %q: укажите минуты (90) или часы (1.5h)", value)
+	}
+	return int(n*scale + 0.5), nil
+}
+
+// formatPinned renders the fixed time of a pinned task, or "" for a flexible one.
+func formatPinned(task *models.Task) string {
+	if !scheduler.IsPinned(task) {
+		return ""
+	}
+	return fmt.Sprintf("📌 %s %s–%s", task.PinnedStart.Format("02.01.2006"), task.PinnedStart.Format("15:04"), task.PinnedEnd.Format("15:04"))
+}
+
+// formatStartAfter renders the earliest start of a task, or "" when it may start any time.
+func formatStartAfter(task *models.Task) string {
+	if task.StartAfter == nil {
+		return ""
+	}
+	return "⏳ Не раньше " + formatDateTime(*task.StartAfter)
+}
+
+// formatTimePreference renders when a task would rather be worked on, its own preference or the
+// one it takes from its project, or "" without a preference.
+func formatTimePreference(user *models.User, task *models.Task) string {
+	spec := scheduler.TimePreferenceSpec(user, task.TimePreference, task.Project)
+	if spec == "" {
+		return ""
+	}
+	pref, err := scheduler.ParseTimePreference(spec)
+	if err != nil {
+		return ""
+	}
+	text := "🕘 Лучше " + describeTimePreference(pref)
+	if task.TimePreference == "" {
+		text += fmt.Sprintf(" (как у #%s)", task.Project)
+	}
+	return text
+}
+
+// energyNames are the Russian names of energy levels.
+var energyNames = map[string]string{
+	scheduler.EnergyHigh:   "высокая",
+	scheduler.EnergyMedium: "средняя",
+	scheduler.EnergyLow:    "низкая",
+}
+
+// formatEnergy renders the energy a task needs, or "" when it is not set.
+func formatEnergy(task *models.Task) string {
+	if task.Energy == "" {
+		return ""
+	}
+	return "⚡ Энергия: " + energyNames[task.Energy]
+}
+
+// formatAging renders how waiting raised an open task's place in the planning queue as of now,
+// or "" when it did not.
+func formatAging(user *models.User, task *models.Task, now time.Time) string {
+	if task.Status == "completed" || task.Status == "cancelled" || task.OpenSubtasks > 0 {
+		return ""
+	}
+	if aging := describeAging(user, task, now); aging != "" {
+		return "⏫ Давно ждёт: " + aging
+	}
+	return ""
+}
+
+// describeAging renders a task's aging in words: "28 дн., приоритет 3 → 7", or "" without aging.
+func describeAging(user *models.User, task *models.Task, now time.Time) string {
+	effective := scheduler.EffectivePriority(user, task, now)
+	soft := scheduler.SoftDeadline(user, task, now)
+	if effective == task.Priority && soft == nil {
+		return ""
+	}
+	text := fmt.Sprintf("%d дн., приоритет %d → %d", scheduler.WaitedDays(task, now), task.Priority, effective)
+	if soft != nil {
+		text += fmt.Sprintf(", с %s в очереди наравне с дедлайнами", soft.Format("02.01.2006"))
+	}
+	return text
+}
+
+// describeTimePreference renders a preference in words: "после 14:00, не Пн, Ср".
+func describeTimePreference(pref models.TimePreference) string {
+	clock := func(minute int) string { return fmt.Sprintf("%02d:%02d", minute/60, minute%60) }
+	var parts []string
+	switch {
+	case pref.From > 0 && pref.Until > 0:
+		parts = append(parts, fmt.Sprintf("с %s до %s", clock(pref.From), clock(pref.Until)))
+	case pref.From > 0:
+		parts = append(parts, "после "+clock(pref.From))
+	case pref.Until > 0:
+		parts = append(parts, "до "+clock(pref.Until))
+	}
+	if len(pref.AvoidWeekdays) > 0 {
+		parts = append(parts, "не "+formatWorkDays(pref.AvoidWeekdays))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatDateTime renders a date, with the time unless it is midnight.
+func formatDateTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format("02.01.2006")
+	}
+	return t.Format("02.01.2006 15:04")
+}
+
+// formatTaskOptions renders the task's own session limits, or "" when it uses the defaults.
+func formatTaskOptions(task *models.Task) string {
+	var parts []string
+	if task.MinChunkMinutes > 0 {
+		parts = append(parts, fmt.Sprintf("блок от %d мин", task.MinChunkMinutes))
+	}
+	if task.MaxHoursPerDay > 0 {
+		parts = append(parts, fmt.Sprintf("до %g ч/день", task.MaxHoursPerDay))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "🧩 " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/adkhorst/planbot/database"
+	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
+)
+
+// handleLog handles /log ID hours: records time already spent on a task.
+func (h *BotHandler) handleLog(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	fields := strings.Fields(msg.CommandArguments())
+	if len(fields) != 2 {
+		h.sendMessage(msg.Chat.ID, "Формат: /log ID часы\nПример: /log 12 1.5")
+		return
+	}
+	taskID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Неверный ID задачи")
+		return
+	}
+	hours, err := strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", "."), 64)
+	if err != nil || hours <= 0 || hours > 24 {
+		h.sendMessage(msg.Chat.ID, "⏱ Укажите потраченное время в часах от 0 до 24, например: 0.5, 1.5")
+		return
+	}
+
+	task, err := database.GetTaskByIDForUser(taskID, user.ID)
+	if err != nil || task == nil {
+		h.sendMessage(msg.Chat.ID, "Задача не найдена")
+		return
+	}
+
+	if err := database.LogTime(user.ID, taskID, hours); err != nil {
+		log.Printf("Error logging time: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при сохранении времени")
+		return
+	}
+
+	task.HoursSpent += hours
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("⏱ Записано %g ч: %s\n%s", hours, task.Title, formatTaskProgressLine(task)))
+}
+
+// handleStartTimer handles /start ID: starts a timer on the task, stopping any running timer.
+func (h *BotHandler) handleStartTimer(msg *tgbotapi.Message, taskID int64) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	task, err := database.GetTaskByIDForUser(taskID, user.ID)
+	if err != nil || task == nil {
+		h.sendMessage(msg.Chat.ID, "Задача не найдена")
+		return
+	}
+	if task.Status == "completed" || task.Status == "cancelled" {
+		h.sendMessage(msg.Chat.ID, "Задача уже закрыта")
+		return
+	}
+
+	stopped, err := database.StartTimer(user.ID, taskID)
+	if err != nil {
+		log.Printf("Error starting timer: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при запуске таймера")
+		return
+	}
+
+	response := fmt.Sprintf("▶️ Таймер запущен: %s\nОстановить: /stop", task.Title)
+	if stopped != nil {
+		response = fmt.Sprintf("⏹ Предыдущий таймер (ID:%d) остановлен: %g ч\n\n", stopped.TaskID, stopped.Hours) + response
+	}
+	h.sendMessage(msg.Chat.ID, response)
+}
+
+// handleStop handles /stop: stops the running timer and records the time.
+func (h *BotHandler) handleStop(msg *tgbotapi.Message) {
+	user, err := h.getUser(msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "Ошибка получения пользователя")
+		return
+	}
+
+	stopped, err := database.StopTimer(user.ID)
+	if err != nil {
+		log.Printf("Error stopping timer: %v", err)
+		h.sendMessage(msg.Chat.ID, "Ошибка при остановке таймера")
+		return
+	}
+	if stopped == nil {
+		h.sendMessage(msg.Chat.ID, "Таймер не запущен. Запустить: /start ID")
+		return
+	}
+
+	response := fmt.Sprintf("⏹ Таймер остановлен: %g ч", stopped.Hours)
+	if task, err := database.GetTaskByIDForUser(stopped.TaskID, user.ID); err == nil && task != nil {
+		response += fmt.Sprintf("\n%s\n%s", task.Title, formatTaskProgressLine(task))
+	}
+	h.sendMessage(msg.Chat.ID, response)
+}
+
+// stopTimerForTask stops the running timer when it belongs to the task (e.g. on /complete)
+// and reports whether it did.
+func (h *BotHandler) stopTimerForTask(userID, taskID int64) bool {
+	running, err := database.GetRunningTimer(userID)
+	if err != nil {
+		log.Printf("Error getting running timer: %v", err)
+		return false
+	}
+	if running == nil || running.TaskID != taskID {
+		return false
+	}
+	if _, err := database.StopTimer(userID); err != nil {
+		log.Printf("Error stopping timer: %v", err)
+		return false
+	}
+	return true
+}
+
+// formatTaskHours renders "⏱ 4 ч" or, once time is logged, "⏱ 1.5 / 4 ч".
+func formatTaskHours(task *models.Task) string {
+	if task.HoursSpent <= 0 {
+		return fmt.Sprintf("⏱ %g ч", task.HoursRequired)
+	}
+	return fmt.Sprintf("⏱ %g / %g ч", task.HoursSpent, task.HoursRequired)
+}
+
+func formatTaskProgressLine(task *models.Task) string {
+	line := formatTaskHours(task)
+	if rest := scheduler.RemainingHours(task); rest > 0 {
+		line += fmt.Sprintf(", осталось %g ч", rest)
+	} else {
+		line += ", оценка исчерпана — задача не планируется, пока не отмечена /complete"
+	}
+	return line
+}
@@ -0,0 +1,82 @@
+package holidays
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+//go:embed data/*.txt
+var files embed.FS
+
+// Countries returns the country codes with a bundled holiday list (e.g. "BY", "KZ", "RU").
+func Countries() []string {
+	entries, err := files.ReadDir("data")
+	if err != nil {
+		return nil
+	}
+	codes := make([]string, 0, len(entries))
+	for _, e := range entries {
+		codes = append(codes, strings.ToUpper(strings.TrimSuffix(e.Name(), ".txt")))
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Load returns the public holidays of a country for the given years as single-day DayOff entries.
+// Lines of a data file are either "MM-DD Название" (every year) or "YYYY-MM-DD Название" (that year only).
+func Load(country string, years ...int) ([]models.DayOff, error) {
+	f, err := files.Open("data/" + strings.ToLower(country) + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("no holiday list for country %q", country)
+	}
+	defer func() { _ = f.Close() }()
+
+	wanted := make(map[int]bool, len(years))
+	for _, y := range years {
+		wanted[y] = true
+	}
+
+	var days []models.DayOff
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dateStr, name, _ := strings.Cut(line, " ")
+		name = strings.TrimSpace(name)
+
+		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
+			if wanted[date.Year()] {
+				days = append(days, holiday(date, name))
+			}
+			continue
+		}
+		if _, err := time.Parse("01-02", dateStr); err != nil {
+			return nil, fmt.Errorf("invalid date %q in %s holidays, line %d", dateStr, country, lineNo)
+		}
+		for _, y := range years {
+			date, err := time.Parse("2006-01-02", fmt.Sprintf("%d-%s", y, dateStr))
+			if err != nil {
+				continue // e.g. 02-29 outside leap years
+			}
+			days = append(days, holiday(date, name))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s holidays: %w", country, err)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].StartDate.Before(days[j].StartDate) })
+	return days, nil
+}
+
+func holiday(date time.Time, name string) models.DayOff {
+	return models.DayOff{StartDate: date, EndDate: date, Reason: name, Source: "holiday"}
+}
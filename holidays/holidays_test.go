@@ -0,0 +1,56 @@
+package holidays
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountries(t *testing.T) {
+	got := Countries()
+	if len(got) == 0 {
+		t.Fatal("expected bundled holiday lists")
+	}
+	found := false
+	for _, c := range got {
+		if c == "RU" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected RU among %v", got)
+	}
+}
+
+func TestLoad_ExpandsYearlyDates(t *testing.T) {
+	days, err := Load("ru", 2026, 2027)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := map[string]bool{"2026-05-09": false, "2027-06-12": false}
+	for i, d := range days {
+		if i > 0 && d.StartDate.Before(days[i-1].StartDate) {
+			t.Fatal("expected holidays sorted by date")
+		}
+		if d.Source != "holiday" || !d.StartDate.Equal(d.EndDate) {
+			t.Errorf("unexpected entry %+v", d)
+		}
+		if _, ok := want[d.StartDate.Format("2006-01-02")]; ok {
+			want[d.StartDate.Format("2006-01-02")] = true
+		}
+	}
+	for date, seen := range want {
+		if !seen {
+			t.Errorf("expected holiday on %s", date)
+		}
+	}
+	if days[0].StartDate != time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("expected first holiday on 2026-01-01, got %v", days[0].StartDate)
+	}
+}
+
+func TestLoad_UnknownCountry(t *testing.T) {
+	if _, err := Load("xx", 2026); err == nil {
+		t.Error("expected error for unknown country")
+	}
+}
@@ -6,18 +6,83 @@ import (
 
 // User represents a Telegram user
 type User struct {
-	ID            int64
-	TelegramID    int64
-	Username      string
-	FirstName     string
-	LastName      string
-	TimeZone      string  // e.g. "Europe/Moscow"
-	WorkStart     string  // e.g. "09:00"
-	WorkEnd       string  // e.g. "18:00"
-	DailyCapacity float64 // hours per day
-	WorkDays      []int   // 1=Monday, 7=Sunday
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID                 int64
+	TelegramID         int64
+	Username           string
+	FirstName          string
+	LastName           string
+	TimeZone           string             // e.g. "Europe/Moscow"
+	WorkStart          string             // e.g. "09:00"
+	WorkEnd            string             // e.g. "18:00"
+	DailyCapacity      float64            // hours per day
+	WorkDays           []int              // 1=Monday, 7=Sunday
+	WorkWindows        []WorkWindow       // optional weekly template; weekdays without windows use WorkStart/WorkEnd
+	DaysOff            []DayOff           // vacations, holidays and single days off
+	CapacityOverrides  []CapacityOverride // per-date caps on task hours, e.g. conference days
+	ProjectBudgets     []ProjectBudget    // weekly caps on the hours of a project's tasks
+	ProjectTimePrefs   []ProjectTimePref  // preferred time of day per project; a task's own wins
+	InflateEstimates   bool               // scale HoursRequired by the learned estimate bias before planning
+	MinChunkMinutes    int                // default shortest work session of a task, 0 = any
+	MaxTaskHoursPerDay float64            // default cap on one task's hours per day, 0 = none
+	SchedulingStrategy string             // "greedy", "asap", "balanced" or "optimal"; empty = greedy
+	BreakEveryMinutes  int                // work minutes between short breaks, 0 = no short breaks
+	BreakMinutes       int                // length of a short break
+	LunchStart         string             // fixed lunch window, e.g. "13:00"; empty = none
+	LunchEnd           string             // e.g. "14:00"
+	ExportBreaks       bool               // export breaks to Google Calendar as separate events
+	BufferBefore       int                // minutes kept free before a calendar meeting
+	BufferAfter        int                // minutes kept free after a calendar meeting
+	TravelMinutes      int                // travel time before and after meetings held at a place
+	ClusterProjects    bool               // lay out a day's tasks of one project back to back
+	MaxTasksPerDay     int                // distinct flexible tasks per day, 0 = no limit; deadlines may exceed it
+	EnergyCurve        string             // energy by time of day, e.g. "high 09:00-12:00 low after 16:00"; "" = none
+	MaxHighEnergyHours float64            // cap on high-energy task hours per day, 0 = none
+	PriorityAgingDays  int                // tasks without a deadline gain +1 priority every N days waited, 0 = off
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// WorkWindow is one working interval of the weekly availability template.
+type WorkWindow struct {
+	Weekday int    // 1=Monday, 7=Sunday
+	Start   string // e.g. "09:00"
+	End     string // e.g. "13:00"
+}
+
+// DayOff is an inclusive range of calendar dates when the user does not work.
+type DayOff struct {
+	ID        int64
+	UserID    int64
+	StartDate time.Time // date only
+	EndDate   time.Time // date only, inclusive
+	Reason    string
+	Source    string // "manual" or "holiday"
+}
+
+// CapacityOverride caps task hours on an inclusive range of dates instead of DailyCapacity.
+type CapacityOverride struct {
+	ID        int64
+	UserID    int64
+	StartDate time.Time // date only
+	EndDate   time.Time // date only, inclusive
+	Hours     float64
+	Reason    string
+}
+
+// ProjectBudget caps the hours planned for a project's tasks in one week (Monday to Sunday).
+type ProjectBudget struct {
+	ID          int64
+	UserID      int64
+	Project     string // lower-case tag without "#"
+	WeeklyHours float64
+}
+
+// ProjectTimePref is the preferred time of day of a project's tasks that have none of their own.
+type ProjectTimePref struct {
+	ID             int64
+	UserID         int64
+	Project        string // lower-case tag without "#"
+	TimePreference string // see TimePreference
 }
 
 // GoogleToken stores OAuth tokens for Google Calendar integration.
@@ -44,26 +109,128 @@ type GoogleCalendarEvent struct {
 
 // BusyInterval is a blocked time range (e.g. from Google Calendar).
 type BusyInterval struct {
-	Start   time.Time
-	End     time.Time
-	Summary string
-	Source  string // "calendar", "external"
-	AllDay  bool
+	Start    time.Time
+	End      time.Time
+	Summary  string
+	Source   string // "calendar", "external"
+	AllDay   bool
+	Location string // event location from Google Calendar; a place means travel time
 }
 
 // Task represents a user's task
 type Task struct {
-	ID            int64
-	UserID        int64
-	Title         string
-	Description   string
-	HoursRequired float64
-	Priority      int
-	Status        string // pending, scheduled, in_progress, completed, cancelled
-	Deadline      *time.Time
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	CompletedAt   *time.Time
+	ID              int64
+	UserID          int64
+	Title           string
+	Description     string
+	HoursRequired   float64
+	Priority        int
+	Status          string // pending, scheduled, in_progress, completed, cancelled
+	Deadline        *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	CompletedAt     *time.Time
+	HoursSpent      float64    // hours logged in time_entries
+	MinChunkMinutes int        // shortest contiguous work block, 0 = user default
+	MaxHoursPerDay  float64    // most hours of this task on one day, 0 = user default
+	PinnedStart     *time.Time // fixed start of an appointment (wall clock in the user's time zone)
+	PinnedEnd       *time.Time // fixed end; a pinned task is never moved by the scheduler
+	StartAfter      *time.Time // earliest start (wall clock in the user's time zone), nil = any time
+	TimePreference  string     // preferred time of day and days, see TimePreference; "" = any time
+	Project         string     // lower-case project tag without "#", "" = no project
+	Energy          string     // "high", "medium" or "low"; "" counts as medium
+	ParentID        *int64     // parent of a subtask; subtasks are one level deep
+	Position        int        // order of a subtask among its parent's subtasks, from 1
+	OpenSubtasks    int        // open subtasks of a parent, set by scheduler.RollUpSubtasks
+	DependsOn       []int64    // IDs of tasks that must be finished first (blocked-by)
+	RecurringID     *int64     // template this task was materialized from
+	Occurrence      *time.Time // occurrence date of a recurring instance
+}
+
+// RecurringTask is a template that materializes one task per occurrence of its rule.
+type RecurringTask struct {
+	ID               int64
+	UserID           int64
+	Title            string
+	Description      string
+	HoursRequired    float64
+	Priority         int
+	RRule            string     // persisted rule, see RecurrenceRule
+	MaterializedThru *time.Time // last date already turned into task rows
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// RecurrenceRule is an RRULE-style subset: FREQ, INTERVAL, BYDAY, BYMONTHDAY, UNTIL, COUNT.
+type RecurrenceRule struct {
+	Freq     string     // "daily", "weekly" or "monthly"
+	Interval int        // every N periods, >= 1
+	Weekdays []int      // BYDAY, 1=Monday … 7=Sunday
+	MonthDay int        // BYMONTHDAY for monthly rules, 0 = day of Start
+	Start    time.Time  // DTSTART (date)
+	Until    *time.Time // inclusive last date
+	Count    int        // total occurrences, 0 = unlimited
+}
+
+// TimePreference is when a task would rather be worked on. It is soft: the scheduler uses other
+// times only when the preferred ones are full.
+type TimePreference struct {
+	From          int   // earliest minute of the day, 0 = from the start of the work day
+	Until         int   // latest minute of the day, 0 = until the end of the work day
+	AvoidWeekdays []int // days to keep the task off, 1=Monday … 7=Sunday
+}
+
+// EnergyWindow is a time of day with one energy level, part of a user's energy curve.
+type EnergyWindow struct {
+	Level string // "high", "medium" or "low"
+	From  int    // first minute of the day, 0 = from the start of the work day
+	Until int    // last minute of the day, 0 = until the end of the work day
+}
+
+// TimeEntry is time spent on a task: a manual /log entry or a /start–/stop timer.
+type TimeEntry struct {
+	ID        int64
+	UserID    int64
+	TaskID    int64
+	StartedAt time.Time
+	EndedAt   *time.Time // nil while the timer is running
+	Hours     float64
+	Source    string // "log" or "timer"
+}
+
+// MissedWork is planned work of an open task on past days that was not tracked as done.
+type MissedWork struct {
+	TaskID   int64
+	Title    string
+	FirstDay time.Time // earliest past day with a block
+	LastDay  time.Time // latest past day with a block
+	Planned  float64   // hours planned on those days
+	Logged   float64   // hours logged for the task since FirstDay
+}
+
+// MissedDayLayout formats the prompt's first and last day in missed-work buttons.
+const MissedDayLayout = "20060102"
+
+// PromptKey identifies the prompt in missed-work buttons: "<first day>:<last day>".
+func (m MissedWork) PromptKey() string {
+	return m.FirstDay.Format(MissedDayLayout) + ":" + m.LastDay.Format(MissedDayLayout)
+}
+
+// Missed returns the planned hours not covered by logged time.
+func (m MissedWork) Missed() float64 {
+	if rest := m.Planned - m.Logged; rest > 0 {
+		return rest
+	}
+	return 0
+}
+
+// EstimateSample compares the estimate of a completed task with the time actually logged.
+type EstimateSample struct {
+	TaskID      int64
+	Priority    int
+	Estimated   float64
+	Actual      float64
+	CompletedAt time.Time
 }
 
 // TaskSchedule represents when a task is scheduled
@@ -85,11 +252,19 @@ type DaySchedule struct {
 
 // ScheduledTaskInfo contains task details with scheduling info
 type ScheduledTaskInfo struct {
-	TaskID         int64
-	Title          string
-	HoursAllocated float64
-	Priority       int
-	Deadline       *time.Time
+	TaskID          int64
+	Title           string
+	HoursAllocated  float64
+	Priority        int
+	Deadline        *time.Time
+	MinChunkMinutes int        // task's own minimum block length, 0 = user default
+	PinnedStart     *time.Time // set for pinned tasks: the block is placed exactly here
+	PinnedEnd       *time.Time
+	StartAfter      *time.Time // task's earliest start; blocks on that day begin no earlier
+	TimePreference  string     // task's preferred time of day; its blocks go there first
+	Project         string     // task's project tag, "" = none
+	Energy          string     // task's energy level; its blocks go to hours of that level first
+	DependsOn       []int64    // task's predecessors; on a shared day their blocks come first
 }
 
 // ScheduleRequest represents a request to schedule tasks
@@ -103,7 +278,63 @@ type ScheduleResult struct {
 	Success          bool
 	Message          string
 	DaySchedules     []DaySchedule
-	UnscheduledTasks []int64 // IDs of tasks that couldn't be scheduled
+	UnscheduledTasks []int64             // IDs of tasks that couldn't be scheduled
+	PastTasks        []int64             // pinned tasks whose time was over before the plan starts
+	BlockedBy        map[int64]int64     // unscheduled task ID -> predecessor that blocks it
+	Diagnoses        map[int64]Diagnosis // unscheduled task ID -> why it did not fit (not for blocked tasks)
+}
+
+// Diagnosis explains why a task did not fit and what would make it fit.
+type Diagnosis struct {
+	NeededHours float64     // hours the task still needs
+	WindowStart time.Time   // first day the task could use
+	WindowEnd   time.Time   // its deadline, or the end of the horizon without one
+	WindowHours float64     // working capacity of the window with nothing else planned
+	BusyHours   float64     // calendar events inside the window
+	TakenHours  float64     // hours of higher-ranked and pinned tasks inside the window
+	FreeHours   float64     // hours that were left for this task
+	TakenBy     []TaskHours // tasks using the window, most hours first
+	ExtraPerDay float64     // more task hours per work day in the window that would make it fit
+	FitDeadline *time.Time  // earliest deadline the current plan could meet, nil if none in the horizon
+	YieldTasks  []TaskHours // tasks ahead only by priority: lowering theirs frees this room
+	BudgetHours float64     // weekly budget of the task's project that limited the free hours, 0 = none
+}
+
+// TaskMove describes how far a task moved between two plans.
+type TaskMove struct {
+	TaskID     int64
+	Title      string
+	MovedHours float64 // hours taken off their old days and planned on other days
+	ShiftDays  int     // change of the first planned day, positive = later
+}
+
+// Block change kinds.
+const (
+	BlockAdded   = "added"
+	BlockRemoved = "removed"
+	BlockMoved   = "moved"
+)
+
+// BlockChange is how one task's time blocks on a day differ between two plans.
+type BlockChange struct {
+	TaskID int64
+	Title  string
+	Kind   string           // BlockAdded, BlockRemoved or BlockMoved
+	Before []SlotAllocation // the task's blocks that day in the old plan
+	After  []SlotAllocation // and in the new one
+}
+
+// DayDiff groups the block changes of one day.
+type DayDiff struct {
+	Date    time.Time
+	Changes []BlockChange
+}
+
+// TaskHours is a task's share of hours in some window.
+type TaskHours struct {
+	TaskID int64
+	Title  string
+	Hours  float64
 }
 
 // SlotAllocation is a concrete time block assigned to a task (for calendar export and display).
@@ -114,6 +345,10 @@ type SlotAllocation struct {
 	Deadline *time.Time
 	Start    time.Time
 	End      time.Time
+	Pinned   bool // fixed-time appointment
+	Break    bool // a break between work blocks (TaskID 0), only built for calendar export
+	// OffPreference marks a block placed outside the task's preferred time because that was full.
+	OffPreference bool
 }
 
 // TimeSlot represents a concrete time interval inside a day
@@ -127,4 +362,5 @@ type TimeSlot struct {
 	AllocatedHours float64   // how many hours are already allocated
 	TaskID         *int64    // optional: ID of the task occupying this slot
 	Source         string    // e.g. "task", "calendar", "blocked", ""
+	AfterBreak     bool      // first slot after a short break: a work session may continue across it
 }
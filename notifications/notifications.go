@@ -4,12 +4,14 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"github.com/adkhorst/planbot/database"
 	"github.com/adkhorst/planbot/models"
+	"github.com/adkhorst/planbot/scheduler"
 )
 
 var (
@@ -81,7 +83,7 @@ func sendUserReminders(user *models.User) {
 		if err == nil {
 			for i := range soonTasks {
 				t := soonTasks[i]
-				sendNotification(user.TelegramID, fmt.Sprintf("⏰ Напоминаю: задача %q истекает завтра (%s)", t.Title, t.Deadline.Format("02.01.2006")))
+				sendNotification(user.TelegramID, fmt.Sprintf("⏰ Напоминаю: задача %q истекает завтра (%s)", t.Title, formatDeadline(*t.Deadline)))
 			}
 		}
 
@@ -93,9 +95,12 @@ func sendUserReminders(user *models.User) {
 		if err == nil {
 			for i := range todayTasks {
 				t := todayTasks[i]
-				sendNotification(user.TelegramID, fmt.Sprintf("⚠️ Задача %q сегодня дедлайн! (%s)", t.Title, t.Deadline.Format("02.01.2006")))
+				sendNotification(user.TelegramID, fmt.Sprintf("⚠️ Задача %q сегодня дедлайн! (%s)", t.Title, formatDeadline(*t.Deadline)))
 			}
 		}
+
+		// Запланированная, но не отмеченная работа прошлых дней
+		sendMissedWorkPrompts(user, now)
 	}
 
 	// 3. Просроченные задачи
@@ -104,12 +109,48 @@ func sendUserReminders(user *models.User) {
 		if err == nil {
 			for i := range overdueTasks {
 				t := overdueTasks[i]
-				sendNotification(user.TelegramID, fmt.Sprintf("❌ Задача %q просрочена! (была до %s)", t.Title, t.Deadline.Format("02.01.2006")))
+				sendNotification(user.TelegramID, fmt.Sprintf("❌ Задача %q просрочена! (была до %s)", t.Title, formatDeadline(*t.Deadline)))
 			}
 		}
 	}
 }
 
+// sendMissedWorkPrompts asks about every open task whose blocks before today were not covered by
+// logged time: done, partly done or skipped. The answer is handled by the missed_* callbacks,
+// which carry the rest over. Blocks are asked about once.
+func sendMissedWorkPrompts(user *models.User, now time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	missed, err := database.GetMissedWork(user.ID, today)
+	if err != nil {
+		log.Printf("Error fetching missed work for user %d: %v", user.ID, err)
+		return
+	}
+
+	taskIDs := make([]int64, 0, len(missed))
+	for _, m := range missed {
+		taskIDs = append(taskIDs, m.TaskID)
+		hours := math.Round(m.Missed()*4) / 4
+		if hours < 0.25 {
+			continue
+		}
+		days := m.FirstDay.Format("02.01")
+		if !m.LastDay.Equal(m.FirstDay) {
+			days += "–" + m.LastDay.Format("02.01")
+		}
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Сделал", fmt.Sprintf("missed_did:%d:%g:%s", m.TaskID, hours, m.PromptKey())),
+			tgbotapi.NewInlineKeyboardButtonData("🌓 Частично", fmt.Sprintf("missed_ask:%d:%g:%s", m.TaskID, hours, m.PromptKey())),
+			tgbotapi.NewInlineKeyboardButtonData("⏭ Не сделал", fmt.Sprintf("missed_did:%d:0:%s", m.TaskID, m.PromptKey())),
+		))
+		sendNotificationWithKeyboard(user.TelegramID,
+			fmt.Sprintf("🕓 %s: по «%s» не отмечено %g ч запланированной работы. Как прошло?\nНесделанное перенесу в свободные слоты.", days, m.Title, hours),
+			keyboard)
+	}
+	if err := database.MarkMissedWorkPrompted(taskIDs, today); err != nil {
+		log.Printf("Error marking missed work for user %d: %v", user.ID, err)
+	}
+}
+
 func getAllUsers() ([]models.User, error) {
 	query := `SELECT id, telegram_id, time_zone FROM users`
 	rows, err := database.DB.Query(query)
@@ -153,11 +194,15 @@ func getTasksByDeadlineRange(userID int64, start, end time.Time) ([]models.Task,
 	return tasks, nil
 }
 
+// getOverdueTasks returns open tasks whose deadline has passed by now (the user's wall clock).
+// A date-only deadline (midnight) lasts until the end of that day, as in the scheduler.
 func getOverdueTasks(userID int64, now time.Time) ([]models.Task, error) {
 	query := `
 		SELECT id, title, deadline
 		FROM tasks
-		WHERE user_id = $1 AND deadline < $2 AND status NOT IN ('completed', 'cancelled')
+		WHERE user_id = $1
+		  AND CASE WHEN deadline = date_trunc('day', deadline) THEN deadline + INTERVAL '1 day' ELSE deadline END < $2
+		  AND status NOT IN ('completed', 'cancelled')
 		ORDER BY deadline ASC`
 
 	rows, err := database.DB.Query(query, userID, now)
@@ -177,6 +222,14 @@ func getOverdueTasks(userID int64, now time.Time) ([]models.Task, error) {
 	return tasks, nil
 }
 
+// formatDeadline renders a deadline with its time, or just the date for a date-only deadline.
+func formatDeadline(deadline time.Time) string {
+	if scheduler.HasDeadlineTime(deadline) {
+		return deadline.Format("02.01.2006 15:04")
+	}
+	return deadline.Format("02.01.2006")
+}
+
 func sendNotification(telegramID int64, message string) {
 	msg := tgbotapi.NewMessage(telegramID, message)
 	_, err := bot.Send(msg)
@@ -185,6 +238,14 @@ func sendNotification(telegramID int64, message string) {
 	}
 }
 
+func sendNotificationWithKeyboard(telegramID int64, message string, keyboard tgbotapi.InlineKeyboardMarkup) {
+	msg := tgbotapi.NewMessage(telegramID, message)
+	msg.ReplyMarkup = keyboard
+	if _, err := bot.Send(msg); err != nil {
+		log.Printf("Error sending notification to user %d: %v", telegramID, err)
+	}
+}
+
 func closeRows(rows *sql.Rows) {
 	if err := rows.Close(); err != nil {
 		log.Printf("close rows: %v", err)
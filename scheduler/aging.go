@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// maxPriority is the top of the 1–10 priority scale that aging raises a task towards.
+const maxPriority = 10
+
+// WaitedDays is how many calendar days a task has waited since it was created, as of now.
+// A task without CreatedAt (not saved yet) has not waited.
+func WaitedDays(task *models.Task, now time.Time) int {
+	if task.CreatedAt.IsZero() || now.IsZero() {
+		return 0
+	}
+	loc := now.Location()
+	created := task.CreatedAt.In(loc)
+	from := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if !to.After(from) {
+		return 0
+	}
+	return int(to.Sub(from).Hours()/24 + 0.5)
+}
+
+// EffectivePriority is the priority a task is planned with: with PriorityAgingDays set, a task
+// without a deadline gains one step for every PriorityAgingDays days it has waited, up to 10.
+func EffectivePriority(user *models.User, task *models.Task, now time.Time) int {
+	if user.PriorityAgingDays <= 0 || task.Deadline != nil || task.Priority >= maxPriority {
+		return task.Priority
+	}
+	aged := task.Priority + WaitedDays(task, now)/user.PriorityAgingDays
+	if aged > maxPriority {
+		return maxPriority
+	}
+	return aged
+}
+
+// SoftDeadline returns, for a task without a deadline that has aged past the top priority, the
+// day it did so, or nil. From then on the task is ordered among deadline tasks as if due that day;
+// it is not a hard deadline and is never reported as missed.
+func SoftDeadline(user *models.User, task *models.Task, now time.Time) *time.Time {
+	if user.PriorityAgingDays <= 0 || task.Deadline != nil || task.CreatedAt.IsZero() {
+		return nil
+	}
+	steps := maxPriority + 1 - task.Priority
+	if steps < 1 {
+		steps = 1
+	}
+	if WaitedDays(task, now) < steps*user.PriorityAgingDays {
+		return nil
+	}
+	created := task.CreatedAt.In(now.Location())
+	due := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, steps*user.PriorityAgingDays)
+	return &due
+}
+
+// AgedTasks returns the tasks whose planning order changed with waiting: a raised effective
+// priority or a soft deadline.
+func AgedTasks(user *models.User, tasks []models.Task, now time.Time) []models.Task {
+	var aged []models.Task
+	for i := range tasks {
+		if EffectivePriority(user, &tasks[i], now) > tasks[i].Priority || SoftDeadline(user, &tasks[i], now) != nil {
+			aged = append(aged, tasks[i])
+		}
+	}
+	return aged
+}
+
+// orderDeadline is the deadline a task is ordered by: its own or implied one, else its soft deadline.
+func (s *Scheduler) orderDeadline(task *models.Task) *time.Time {
+	if d := s.deadlineFor(task); d != nil {
+		return d
+	}
+	return SoftDeadline(s.user, task, s.now)
+}
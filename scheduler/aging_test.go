@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestEffectivePriority(t *testing.T) {
+	now := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	user := &models.User{PriorityAgingDays: 7}
+	task := &models.Task{Priority: 4, CreatedAt: now.AddDate(0, 0, -15).Add(14 * time.Hour)}
+
+	if got := EffectivePriority(user, task, now); got != 6 {
+		t.Errorf("expected 4 + 2 steps after 15 days, got %d", got)
+	}
+	if SoftDeadline(user, task, now) != nil {
+		t.Error("expected no soft deadline before the task aged past 10")
+	}
+	if got := EffectivePriority(&models.User{}, task, now); got != 4 {
+		t.Errorf("expected no aging when it is off, got %d", got)
+	}
+	deadline := now.AddDate(0, 0, 10)
+	if got := EffectivePriority(user, &models.Task{Priority: 4, CreatedAt: task.CreatedAt, Deadline: &deadline}, now); got != 4 {
+		t.Errorf("expected deadline tasks not to age, got %d", got)
+	}
+
+	old := &models.Task{Priority: 4, CreatedAt: now.AddDate(0, 0, -60)}
+	if got := EffectivePriority(user, old, now); got != 10 {
+		t.Errorf("expected the priority capped at 10, got %d", got)
+	}
+	soft := SoftDeadline(user, old, now)
+	if want := old.CreatedAt.AddDate(0, 0, 49); soft == nil || !soft.Equal(want) {
+		t.Errorf("expected a soft deadline after 7 steps of 7 days (%s), got %v", want.Format("2006-01-02"), soft)
+	}
+}
+
+func TestScheduler_PriorityAging(t *testing.T) {
+	monday := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	friday := monday.AddDate(0, 0, 4)
+	firstDay := func(user *models.User, tasks []models.Task) map[int64]string {
+		result := NewScheduler(user, tasks).Schedule(monday)
+		days := make(map[int64]string)
+		for _, day := range result.DaySchedules {
+			for _, info := range day.Tasks {
+				if _, ok := days[info.TaskID]; !ok {
+					days[info.TaskID] = day.Date.Format("2006-01-02")
+				}
+			}
+		}
+		return days
+	}
+	tasks := []models.Task{
+		{ID: 1, Title: "Due", HoursRequired: 2, Priority: 5, Deadline: &friday, CreatedAt: monday},
+		{ID: 2, Title: "Backlog", HoursRequired: 2, Priority: 2, CreatedAt: monday.AddDate(0, 0, -60)},
+		{ID: 3, Title: "Fresh", HoursRequired: 2, Priority: 6, CreatedAt: monday},
+		{ID: 4, Title: "Waiting", HoursRequired: 2, Priority: 3, CreatedAt: monday.AddDate(0, 0, -20)},
+	}
+
+	user := &models.User{ID: 1, DailyCapacity: 2, WorkDays: []int{1, 2, 3, 4, 5}, SchedulingStrategy: StrategyASAP}
+	if days := firstDay(user, tasks); days[1] != "2025-03-03" || days[2] != "2025-03-06" {
+		t.Errorf("without aging expected the deadline task first and the backlog last, got %v", days)
+	}
+
+	user.PriorityAgingDays = 5
+	days := firstDay(user, tasks)
+	if days[2] != "2025-03-03" || days[1] != "2025-03-04" {
+		t.Errorf("expected the long-waiting task ahead of the deadline task, got %v", days)
+	}
+	if days[4] != "2025-03-05" || days[3] != "2025-03-06" {
+		t.Errorf("expected the waiting task (3 → 7) ahead of the fresh one (6), got %v", days)
+	}
+}
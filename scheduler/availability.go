@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// WorkPeriod is one continuous working window on a concrete date.
+type WorkPeriod struct {
+	Start      time.Time
+	End        time.Time
+	AfterBreak bool // FocusPeriodsOn: the period follows a short break in the same window
+}
+
+// WorkPeriodsOn returns the user's working windows for the given date, sorted by start.
+// Weekdays missing from the weekly template fall back to WorkStart/WorkEnd (default 09:00–18:00).
+func WorkPeriodsOn(user *models.User, day time.Time) []WorkPeriod {
+	weekday := isoWeekday(day)
+
+	var windows []models.WorkWindow
+	for _, w := range user.WorkWindows {
+		if w.Weekday == weekday {
+			windows = append(windows, w)
+		}
+	}
+	if len(windows) == 0 {
+		workStart := user.WorkStart
+		workEnd := user.WorkEnd
+		if workStart == "" {
+			workStart = "09:00"
+		}
+		if workEnd == "" {
+			workEnd = "18:00"
+		}
+		windows = []models.WorkWindow{{Weekday: weekday, Start: workStart, End: workEnd}}
+	}
+
+	loc := day.Location()
+	periods := make([]WorkPeriod, 0, len(windows))
+	for _, w := range windows {
+		startClock, errStart := time.ParseInLocation("15:04", w.Start, loc)
+		endClock, errEnd := time.ParseInLocation("15:04", w.End, loc)
+		if errStart != nil || errEnd != nil || !endClock.After(startClock) {
+			// Invalid windows are skipped rather than failing the whole day
+			continue
+		}
+		periods = append(periods, WorkPeriod{
+			Start: time.Date(day.Year(), day.Month(), day.Day(), startClock.Hour(), startClock.Minute(), 0, 0, loc),
+			End:   time.Date(day.Year(), day.Month(), day.Day(), endClock.Hour(), endClock.Minute(), 0, 0, loc),
+		})
+	}
+
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+	return periods
+}
+
+// WorkHoursOn returns total working hours on a date according to the weekly template,
+// without the lunch window and short breaks.
+func WorkHoursOn(user *models.User, day time.Time) float64 {
+	var total float64
+	for _, p := range FocusPeriodsOn(user, day) {
+		total += p.End.Sub(p.Start).Hours()
+	}
+	return total
+}
+
+// IsWorkDay reports whether the user works on the date: its weekday is in WorkDays and it is not a day off.
+func IsWorkDay(user *models.User, date time.Time) bool {
+	weekday := isoWeekday(date)
+	for _, workDay := range user.WorkDays {
+		if workDay == weekday {
+			return !IsDayOff(user, date)
+		}
+	}
+	return false
+}
+
+// IsDayOff reports whether the date falls into one of the user's vacations, holidays or days off.
+func IsDayOff(user *models.User, date time.Time) bool {
+	_, ok := DayOffOn(user, date)
+	return ok
+}
+
+// DayOffOn returns the day-off range covering the date.
+// Dates are compared as calendar days so the time zone of stored DATE values does not matter.
+func DayOffOn(user *models.User, date time.Time) (models.DayOff, bool) {
+	key := date.Format("2006-01-02")
+	for _, d := range user.DaysOff {
+		if key >= d.StartDate.Format("2006-01-02") && key <= d.EndDate.Format("2006-01-02") {
+			return d, true
+		}
+	}
+	return models.DayOff{}, false
+}
+
+// DailyCapacityOn returns the cap on task hours for a date: the lowest override covering it,
+// or DailyCapacity.
+func DailyCapacityOn(user *models.User, date time.Time) float64 {
+	if o, ok := CapacityOverrideOn(user, date); ok {
+		return o.Hours
+	}
+	return user.DailyCapacity
+}
+
+// CapacityOverrideOn returns the capacity override covering the date; of overlapping ones the lowest wins.
+func CapacityOverrideOn(user *models.User, date time.Time) (models.CapacityOverride, bool) {
+	key := date.Format("2006-01-02")
+	var found models.CapacityOverride
+	ok := false
+	for _, o := range user.CapacityOverrides {
+		if key < o.StartDate.Format("2006-01-02") || key > o.EndDate.Format("2006-01-02") {
+			continue
+		}
+		if !ok || o.Hours < found.Hours {
+			found, ok = o, true
+		}
+	}
+	return found, ok
+}
+
+// isoWeekday converts time.Weekday to 1=Monday … 7=Sunday.
+func isoWeekday(date time.Time) int {
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		weekday = 7 // Sunday = 7
+	}
+	return weekday
+}
@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestWorkPeriodsOn_FallsBackToWorkStartEnd(t *testing.T) {
+	user := &models.User{
+		WorkStart:   "10:00",
+		WorkEnd:     "16:00",
+		WorkWindows: []models.WorkWindow{{Weekday: 5, Start: "10:00", End: "15:00"}},
+	}
+
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	periods := WorkPeriodsOn(user, monday)
+	if len(periods) != 1 || periods[0].Start.Hour() != 10 || periods[0].End.Hour() != 16 {
+		t.Errorf("expected default 10:00-16:00 on Monday, got %+v", periods)
+	}
+
+	friday := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	if got := WorkHoursOn(user, friday); got != 5 {
+		t.Errorf("expected 5 hours on Friday, got %.2f", got)
+	}
+}
+
+func TestSlotScheduler_BuildDailySlots_SplitWindows(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "1")
+	t.Setenv("PLANNING_SLOT_MINUTES", "60")
+
+	user := &models.User{
+		ID:       1,
+		WorkDays: []int{1, 2, 3, 4, 5},
+		WorkWindows: []models.WorkWindow{
+			{Weekday: 1, Start: "14:00", End: "16:00"},
+			{Weekday: 1, Start: "09:00", End: "11:00"},
+		},
+	}
+
+	slots := NewSlotScheduler(user).BuildDailySlots(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if len(slots) != 4 {
+		t.Fatalf("expected 4 hourly slots, got %d", len(slots))
+	}
+	wantHours := []int{9, 10, 14, 15}
+	for i, slot := range slots {
+		if slot.Start.Hour() != wantHours[i] {
+			t.Errorf("slot %d starts at %v, want %02d:00", i, slot.Start, wantHours[i])
+		}
+	}
+}
+
+func TestScheduler_ShortFridayCapsCapacity(t *testing.T) {
+	user := &models.User{
+		ID:            1,
+		DailyCapacity: 8,
+		WorkDays:      []int{1, 2, 3, 4, 5},
+		WorkStart:     "09:00",
+		WorkEnd:       "17:00",
+		WorkWindows:   []models.WorkWindow{{Weekday: 5, Start: "10:00", End: "13:00"}},
+	}
+	tasks := []models.Task{{ID: 1, Title: "Report", HoursRequired: 5}}
+
+	friday := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	result := NewScheduler(user, tasks).Schedule(friday)
+	if !result.Success {
+		t.Fatalf("expected success, got %s", result.Message)
+	}
+	if result.DaySchedules[0].TotalHours != 3 {
+		t.Errorf("expected 3 hours on short Friday, got %.2f", result.DaySchedules[0].TotalHours)
+	}
+	if result.DaySchedules[1].Date.Weekday() != time.Monday || result.DaySchedules[1].TotalHours != 2 {
+		t.Errorf("expected remaining 2 hours on Monday, got %+v", result.DaySchedules[1])
+	}
+}
+
+func TestIsWorkDay_SkipsDaysOff(t *testing.T) {
+	user := &models.User{
+		WorkDays: []int{1, 2, 3, 4, 5},
+		DaysOff: []models.DayOff{{
+			StartDate: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2027, 1, 8, 0, 0, 0, 0, time.UTC),
+			Reason:    "Отпуск",
+		}},
+	}
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		date time.Time
+		want bool
+	}{
+		{time.Date(2026, 12, 23, 0, 0, 0, 0, moscow), true},
+		{time.Date(2026, 12, 24, 0, 0, 0, 0, moscow), false},
+		{time.Date(2027, 1, 8, 0, 0, 0, 0, moscow), false},
+		{time.Date(2027, 1, 11, 0, 0, 0, 0, moscow), true},
+		{time.Date(2027, 1, 10, 0, 0, 0, 0, moscow), false}, // Sunday
+	}
+	for _, tc := range tests {
+		if got := IsWorkDay(user, tc.date); got != tc.want {
+			t.Errorf("IsWorkDay(%s) = %v, want %v", tc.date.Format("2006-01-02"), got, tc.want)
+		}
+	}
+}
+
+func TestScheduler_SkipsDaysOff(t *testing.T) {
+	user := &models.User{
+		ID:            1,
+		DailyCapacity: 4,
+		WorkDays:      []int{1, 2, 3, 4, 5},
+		DaysOff: []models.DayOff{{
+			StartDate: time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+	tasks := []models.Task{{ID: 1, Title: "Report", HoursRequired: 8}}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if !result.Success || len(result.DaySchedules) != 2 {
+		t.Fatalf("expected 2 days, got %+v", result)
+	}
+	if result.DaySchedules[1].Date.Weekday() != time.Wednesday {
+		t.Errorf("expected Tuesday day off to be skipped, got %v", result.DaySchedules[1].Date.Weekday())
+	}
+
+	slots := NewSlotScheduler(user).BuildDailySlots(time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC))
+	if len(slots) == 0 {
+		t.Fatal("expected slots after the day off")
+	}
+	if slots[0].Date.Day() != 8 {
+		t.Errorf("expected first slot on Wednesday, got %v", slots[0].Date)
+	}
+}
+
+func TestScheduler_CapacityOverrideCapsDate(t *testing.T) {
+	tuesday := time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	user := &models.User{
+		ID:            1,
+		DailyCapacity: 8,
+		WorkDays:      []int{1, 2, 3, 4, 5},
+		CapacityOverrides: []models.CapacityOverride{
+			{StartDate: tuesday, EndDate: tuesday.AddDate(0, 0, 1), Hours: 3, Reason: "Конференция"},
+			{StartDate: tuesday, EndDate: tuesday, Hours: 2},
+		},
+	}
+	if got := DailyCapacityOn(user, tuesday); got != 2 {
+		t.Errorf("expected the lowest overlapping cap 2, got %v", got)
+	}
+	if got := DailyCapacityOn(user, tuesday.AddDate(0, 0, 2)); got != 8 {
+		t.Errorf("expected DailyCapacity after the range, got %v", got)
+	}
+
+	tasks := []models.Task{{ID: 1, Title: "Report", HoursRequired: 13}}
+	result := NewScheduler(user, tasks).Schedule(tuesday)
+	if !result.Success || len(result.DaySchedules) != 3 {
+		t.Fatalf("expected 3 days, got %+v", result)
+	}
+	for i, want := range []float64{2, 3, 8} {
+		if got := result.DaySchedules[i].TotalHours; got != want {
+			t.Errorf("day %d: expected %v hours, got %v", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// Pomodoro rule set by "/settings breaks pomodoro".
+const (
+	PomodoroWorkMinutes  = 25
+	PomodoroBreakMinutes = 5
+)
+
+// FocusPeriodsOn returns the day's working windows without the lunch window and, with a short-break
+// rule, split into stretches of BreakEveryMinutes separated by breaks of BreakMinutes. The count
+// starts over at the start of every window and after lunch.
+func FocusPeriodsOn(user *models.User, day time.Time) []WorkPeriod {
+	var periods []WorkPeriod
+	for _, window := range withoutLunch(user, day, WorkPeriodsOn(user, day)) {
+		periods = append(periods, splitByBreaks(user, window)...)
+	}
+	return periods
+}
+
+// BreaksOn returns the breaks inside the day's working windows, by start: the lunch window and the
+// short breaks. lunch marks the lunch window.
+func BreaksOn(user *models.User, day time.Time) (breaks []WorkPeriod, lunch WorkPeriod) {
+	windows := WorkPeriodsOn(user, day)
+	if l, ok := lunchOn(user, day); ok {
+		for _, w := range windows {
+			start, end := maxTime(w.Start, l.Start), minTime(w.End, l.End)
+			if end.After(start) {
+				breaks = append(breaks, WorkPeriod{Start: start, End: end})
+			}
+		}
+		lunch = l
+	}
+	for _, w := range withoutLunch(user, day, windows) {
+		parts := splitByBreaks(user, w)
+		for i := 1; i < len(parts); i++ {
+			breaks = append(breaks, WorkPeriod{Start: parts[i-1].End, End: parts[i].Start})
+		}
+	}
+	sort.Slice(breaks, func(i, j int) bool { return breaks[i].Start.Before(breaks[j].Start) })
+	return breaks, lunch
+}
+
+// BreakAllocations returns the breaks that fall between the first and the last planned block of
+// each day in allocations, as blocks with Break set, for export to Google Calendar. Breaks that
+// overlap a planned block (e.g. a pinned meeting) are left out.
+func BreakAllocations(user *models.User, allocations []models.SlotAllocation) []models.SlotAllocation {
+	type span struct{ first, last time.Time }
+	days := make(map[string]*span)
+	var keys []string
+	for _, a := range allocations {
+		if a.Break {
+			continue
+		}
+		key := a.Start.Format("2006-01-02")
+		d, ok := days[key]
+		if !ok {
+			d = &span{first: a.Start, last: a.End}
+			days[key] = d
+			keys = append(keys, key)
+		}
+		d.first, d.last = minTime(d.first, a.Start), maxTime(d.last, a.End)
+	}
+	sort.Strings(keys)
+
+	var out []models.SlotAllocation
+	for _, key := range keys {
+		d := days[key]
+		date := time.Date(d.first.Year(), d.first.Month(), d.first.Day(), 0, 0, 0, 0, d.first.Location())
+		breaks, lunch := BreaksOn(user, date)
+		for _, b := range breaks {
+			if b.Start.Before(d.first) || b.End.After(d.last) || overlapsAllocation(b, allocations) {
+				continue
+			}
+			title := "☕ Перерыв"
+			if !b.Start.Before(lunch.Start) && !b.End.After(lunch.End) {
+				title = "🍽 Обед"
+			}
+			out = append(out, models.SlotAllocation{Title: title, Start: b.Start, End: b.End, Break: true})
+		}
+	}
+	return out
+}
+
+func overlapsAllocation(b WorkPeriod, allocations []models.SlotAllocation) bool {
+	for _, a := range allocations {
+		if !a.Break && overlapHours(b.Start, b.End, a.Start, a.End) > 1e-9 {
+			return true
+		}
+	}
+	return false
+}
+
+// lunchOn returns the user's lunch window on day, if one is set.
+func lunchOn(user *models.User, day time.Time) (WorkPeriod, bool) {
+	if user.LunchStart == "" || user.LunchEnd == "" {
+		return WorkPeriod{}, false
+	}
+	loc := day.Location()
+	start, errStart := time.ParseInLocation("15:04", user.LunchStart, loc)
+	end, errEnd := time.ParseInLocation("15:04", user.LunchEnd, loc)
+	if errStart != nil || errEnd != nil || !end.After(start) {
+		return WorkPeriod{}, false
+	}
+	return WorkPeriod{
+		Start: time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc),
+		End:   time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc),
+	}, true
+}
+
+// withoutLunch cuts the lunch window out of the day's windows.
+func withoutLunch(user *models.User, day time.Time, windows []WorkPeriod) []WorkPeriod {
+	lunch, ok := lunchOn(user, day)
+	if !ok {
+		return windows
+	}
+	var out []WorkPeriod
+	for _, w := range windows {
+		if end := minTime(w.End, lunch.Start); end.After(w.Start) {
+			out = append(out, WorkPeriod{Start: w.Start, End: end})
+		}
+		if start := maxTime(w.Start, lunch.End); w.End.After(start) {
+			out = append(out, WorkPeriod{Start: start, End: w.End})
+		}
+	}
+	return out
+}
+
+// splitByBreaks splits one window into stretches of work separated by the user's short breaks.
+func splitByBreaks(user *models.User, w WorkPeriod) []WorkPeriod {
+	if user.BreakEveryMinutes <= 0 || user.BreakMinutes <= 0 {
+		return []WorkPeriod{w}
+	}
+	every := time.Duration(user.BreakEveryMinutes) * time.Minute
+	pause := time.Duration(user.BreakMinutes) * time.Minute
+
+	var parts []WorkPeriod
+	afterBreak := false
+	for start := w.Start; start.Before(w.End); start = start.Add(every + pause) {
+		end := start.Add(every)
+		if !end.Before(w.End) {
+			parts = append(parts, WorkPeriod{Start: start, End: w.End, AfterBreak: afterBreak})
+			break
+		}
+		parts = append(parts, WorkPeriod{Start: start, End: end, AfterBreak: afterBreak})
+		afterBreak = true
+	}
+	return parts
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
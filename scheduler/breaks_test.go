@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestFocusPeriodsOn_BreaksAndLunch(t *testing.T) {
+	user := &models.User{
+		WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00",
+		BreakEveryMinutes: 90, BreakMinutes: 10, LunchStart: "13:00", LunchEnd: "14:00",
+	}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	var got []string
+	for _, p := range FocusPeriodsOn(user, monday) {
+		got = append(got, p.Start.Format("15:04")+"-"+p.End.Format("15:04"))
+	}
+	want := []string{"09:00-10:30", "10:40-12:10", "12:20-13:00", "14:00-15:30", "15:40-17:00"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if h := WorkHoursOn(user, monday); h < 6.5-1e-9 || h > 6.5+1e-9 {
+		t.Errorf("expected 6.5 work hours net of lunch and breaks, got %v", h)
+	}
+
+	breaks, _ := BreaksOn(user, monday)
+	if len(breaks) != 4 || breaks[2].Start.Hour() != 13 || breaks[2].End.Hour() != 14 {
+		t.Errorf("expected three short breaks and lunch, got %+v", breaks)
+	}
+}
+
+func TestFocusPeriodsOn_Pomodoro(t *testing.T) {
+	user := &models.User{
+		WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "10:00",
+		BreakEveryMinutes: PomodoroWorkMinutes, BreakMinutes: PomodoroBreakMinutes,
+	}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	periods := FocusPeriodsOn(user, monday)
+	if len(periods) != 2 || periods[1].Start.Format("15:04") != "09:30" || periods[1].End.Format("15:04") != "09:55" {
+		t.Fatalf("expected 25/5 rounds, got %+v", periods)
+	}
+	if periods[0].AfterBreak || !periods[1].AfterBreak {
+		t.Errorf("expected AfterBreak only after a break, got %+v", periods)
+	}
+}
+
+func TestPlanTimeAllocations_SessionContinuesAfterBreak(t *testing.T) {
+	user := &models.User{
+		ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "12:00",
+		BreakEveryMinutes: 60, BreakMinutes: 15,
+	}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	days := []models.DaySchedule{{
+		Date:       monday,
+		Tasks:      []models.ScheduledTaskInfo{{TaskID: 1, Title: "Report", HoursAllocated: 2, MinChunkMinutes: 120}},
+		TotalHours: 2,
+	}}
+
+	allocations := PlanTimeAllocations(user, days, monday, nil)
+	var got []string
+	for _, a := range allocations {
+		got = append(got, a.Start.Format("15:04")+"-"+a.End.Format("15:04"))
+	}
+	if len(got) != 2 || got[0] != "09:00-10:00" || got[1] != "10:15-11:15" {
+		t.Fatalf("expected one session split by the break, got %v", got)
+	}
+
+	breaks := BreakAllocations(user, allocations)
+	if len(breaks) != 1 || !breaks[0].Break || breaks[0].TaskID != 0 || breaks[0].Start.Format("15:04") != "10:00" {
+		t.Errorf("expected the 10:00 break between the blocks only, got %+v", breaks)
+	}
+}
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// onlineMeetingMarkers are location fragments of video calls: no travel is needed for them.
+var onlineMeetingMarkers = []string{"http://", "https://", "zoom", "meet.google", "teams", "skype", "telemost", "онлайн", "online"}
+
+// PadBusyIntervals widens timed calendar events by the user's buffers before blocking slots:
+// BufferBefore minutes before and BufferAfter minutes after, or TravelMinutes on both sides of
+// an event held at a place when that is longer. All-day events are left as they are.
+func PadBusyIntervals(user *models.User, busy []models.BusyInterval) []models.BusyInterval {
+	if user.BufferBefore <= 0 && user.BufferAfter <= 0 && user.TravelMinutes <= 0 {
+		return busy
+	}
+	padded := make([]models.BusyInterval, len(busy))
+	for i, b := range busy {
+		padded[i] = b
+		if b.AllDay {
+			continue
+		}
+		before, after := user.BufferBefore, user.BufferAfter
+		if NeedsTravel(b.Location) {
+			before, after = max(before, user.TravelMinutes), max(after, user.TravelMinutes)
+		}
+		padded[i].Start = b.Start.Add(-time.Duration(before) * time.Minute)
+		padded[i].End = b.End.Add(time.Duration(after) * time.Minute)
+	}
+	return padded
+}
+
+// NeedsTravel reports whether an event location is a place to get to rather than a call link.
+func NeedsTravel(location string) bool {
+	location = strings.ToLower(strings.TrimSpace(location))
+	if location == "" {
+		return false
+	}
+	for _, marker := range onlineMeetingMarkers {
+		if strings.Contains(location, marker) {
+			return false
+		}
+	}
+	return true
+}
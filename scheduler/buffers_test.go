@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestPadBusyIntervals(t *testing.T) {
+	user := &models.User{BufferBefore: 10, BufferAfter: 15, TravelMinutes: 30}
+	at := func(h, m int) time.Time { return time.Date(2025, 1, 6, h, m, 0, 0, time.UTC) }
+	busy := []models.BusyInterval{
+		{Start: at(10, 0), End: at(11, 0), Source: "calendar"},
+		{Start: at(12, 0), End: at(13, 0), Source: "calendar", Location: "Офис, Тверская 1"},
+		{Start: at(14, 0), End: at(15, 0), Source: "calendar", Location: "https://zoom.us/j/123"},
+		{Start: at(9, 0), End: at(18, 0), Source: "calendar", AllDay: true},
+	}
+
+	padded := PadBusyIntervals(user, busy)
+	want := [][2]time.Time{
+		{at(9, 50), at(11, 15)},
+		{at(11, 30), at(13, 30)},
+		{at(13, 50), at(15, 15)},
+		{at(9, 0), at(18, 0)},
+	}
+	for i, w := range want {
+		if !padded[i].Start.Equal(w[0]) || !padded[i].End.Equal(w[1]) {
+			t.Errorf("interval %d: got %s–%s, want %s–%s", i,
+				padded[i].Start.Format("15:04"), padded[i].End.Format("15:04"), w[0].Format("15:04"), w[1].Format("15:04"))
+		}
+	}
+	if !busy[0].Start.Equal(at(10, 0)) {
+		t.Error("expected the input left untouched")
+	}
+}
+
+func TestBuildWorkSlots_BufferAfterMeeting(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "12:00", BufferAfter: 30}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	busy := PadBusyIntervals(user, []models.BusyInterval{{
+		Start: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC), Source: "calendar",
+	}})
+
+	slots := BuildWorkSlots(user, monday, busy)
+	if free := freeHours(slotsOn(slots, "2025-01-06", time.Time{}, time.Time{})); free < 1.5-1e-9 || free > 1.5+1e-9 {
+		t.Errorf("expected 1.5 free hours after the meeting and its buffer, got %v", free)
+	}
+}
@@ -0,0 +1,149 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// MinChunkHours resolves the shortest work session of a task: its own minutes or the user default.
+func MinChunkHours(user *models.User, taskMinutes int) float64 {
+	minutes := taskMinutes
+	if minutes <= 0 && user != nil {
+		minutes = user.MinChunkMinutes
+	}
+	if minutes <= 0 {
+		return 0
+	}
+	return float64(minutes) / 60
+}
+
+// MaxHoursPerDay resolves the daily cap of a task: its own value or the user default; 0 means no cap.
+func MaxHoursPerDay(user *models.User, task *models.Task) float64 {
+	if task.MaxHoursPerDay > 0 {
+		return task.MaxHoursPerDay
+	}
+	if user != nil && user.MaxTaskHoursPerDay > 0 {
+		return user.MaxTaskHoursPerDay
+	}
+	return 0
+}
+
+// fitChunk shrinks a block of take hours so that neither the block nor the rest of the task
+// (left hours, including this block) ends up shorter than minChunk. A block covering
+// everything left is always allowed. Returns 0 when no valid block fits.
+func fitChunk(take, left, minChunk float64) float64 {
+	if minChunk <= 0 || take >= left-1e-9 {
+		return math.Min(take, left)
+	}
+	if left-take < minChunk {
+		take = left - minChunk
+	}
+	if take < minChunk-1e-9 {
+		return 0
+	}
+	return take
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+func hoursOf(intervals []interval) float64 {
+	var h float64
+	for _, iv := range intervals {
+		h += iv.end.Sub(iv.start).Hours()
+	}
+	return h
+}
+
+// freeRun is a stretch of adjacent slots with contiguous free time. A short break does not end a
+// run: a work session continues after it.
+type freeRun struct {
+	slots []*models.TimeSlot
+	free  float64
+}
+
+// freeRuns splits one day's slots (in time order) into runs of contiguous free time.
+// Booked time sits at the start of a slot, so a run only continues into an untouched adjacent slot
+// (or the untouched slot right after a short break).
+func freeRuns(daySlots []*models.TimeSlot) []freeRun {
+	var runs []freeRun
+	open := false
+	for i, slot := range daySlots {
+		free := slot.CapacityHours - slot.AllocatedHours
+		if free <= 1e-9 {
+			open = false
+			continue
+		}
+		if open && slot.AllocatedHours <= 1e-9 && (slot.Start.Equal(daySlots[i-1].End) || slot.AfterBreak) {
+			last := &runs[len(runs)-1]
+			last.slots = append(last.slots, slot)
+			last.free += free
+		} else {
+			runs = append(runs, freeRun{slots: []*models.TimeSlot{slot}, free: free})
+		}
+		open = true
+	}
+	return runs
+}
+
+// bookSlot books hours at the first free minute of the slot.
+func bookSlot(slot *models.TimeSlot, hours float64) interval {
+	start := slot.Start.Add(time.Duration(slot.AllocatedHours * float64(time.Hour)))
+	slot.AllocatedHours += hours
+	return interval{start: start, end: start.Add(time.Duration(hours * float64(time.Hour)))}
+}
+
+// placeChunks books up to want hours of a task on one day's slots and returns the booked intervals.
+// left is how much of the task is still unplaced (want included). With minChunk > 0 every block is a
+// contiguous run of at least minChunk hours, or everything left; shorter gaps are skipped. A run that
+// spans a short break is booked as one interval per side of the break.
+func placeChunks(daySlots []*models.TimeSlot, want, left, minChunk float64) []interval {
+	var placed []interval
+	if minChunk <= 0 {
+		for _, slot := range daySlots {
+			if want <= 1e-9 {
+				break
+			}
+			free := slot.CapacityHours - slot.AllocatedHours
+			if free <= 1e-9 {
+				continue
+			}
+			take := math.Min(want, free)
+			placed = append(placed, bookSlot(slot, take))
+			want -= take
+		}
+		return placed
+	}
+
+	for _, run := range freeRuns(daySlots) {
+		if want <= 1e-9 {
+			break
+		}
+		take := fitChunk(math.Min(want, run.free), left, minChunk)
+		if take <= 1e-9 {
+			continue
+		}
+		var blocks []interval
+		rest := take
+		for _, slot := range run.slots {
+			if rest <= 1e-9 {
+				break
+			}
+			h := math.Min(rest, slot.CapacityHours-slot.AllocatedHours)
+			iv := bookSlot(slot, h)
+			if n := len(blocks); n > 0 && iv.start.Equal(blocks[n-1].end) {
+				blocks[n-1].end = iv.end
+			} else {
+				blocks = append(blocks, iv)
+			}
+			rest -= h
+		}
+		placed = append(placed, blocks...)
+		want -= take
+		left -= take
+	}
+	return placed
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestFitChunk(t *testing.T) {
+	tests := []struct {
+		take, left, minChunk, want float64
+	}{
+		{take: 0.5, left: 2, minChunk: 0, want: 0.5},
+		{take: 0.5, left: 2, minChunk: 1, want: 0},     // gap too short
+		{take: 1.5, left: 2, minChunk: 1, want: 1},     // keep 1h for the rest
+		{take: 1.5, left: 1.75, minChunk: 1, want: 0},  // cannot split into two valid blocks
+		{take: 0.5, left: 0.5, minChunk: 1, want: 0.5}, // whole remainder is always allowed
+		{take: 3, left: 5, minChunk: 1, want: 3},
+	}
+	for _, tc := range tests {
+		if got := fitChunk(tc.take, tc.left, tc.minChunk); got != tc.want {
+			t.Errorf("fitChunk(%v, %v, %v) = %v, want %v", tc.take, tc.left, tc.minChunk, got, tc.want)
+		}
+	}
+}
+
+// fragmentedMonday is 09:00-13:00 with meetings 09:30-10:00 and 11:30-12:00 on a 30-minute grid.
+func fragmentedMonday(t *testing.T, minChunkMinutes int) (*models.User, time.Time, []models.BusyInterval) {
+	t.Helper()
+	t.Setenv("PLANNING_HORIZON_DAYS", "1")
+	t.Setenv("PLANNING_SLOT_MINUTES", "30")
+
+	user := &models.User{
+		ID:              1,
+		DailyCapacity:   4,
+		WorkDays:        []int{1, 2, 3, 4, 5},
+		WorkStart:       "09:00",
+		WorkEnd:         "13:00",
+		MinChunkMinutes: minChunkMinutes,
+	}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	busy := []models.BusyInterval{
+		{Start: time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 1, 6, 11, 30, 0, 0, time.UTC), End: time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)},
+	}
+	return user, monday, busy
+}
+
+func TestSchedule_MinChunkSkipsShortGaps(t *testing.T) {
+	user, monday, busy := fragmentedMonday(t, 60)
+	tasks := []models.Task{{ID: 1, Title: "Deep work", HoursRequired: 2, Priority: 5}}
+
+	result := NewSchedulerWithSlots(user, tasks, BuildWorkSlots(user, monday, busy)).Schedule(monday)
+	if !result.Success || len(result.DaySchedules) != 1 || result.DaySchedules[0].TotalHours != 2 {
+		t.Fatalf("expected 2h on Monday, got %+v", result)
+	}
+
+	allocations := PlanTimeAllocations(user, result.DaySchedules, monday, busy)
+	if len(allocations) != 2 {
+		t.Fatalf("expected 2 blocks, got %+v", allocations)
+	}
+	for _, a := range allocations {
+		if d := a.End.Sub(a.Start); d < time.Hour {
+			t.Errorf("block %s-%s is shorter than 60 min", a.Start.Format("15:04"), a.End.Format("15:04"))
+		}
+	}
+	if allocations[0].Start.Hour() != 10 || allocations[1].Start.Hour() != 12 {
+		t.Errorf("expected blocks at 10:00 and 12:00, got %s and %s", allocations[0].Start.Format("15:04"), allocations[1].Start.Format("15:04"))
+	}
+}
+
+func TestPlanTimeAllocations_NoMinChunkFillsGaps(t *testing.T) {
+	user, monday, busy := fragmentedMonday(t, 0)
+	days := []models.DaySchedule{{Date: monday, Tasks: []models.ScheduledTaskInfo{{TaskID: 1, Title: "Any", HoursAllocated: 2}}, TotalHours: 2}}
+
+	allocations := PlanTimeAllocations(user, days, monday, busy)
+	if len(allocations) == 0 || allocations[0].Start.Hour() != 9 || allocations[0].End.Sub(allocations[0].Start) != 30*time.Minute {
+		t.Errorf("expected the first 30-minute gap to be used, got %+v", allocations)
+	}
+}
+
+func TestSchedule_MaxHoursPerDaySpreadsTask(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, MaxTaskHoursPerDay: 3}
+	tasks := []models.Task{
+		{ID: 1, Title: "Default cap", HoursRequired: 6, Priority: 5},
+		{ID: 2, Title: "Own cap", HoursRequired: 3, Priority: 4, MaxHoursPerDay: 1},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if !result.Success || len(result.DaySchedules) != 3 {
+		t.Fatalf("expected 3 days, got %+v", result)
+	}
+	for _, day := range result.DaySchedules {
+		for _, info := range day.Tasks {
+			limit := 3.0
+			if info.TaskID == 2 {
+				limit = 1
+			}
+			if info.HoursAllocated > limit+1e-9 {
+				t.Errorf("task %d got %.2fh on %s, cap %.0fh", info.TaskID, info.HoursAllocated, day.Date.Format("2006-01-02"), limit)
+			}
+		}
+	}
+}
+
+func TestScheduleTaskIntoExisting_RespectsMinChunk(t *testing.T) {
+	user, monday, busy := fragmentedMonday(t, 0)
+	task := &models.Task{ID: 5, Title: "Focus", HoursRequired: 1.5, MinChunkMinutes: 90}
+
+	days, ok := ScheduleTaskIntoExisting(user, task, nil, monday, busy)
+	if !ok || len(days) != 1 || len(days[0].Tasks) != 1 || days[0].Tasks[0].HoursAllocated != 1.5 {
+		t.Fatalf("expected one 1.5h entry, got %+v (ok=%v)", days, ok)
+	}
+
+	allocations := PlanTimeAllocations(user, days, monday, busy)
+	if len(allocations) != 1 || allocations[0].Start.Hour() != 10 || allocations[0].End.Sub(allocations[0].Start) != 90*time.Minute {
+		t.Errorf("expected one 10:00-11:30 block, got %+v", allocations)
+	}
+}
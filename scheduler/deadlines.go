@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// HasDeadlineTime reports whether a deadline names a time of day. Deadlines are stored as the
+// user's wall clock; midnight means a date-only deadline, i.e. the whole day is usable.
+func HasDeadlineTime(deadline time.Time) bool {
+	return deadline.Hour() != 0 || deadline.Minute() != 0 || deadline.Second() != 0
+}
+
+// DeadlineEnd returns the moment all work must be done by: the deadline itself, or the end of
+// the day for a date-only deadline.
+func DeadlineEnd(deadline time.Time) time.Time {
+	if HasDeadlineTime(deadline) {
+		return deadline
+	}
+	return deadline.AddDate(0, 0, 1)
+}
+
+// deadlineDay returns the last day a task with the given deadline may use, in loc.
+func deadlineDay(deadline time.Time, loc *time.Location) time.Time {
+	d := wallClock(deadline, loc)
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+}
+
+// notAfterOn returns the moment work must end by on the day dateKey for a task due at a time of day,
+// or zero when the whole day is usable (no deadline, another day or a date-only deadline).
+func notAfterOn(deadline *time.Time, dateKey string, loc *time.Location) time.Time {
+	if deadline == nil || !HasDeadlineTime(*deadline) {
+		return time.Time{}
+	}
+	end := wallClock(*deadline, loc)
+	if end.Format("2006-01-02") != dateKey {
+		return time.Time{}
+	}
+	return end
+}
+
+// slotsUntil keeps the slots that end at or before until; zero until keeps all of them.
+// A slot running past a deadline is dropped whole so no work lands after it.
+func slotsUntil(daySlots []*models.TimeSlot, until time.Time) []*models.TimeSlot {
+	if until.IsZero() {
+		return daySlots
+	}
+	var kept []*models.TimeSlot
+	for _, slot := range daySlots {
+		if !slot.End.After(until) {
+			kept = append(kept, slot)
+		}
+	}
+	return kept
+}
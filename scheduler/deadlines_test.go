@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestSchedule_DeadlineTimeOfDay(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	due := monday.AddDate(0, 0, 2).Add(11 * time.Hour) // Wednesday 11:00
+	tasks := []models.Task{{ID: 1, Title: "Slides", HoursRequired: 3, Priority: 8, Deadline: &due}}
+
+	result := NewSchedulerWithSlots(user, tasks, BuildWorkSlots(user, monday, nil)).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected the task to fit, got %+v", result)
+	}
+	for _, day := range result.DaySchedules {
+		if day.Date.Equal(monday.AddDate(0, 0, 2)) && day.TotalHours > 2 {
+			t.Errorf("expected at most 2h on Wednesday before 11:00, got %v", day.TotalHours)
+		}
+	}
+	for _, a := range PlanTimeAllocations(user, result.DaySchedules, monday, nil) {
+		if a.End.After(due) {
+			t.Errorf("block %v–%v ends after the deadline", a.Start, a.End)
+		}
+	}
+
+	// Without a slot grid the day-level plan counts only the hours before 11:00 as well.
+	result = NewScheduler(user, tasks).Schedule(monday)
+	for _, day := range result.DaySchedules {
+		if day.Date.Equal(monday.AddDate(0, 0, 2)) && day.TotalHours > 2 {
+			t.Errorf("expected at most 2h on Wednesday without slots, got %v", day.TotalHours)
+		}
+	}
+	if got := WindowHours(user, monday, due); got != 10 {
+		t.Errorf("WindowHours = %v, want 10", got)
+	}
+}
+
+func TestPlanTimeAllocations_DeadlineTaskGoesFirst(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	due := monday.Add(11 * time.Hour)
+	days := []models.DaySchedule{{
+		Date: monday,
+		Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, Title: "Flexible", HoursAllocated: 2, Priority: 9},
+			{TaskID: 2, Title: "Due at 11", HoursAllocated: 2, Priority: 5, Deadline: &due},
+		},
+		TotalHours: 4,
+	}}
+
+	allocations := PlanTimeAllocations(user, days, monday, nil)
+	if len(allocations) != 2 || allocations[0].TaskID != 2 || allocations[0].End.After(due) {
+		t.Fatalf("expected the task due at 11:00 to take 09:00–11:00, got %+v", allocations)
+	}
+}
+
+func TestSchedule_DeadlineTimeInUserZone(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	loc := time.FixedZone("UTC+3", 3*3600)
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, loc)
+	// Deadlines come back from the database as the user's wall clock labelled UTC.
+	due := time.Date(2025, 1, 6, 11, 0, 0, 0, time.UTC)
+	tasks := []models.Task{{ID: 1, Title: "Report", HoursRequired: 2, Deadline: &due}}
+
+	result := NewSchedulerWithSlots(user, tasks, BuildWorkSlots(user, monday, nil)).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected 2h before 11:00 on Monday, got %+v", result)
+	}
+	limit := time.Date(2025, 1, 6, 11, 0, 0, 0, loc)
+	for _, a := range PlanTimeAllocations(user, result.DaySchedules, monday, nil) {
+		if a.End.After(limit) {
+			t.Errorf("block %v–%v ends after 11:00 local time", a.Start, a.End)
+		}
+	}
+}
+
+func TestScheduleTaskIntoExisting_DeadlineTime(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	due := monday.Add(11 * time.Hour)
+
+	if _, ok := ScheduleTaskIntoExisting(user, &models.Task{ID: 1, Title: "Too long", HoursRequired: 3, Deadline: &due}, nil, monday, nil); ok {
+		t.Error("expected 3h not to fit before 11:00")
+	}
+	days, ok := ScheduleTaskIntoExisting(user, &models.Task{ID: 2, Title: "Fits", HoursRequired: 2, Deadline: &due}, nil, monday, nil)
+	if !ok || len(days) != 1 || days[0].TotalHours != 2 {
+		t.Fatalf("expected 2h on Monday morning, got %+v (ok=%v)", days, ok)
+	}
+}
+
+func TestDeadlineEnd(t *testing.T) {
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	if got := DeadlineEnd(day); !got.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("date-only deadline should last the whole day, got %v", got)
+	}
+	noon := day.Add(12 * time.Hour)
+	if got := DeadlineEnd(noon); !got.Equal(noon) {
+		t.Errorf("timed deadline should end at its time, got %v", got)
+	}
+}
@@ -0,0 +1,302 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// WouldCreateCycle reports whether adding "taskID depends on dependsOnID" to deps closes a cycle.
+// deps maps a task ID to the IDs of its predecessors.
+func WouldCreateCycle(deps map[int64][]int64, taskID, dependsOnID int64) bool {
+	if taskID == dependsOnID {
+		return true
+	}
+
+	// A cycle appears if taskID is already reachable from dependsOnID via predecessor edges.
+	visited := make(map[int64]bool)
+	stack := []int64{dependsOnID}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == taskID {
+			return true
+		}
+		if visited[cur] {
+			continue
+		}
+		visited[cur] = true
+		stack = append(stack, deps[cur]...)
+	}
+	return false
+}
+
+// orderByDependencies propagates implied deadlines from successors to predecessors and
+// returns tasks sorted by deadline/priority with every predecessor placed before its successors.
+// Tasks that sit on (or behind) a dependency cycle are returned in blocked, keyed by task ID.
+func (s *Scheduler) orderByDependencies(tasks []models.Task) (ordered []models.Task, blocked map[int64]int64) {
+	blocked = make(map[int64]int64)
+	byID := make(map[int64]*models.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	// Only predecessors that are still active constrain planning; finished ones are satisfied.
+	preds := make(map[int64][]int64, len(tasks))
+	succs := make(map[int64][]int64, len(tasks))
+	for i := range tasks {
+		for _, p := range tasks[i].DependsOn {
+			if _, ok := byID[p]; !ok || p == tasks[i].ID {
+				continue
+			}
+			preds[tasks[i].ID] = append(preds[tasks[i].ID], p)
+			succs[p] = append(succs[p], tasks[i].ID)
+		}
+	}
+
+	// Kahn's algorithm: anything left over is part of a cycle or depends on one.
+	inDegree := make(map[int64]int, len(tasks))
+	for i := range tasks {
+		inDegree[tasks[i].ID] = len(preds[tasks[i].ID])
+	}
+	var topo []int64
+	var queue []int64
+	for i := range tasks {
+		if inDegree[tasks[i].ID] == 0 {
+			queue = append(queue, tasks[i].ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		topo = append(topo, id)
+		for _, next := range succs[id] {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	for i := range tasks {
+		id := tasks[i].ID
+		if inDegree[id] == 0 {
+			continue
+		}
+		for _, p := range preds[id] {
+			if inDegree[p] > 0 {
+				blocked[id] = p
+				break
+			}
+		}
+	}
+
+	// Walk successors before predecessors so implied deadlines flow backwards along chains.
+	for i := len(topo) - 1; i >= 0; i-- {
+		id := topo[i]
+		for _, next := range succs[id] {
+			succDeadline := s.deadlineFor(byID[next])
+			if succDeadline == nil {
+				continue
+			}
+			implied := s.impliedDeadline(*succDeadline, RemainingHours(byID[next]))
+			if own := s.deadlineFor(byID[id]); own == nil || implied.Before(*own) {
+				s.setEffectiveDeadline(id, implied)
+			}
+		}
+	}
+
+	acyclic := make([]models.Task, 0, len(topo))
+	for i := range tasks {
+		if _, isBlocked := blocked[tasks[i].ID]; !isBlocked {
+			acyclic = append(acyclic, tasks[i])
+		}
+	}
+	sorted := s.sortTasksByDeadlineAndPriority(acyclic)
+
+	// Stable topological pass: keep the deadline/priority order unless a predecessor is still pending.
+	emitted := make(map[int64]bool, len(sorted))
+	ordered = make([]models.Task, 0, len(sorted))
+	for len(ordered) < len(sorted) {
+		for i := range sorted {
+			id := sorted[i].ID
+			if emitted[id] || !allEmitted(preds[id], emitted) {
+				continue
+			}
+			emitted[id] = true
+			ordered = append(ordered, sorted[i])
+			break
+		}
+	}
+
+	return ordered, blocked
+}
+
+// impliedDeadline returns the latest day a predecessor may still occupy so that a successor
+// needing hours can fit between it and the successor's deadline.
+func (s *Scheduler) impliedDeadline(successorDeadline time.Time, hours float64) time.Time {
+	current := s.normalizeDate(successorDeadline)
+	remaining := hours
+	for i := 0; i < s.planningHorizonDays; i++ {
+		if s.isWorkDay(current) {
+			capacity := s.capacityOn(current)
+			if remaining < capacity-1e-9 {
+				return current
+			}
+			remaining -= capacity
+		}
+		current = current.AddDate(0, 0, -1)
+	}
+	return current
+}
+
+// earliestAfterPredecessors returns the first day the task may use and the predecessor that sets it.
+// ok is false when a predecessor could not be scheduled at all.
+func (s *Scheduler) earliestAfterPredecessors(task *models.Task, lastDays map[int64]time.Time, failed map[int64]bool) (earliest time.Time, blocker int64, ok bool) {
+	for _, p := range task.DependsOn {
+		if failed[p] {
+			return time.Time{}, p, false
+		}
+		last, scheduled := lastDays[p]
+		if !scheduled {
+			continue
+		}
+		if blocker == 0 || last.After(earliest) {
+			earliest = last
+			blocker = p
+		}
+	}
+	return earliest, blocker, true
+}
+
+// orderDayByDependencies reorders a day's tasks so that each comes after its predecessors on the
+// same day and keeps the given order otherwise. Tasks on a dependency cycle keep their order.
+func orderDayByDependencies(tasks []models.ScheduledTaskInfo) []models.ScheduledTaskInfo {
+	present := make(map[int64]bool, len(tasks))
+	for _, task := range tasks {
+		present[task.TaskID] = true
+	}
+	ready := func(task models.ScheduledTaskInfo, emitted map[int64]bool) bool {
+		for _, p := range task.DependsOn {
+			if p != task.TaskID && present[p] && !emitted[p] {
+				return false
+			}
+		}
+		return true
+	}
+
+	emitted := make(map[int64]bool, len(tasks))
+	done := make([]bool, len(tasks))
+	ordered := make([]models.ScheduledTaskInfo, 0, len(tasks))
+	for len(ordered) < len(tasks) {
+		next := -1
+		for i := range tasks {
+			if !done[i] && ready(tasks[i], emitted) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			for i := range tasks {
+				if !done[i] {
+					ordered = append(ordered, tasks[i])
+				}
+			}
+			break
+		}
+		done[next], emitted[tasks[next].TaskID] = true, true
+		ordered = append(ordered, tasks[next])
+	}
+	return ordered
+}
+
+// predecessorsEnd is the latest end among the blocks already laid out on the day for the task's
+// predecessors, or zero when none of them is on it.
+func predecessorsEnd(task models.ScheduledTaskInfo, ends map[int64]time.Time) time.Time {
+	var latest time.Time
+	for _, p := range task.DependsOn {
+		if end, ok := ends[p]; ok && p != task.TaskID && end.After(latest) {
+			latest = end
+		}
+	}
+	return latest
+}
+
+// predecessorsEndOn is, on the slot grid, the latest end of a block the predecessors hold on the
+// day (blockEnds: end of each task's latest block), or zero when they hold none there.
+func predecessorsEndOn(dependsOn []int64, blockEnds map[int64]time.Time, dateKey string) time.Time {
+	var latest time.Time
+	for _, p := range dependsOn {
+		if end, ok := blockEnds[p]; ok && end.Format("2006-01-02") == dateKey && end.After(latest) {
+			latest = end
+		}
+	}
+	return latest
+}
+
+// noteBlockEnds remembers the end of the task's latest block on the slot grid.
+func (s *Scheduler) noteBlockEnds(taskID int64, blocks []interval) {
+	if s.blockEnds == nil {
+		s.blockEnds = make(map[int64]time.Time)
+	}
+	for _, b := range blocks {
+		if b.end.After(s.blockEnds[taskID]) {
+			s.blockEnds[taskID] = b.end
+		}
+	}
+}
+
+// blockEndsOf returns the end of each task's latest block in a laid-out plan.
+func blockEndsOf(allocations []models.SlotAllocation) map[int64]time.Time {
+	ends := make(map[int64]time.Time)
+	for _, a := range allocations {
+		if a.End.After(ends[a.TaskID]) {
+			ends[a.TaskID] = a.End
+		}
+	}
+	return ends
+}
+
+// lastAllocatedDate returns the latest day holding hours of the task.
+func lastAllocatedDate(taskID int64, daySlots map[string]*models.DaySchedule) (time.Time, bool) {
+	var last time.Time
+	found := false
+	for _, day := range daySlots {
+		for _, info := range day.Tasks {
+			if info.TaskID == taskID && info.HoursAllocated > 1e-9 {
+				if !found || day.Date.After(last) {
+					last = day.Date
+				}
+				found = true
+			}
+		}
+	}
+	return last, found
+}
+
+// lastScheduledDateOf returns the latest planned day among the given task IDs in a saved plan.
+func lastScheduledDateOf(taskIDs []int64, existing []models.DaySchedule) (time.Time, bool) {
+	want := make(map[int64]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		want[id] = true
+	}
+	var last time.Time
+	found := false
+	for _, day := range existing {
+		for _, info := range day.Tasks {
+			if want[info.TaskID] && (!found || day.Date.After(last)) {
+				last = day.Date
+				found = true
+			}
+		}
+	}
+	return last, found
+}
+
+func allEmitted(ids []int64, emitted map[int64]bool) bool {
+	for _, id := range ids {
+		if !emitted[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,283 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestWouldCreateCycle(t *testing.T) {
+	deps := map[int64][]int64{
+		2: {1}, // 2 after 1
+		3: {2}, // 3 after 2
+	}
+
+	tests := []struct {
+		name        string
+		task, after int64
+		want        bool
+	}{
+		{"self", 1, 1, true},
+		{"direct back edge", 1, 2, true},
+		{"indirect back edge", 1, 3, true},
+		{"new independent edge", 3, 1, false},
+		{"unrelated task", 4, 3, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := WouldCreateCycle(deps, tc.task, tc.after); got != tc.want {
+				t.Errorf("WouldCreateCycle(%d, %d) = %v, want %v", tc.task, tc.after, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScheduler_PredecessorScheduledFirst(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 4, WorkDays: []int{1, 2, 3, 4, 5}}
+	tasks := []models.Task{
+		{ID: 1, Title: "Implement", HoursRequired: 4, Priority: 10, DependsOn: []int64{2}},
+		{ID: 2, Title: "Write spec", HoursRequired: 4, Priority: 1},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if !result.Success {
+		t.Fatalf("expected success, got %s", result.Message)
+	}
+	if len(result.DaySchedules) != 2 {
+		t.Fatalf("expected 2 days, got %d", len(result.DaySchedules))
+	}
+	if result.DaySchedules[0].Tasks[0].TaskID != 2 {
+		t.Errorf("expected spec (ID 2) on Monday, got %d", result.DaySchedules[0].Tasks[0].TaskID)
+	}
+	if result.DaySchedules[1].Tasks[0].TaskID != 1 {
+		t.Errorf("expected implement (ID 1) on Tuesday, got %d", result.DaySchedules[1].Tasks[0].TaskID)
+	}
+}
+
+func TestScheduler_ImpliedDeadlinePropagatesBackwards(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 4, WorkDays: []int{1, 2, 3, 4, 5}}
+	friday := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: 1, Title: "Release", HoursRequired: 4, Priority: 5, Deadline: &friday, DependsOn: []int64{2}},
+		{ID: 2, Title: "Prepare", HoursRequired: 4, Priority: 5},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if !result.Success {
+		t.Fatalf("expected success, got %s", result.Message)
+	}
+
+	days := map[int64]time.Weekday{}
+	for _, ds := range result.DaySchedules {
+		for _, info := range ds.Tasks {
+			days[info.TaskID] = ds.Date.Weekday()
+		}
+	}
+	if days[2] != time.Thursday {
+		t.Errorf("expected predecessor on Thursday (implied deadline), got %v", days[2])
+	}
+	if days[1] != time.Friday {
+		t.Errorf("expected successor on Friday, got %v", days[1])
+	}
+}
+
+func TestScheduler_SharedDayKeepsPredecessorFirst(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}}
+	tasks := []models.Task{
+		{ID: 1, Title: "Second", HoursRequired: 2, Priority: 10, DependsOn: []int64{2}},
+		{ID: 2, Title: "First", HoursRequired: 2, Priority: 1},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if len(result.DaySchedules) != 1 || len(result.DaySchedules[0].Tasks) != 2 {
+		t.Fatalf("expected both tasks on one day, got %+v", result.DaySchedules)
+	}
+	if result.DaySchedules[0].Tasks[0].TaskID != 2 {
+		t.Errorf("predecessor must be laid out first within the day, got order %+v", result.DaySchedules[0].Tasks)
+	}
+}
+
+func TestScheduler_CycleReportedAsBlocked(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}}
+	tasks := []models.Task{
+		{ID: 1, Title: "A", HoursRequired: 1, DependsOn: []int64{2}},
+		{ID: 2, Title: "B", HoursRequired: 1, DependsOn: []int64{1}},
+		{ID: 3, Title: "Free", HoursRequired: 1},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if result.Success {
+		t.Fatal("expected failure for cyclic tasks")
+	}
+	if len(result.UnscheduledTasks) != 2 {
+		t.Errorf("expected 2 unscheduled tasks, got %v", result.UnscheduledTasks)
+	}
+	if result.BlockedBy[1] != 2 || result.BlockedBy[2] != 1 {
+		t.Errorf("unexpected blockers: %v", result.BlockedBy)
+	}
+}
+
+func TestScheduler_UnschedulablePredecessorBlocksChain(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}}
+	past := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: 1, Title: "Late spec", HoursRequired: 2, Deadline: &past},
+		{ID: 2, Title: "Implement", HoursRequired: 2, DependsOn: []int64{1}},
+		{ID: 3, Title: "Test", HoursRequired: 2, DependsOn: []int64{2}},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if len(result.UnscheduledTasks) != 3 {
+		t.Fatalf("expected whole chain unscheduled, got %v", result.UnscheduledTasks)
+	}
+	if result.BlockedBy[2] != 1 || result.BlockedBy[3] != 2 {
+		t.Errorf("expected chain 3->2->1 in blockers, got %v", result.BlockedBy)
+	}
+	if _, ok := result.BlockedBy[1]; ok {
+		t.Error("root task has no blocker")
+	}
+}
+
+func TestScheduleTaskIntoExisting_AfterPredecessor(t *testing.T) {
+	user := &models.User{
+		ID:            1,
+		DailyCapacity: 8,
+		WorkDays:      []int{1, 2, 3, 4, 5},
+		WorkStart:     "09:00",
+		WorkEnd:       "17:00",
+	}
+	startDate := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	existing := []models.DaySchedule{
+		{
+			Date:       startDate.AddDate(0, 0, 2), // Wednesday
+			Tasks:      []models.ScheduledTaskInfo{{TaskID: 1, Title: "Spec", HoursAllocated: 2}},
+			TotalHours: 2,
+		},
+	}
+	task := models.Task{ID: 2, Title: "Implement", HoursRequired: 2, DependsOn: []int64{1}}
+
+	days, ok := ScheduleTaskIntoExisting(user, &task, existing, startDate, nil)
+	if !ok {
+		t.Fatal("expected task to be scheduled")
+	}
+	if days[0].Date.Weekday() != time.Wednesday {
+		t.Errorf("expected placement on predecessor's day (Wednesday), got %v", days[0].Date.Weekday())
+	}
+}
+
+func TestScheduleTaskIntoExisting_CarryOverAfterPredecessorBlock(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00", SchedulingStrategy: StrategyASAP}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	existing := []models.DaySchedule{{
+		Date:       monday,
+		Tasks:      []models.ScheduledTaskInfo{{TaskID: 1, Title: "Spec", HoursAllocated: 6}},
+		TotalHours: 6,
+	}}
+	// The rest of a task the user did not finish, carried over after a missed-work answer.
+	carry := models.Task{ID: 2, Title: "Implement", HoursRequired: 5, HoursSpent: 1, DependsOn: []int64{1}}
+
+	days, ok := ScheduleTaskIntoExisting(user, &carry, existing, monday, nil)
+	if !ok {
+		t.Fatal("expected the rest to be carried over")
+	}
+	for _, day := range days {
+		if day.Date.Equal(monday) && day.TotalHours > 2+1e-9 {
+			t.Errorf("expected at most the 2 hours after the spec on Monday, got %.1f h", day.TotalHours)
+		}
+	}
+}
+
+func TestPlanTimeAllocations_PredecessorBeforePreferredSuccessor(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00"}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	days := []models.DaySchedule{{
+		Date: monday,
+		Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, Title: "Implement", HoursAllocated: 2, TimePreference: "before 12:00", DependsOn: []int64{2}},
+			{TaskID: 2, Title: "Spec", HoursAllocated: 2},
+		},
+		TotalHours: 4,
+	}}
+
+	first := make(map[int64]time.Time)
+	last := make(map[int64]time.Time)
+	for _, a := range PlanTimeAllocations(user, days, monday, nil) {
+		if _, ok := first[a.TaskID]; !ok {
+			first[a.TaskID] = a.Start
+		}
+		last[a.TaskID] = a.End
+	}
+	if first[1].Before(last[2]) {
+		t.Errorf("successor starts at %s before its predecessor ends at %s", first[1].Format("15:04"), last[2].Format("15:04"))
+	}
+}
+
+func TestPlanTimeAllocations_SuccessorAfterLatePredecessor(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00"}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	days := []models.DaySchedule{{
+		Date: monday,
+		Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, Title: "Spec", HoursAllocated: 2, TimePreference: "after 13:00"},
+			{TaskID: 2, Title: "Implement", HoursAllocated: 2, DependsOn: []int64{1}},
+		},
+		TotalHours: 4,
+	}}
+
+	for _, a := range PlanTimeAllocations(user, days, monday, nil) {
+		if a.TaskID == 2 && a.Start.Hour() < 15 {
+			t.Errorf("successor block at %s starts before the predecessor ends at 15:00", a.Start.Format("15:04"))
+		}
+	}
+}
+
+func TestScheduler_SharedDayStartsAfterPredecessorEnds(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00", SchedulingStrategy: StrategyASAP}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	start, end := monday.Add(14*time.Hour), monday.Add(16*time.Hour)
+	tasks := []models.Task{
+		{ID: 1, Title: "Kickoff", HoursRequired: 2, Priority: 5, PinnedStart: &start, PinnedEnd: &end},
+		{ID: 2, Title: "Follow-up", HoursRequired: 3, Priority: 5, DependsOn: []int64{1}},
+	}
+
+	result := NewSchedulerWithSlots(user, tasks, BuildWorkSlots(user, monday, nil)).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected both tasks planned, got %+v", result)
+	}
+	var planned float64
+	for _, a := range PlanTimeAllocations(user, result.DaySchedules, monday, nil) {
+		if a.TaskID != 2 {
+			continue
+		}
+		if a.Start.Before(end) {
+			t.Errorf("follow-up block at %s starts before the kickoff ends", a.Start.Format("02.01 15:04"))
+		}
+		planned += a.End.Sub(a.Start).Hours()
+	}
+	if planned < 3-1e-9 {
+		t.Errorf("expected all 3 hours of the follow-up laid out, got %.1f", planned)
+	}
+}
+
+func TestScheduleTaskIntoExisting_SharedDayAfterPredecessor(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00", SchedulingStrategy: StrategyASAP}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	start, end := monday.Add(14*time.Hour), monday.Add(16*time.Hour)
+	existing := []models.DaySchedule{{
+		Date:       monday,
+		Tasks:      []models.ScheduledTaskInfo{{TaskID: 1, Title: "Kickoff", HoursAllocated: 2, PinnedStart: &start, PinnedEnd: &end}},
+		TotalHours: 2,
+	}}
+	task := models.Task{ID: 2, Title: "Follow-up", HoursRequired: 3, DependsOn: []int64{1}}
+
+	days, ok := ScheduleTaskIntoExisting(user, &task, existing, monday, nil)
+	if !ok {
+		t.Fatal("expected the task to be scheduled")
+	}
+	for _, day := range days {
+		if day.Date.Equal(monday) && day.TotalHours > 1+1e-9 {
+			t.Errorf("expected at most the hour after the kickoff on Monday, got %.1f h", day.TotalHours)
+		}
+	}
+}
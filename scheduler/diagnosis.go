@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// diagnose explains why task did not fit, measured against the plan built so far: daySlots holds
+// only tasks ranked ahead of it (and pinned ones), plus whatever part of it was placed.
+func (s *Scheduler) diagnose(task *models.Task, startDate, notBefore time.Time, daySlots map[string]*models.DaySchedule) models.Diagnosis {
+	loc := startDate.Location()
+	first := s.firstDayFor(task, startDate, notBefore)
+	horizonEnd := s.normalizeDate(startDate).AddDate(0, 0, s.planningHorizonDays-1)
+	deadline := s.deadlineFor(task)
+
+	d := models.Diagnosis{NeededHours: RemainingHours(task), WindowStart: first, WindowEnd: horizonEnd}
+	last := horizonEnd
+	if deadline != nil {
+		last = deadlineDay(*deadline, loc)
+		d.WindowEnd = wallClock(*deadline, loc)
+	}
+
+	slotsByDay := make(map[string][]int)
+	for i := range s.workSlots {
+		key := s.formatDate(s.workSlots[i].Date)
+		slotsByDay[key] = append(slotsByDay[key], i)
+	}
+
+	taken := make(map[int64]*models.TaskHours)
+	maxPerDay := MaxHoursPerDay(s.user, task)
+	budget := ProjectBudget(s.user, task.Project)
+	budgetLeftByWeek := make(map[string]float64) // Monday -> project budget still open to the task
+	workDays := 0
+	reachable := 0.0 // free hours from the first day on, ignoring the deadline
+
+	for day := first; !day.After(horizonEnd); day = day.AddDate(0, 0, 1) {
+		if !s.isWorkDay(day) {
+			continue
+		}
+		dateKey := s.formatDate(day)
+		inWindow := !day.After(last)
+		until := time.Time{}
+		if inWindow {
+			until = notAfterOn(deadline, dateKey, loc)
+		}
+		work, busy := s.rangeHours(day, slotsByDay[dateKey], notBeforeOn(task.StartAfter, dateKey, loc), until)
+
+		var other float64
+		if planned, ok := daySlots[dateKey]; ok {
+			for _, info := range planned.Tasks {
+				if info.TaskID == task.ID {
+					continue
+				}
+				other += info.HoursAllocated
+				if !inWindow {
+					continue
+				}
+				if taken[info.TaskID] == nil {
+					taken[info.TaskID] = &models.TaskHours{TaskID: info.TaskID, Title: info.Title}
+				}
+				taken[info.TaskID].Hours += info.HoursAllocated
+			}
+		}
+
+		capacity := s.capacityOn(day)
+		free := math.Max(0, math.Min(capacity, work-busy)-other)
+		if maxPerDay > 0 {
+			free = math.Min(free, maxPerDay)
+		}
+		if budget > 0 {
+			week := s.formatDate(WeekStart(day))
+			left, seen := budgetLeftByWeek[week]
+			if !seen {
+				left = math.Max(0, budget-projectHoursInWeek(task.Project, day, daySlots, task.ID))
+			}
+			if free > left {
+				free = left
+				if inWindow {
+					d.BudgetHours = budget
+				}
+			}
+			budgetLeftByWeek[week] = left - free
+		}
+		reachable += free
+
+		if inWindow {
+			workDays++
+			d.WindowHours += math.Min(capacity, work)
+			d.BusyHours += busy
+			d.TakenHours += other
+			d.FreeHours += free
+			continue
+		}
+		if deadline != nil && reachable >= d.NeededHours-1e-9 {
+			fit := day
+			d.FitDeadline = &fit
+			break
+		}
+	}
+
+	for _, th := range taken {
+		d.TakenBy = append(d.TakenBy, *th)
+	}
+	sort.Slice(d.TakenBy, func(i, j int) bool {
+		if d.TakenBy[i].Hours != d.TakenBy[j].Hours {
+			return d.TakenBy[i].Hours > d.TakenBy[j].Hours
+		}
+		return d.TakenBy[i].TaskID < d.TakenBy[j].TaskID
+	})
+
+	shortfall := d.NeededHours - d.FreeHours
+	if shortfall <= 1e-9 {
+		// Enough room in total: the free time is split into pieces the task's limits cannot use.
+		d.FitDeadline = nil
+		return d
+	}
+	if workDays > 0 {
+		d.ExtraPerDay = math.Ceil(shortfall/float64(workDays)*2) / 2
+	}
+	d.YieldTasks = s.yieldCandidates(task, d.TakenBy, shortfall)
+	return d
+}
+
+// yieldCandidates picks tasks from taken that are ahead of task only by priority (not by an earlier
+// deadline and not pinned), most hours first, until their hours cover shortfall.
+func (s *Scheduler) yieldCandidates(task *models.Task, taken []models.TaskHours, shortfall float64) []models.TaskHours {
+	byID := make(map[int64]*models.Task, len(s.tasks))
+	for i := range s.tasks {
+		byID[s.tasks[i].ID] = &s.tasks[i]
+	}
+	own := s.deadlineFor(task)
+
+	var picked []models.TaskHours
+	for _, th := range taken {
+		other, ok := byID[th.TaskID]
+		if !ok || IsPinned(other) {
+			continue
+		}
+		if d := s.deadlineFor(other); d != nil && (own == nil || d.Before(*own)) {
+			continue
+		}
+		picked = append(picked, th)
+		shortfall -= th.Hours
+		if shortfall <= 1e-9 {
+			break
+		}
+	}
+	return picked
+}
+
+// rangeHours returns the working and calendar-busy hours of a day between from and until
+// (zero bounds leave the day open). daySlots are indices into the slot grid; without a grid
+// calendar events are unknown and busy is 0.
+func (s *Scheduler) rangeHours(date time.Time, daySlots []int, from, until time.Time) (work, busy float64) {
+	if len(s.workSlots) == 0 {
+		return workHoursBetween(s.user, date, from, until), 0
+	}
+	for _, i := range daySlots {
+		slot := &s.workSlots[i]
+		if (!from.IsZero() && slot.Start.Before(from)) || (!until.IsZero() && slot.End.After(until)) {
+			continue
+		}
+		work += slot.CapacityHours
+		busy += s.busyHours[i]
+	}
+	return work, busy
+}
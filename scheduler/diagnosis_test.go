@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestSchedule_DiagnosisOfUnscheduledTask(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	busy := []models.BusyInterval{{Start: monday.Add(9 * time.Hour), End: monday.Add(10 * time.Hour), Source: "calendar"}}
+	tasks := []models.Task{
+		{ID: 1, Title: "Important", HoursRequired: 5, Priority: 9, Deadline: &tuesday},
+		{ID: 2, Title: "Report", HoursRequired: 6, Priority: 5, Deadline: &tuesday},
+	}
+
+	result := NewSchedulerWithSlots(user, tasks, BuildWorkSlots(user, monday, busy)).Schedule(monday)
+	if len(result.UnscheduledTasks) != 1 || result.UnscheduledTasks[0] != 2 {
+		t.Fatalf("expected only task 2 unscheduled, got %+v", result.UnscheduledTasks)
+	}
+	d, ok := result.Diagnoses[2]
+	if !ok {
+		t.Fatal("expected a diagnosis for task 2")
+	}
+	// Monday and Tuesday 09:00–13:00: 8h, 1h of it a calendar event, 5h taken by task 1.
+	if d.WindowHours != 8 || d.BusyHours != 1 || d.TakenHours != 5 || d.FreeHours != 2 {
+		t.Errorf("unexpected accounting: %+v", d)
+	}
+	if len(d.TakenBy) != 1 || d.TakenBy[0].TaskID != 1 {
+		t.Errorf("expected task 1 to be named, got %+v", d.TakenBy)
+	}
+	// 4h short over two work days.
+	if d.ExtraPerDay != 2 {
+		t.Errorf("ExtraPerDay = %v, want 2", d.ExtraPerDay)
+	}
+	// Wednesday adds 4 free hours: 2 + 4 covers the 6 needed.
+	if d.FitDeadline == nil || !d.FitDeadline.Equal(monday.AddDate(0, 0, 2)) {
+		t.Errorf("FitDeadline = %v, want Wednesday", d.FitDeadline)
+	}
+	// Task 1 is ahead only by priority (same deadline).
+	if len(d.YieldTasks) != 1 || d.YieldTasks[0].TaskID != 1 {
+		t.Errorf("expected task 1 as a priority candidate, got %+v", d.YieldTasks)
+	}
+}
+
+func TestSchedule_DiagnosisSkipsEarlierDeadlines(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	tasks := []models.Task{
+		{ID: 1, Title: "Due Monday", HoursRequired: 4, Priority: 1, Deadline: &monday},
+		{ID: 2, Title: "Due Tuesday", HoursRequired: 6, Priority: 9, Deadline: &tuesday},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	d, ok := result.Diagnoses[2]
+	if !ok {
+		t.Fatalf("expected a diagnosis for task 2, got %+v", result)
+	}
+	if d.TakenHours != 4 || len(d.YieldTasks) != 0 {
+		t.Errorf("a task with an earlier deadline is not a priority candidate: %+v", d)
+	}
+}
+
+func TestSchedule_NoDiagnosisForBlockedTask(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: 1, Title: "A", HoursRequired: 2, DependsOn: []int64{2}},
+		{ID: 2, Title: "B", HoursRequired: 2, DependsOn: []int64{1}},
+	}
+
+	result := NewScheduler(morningUser(), tasks).Schedule(monday)
+	if len(result.UnscheduledTasks) != 2 || len(result.Diagnoses) != 0 {
+		t.Errorf("blocked tasks are explained by BlockedBy, got %+v", result)
+	}
+}
@@ -0,0 +1,226 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// Energy levels of tasks and of the hours of the day.
+const (
+	EnergyHigh   = "high"
+	EnergyMedium = "medium"
+	EnergyLow    = "low"
+)
+
+var energyLevels = map[string]string{
+	"high": EnergyHigh, "высокая": EnergyHigh, "высокий": EnergyHigh,
+	"medium": EnergyMedium, "средняя": EnergyMedium, "средний": EnergyMedium,
+	"low": EnergyLow, "низкая": EnergyLow, "низкий": EnergyLow,
+}
+
+// energyOrder is the order in which a task of a level takes hours of each level: its own level
+// first, then the nearest. Demanding tasks keep away from low hours and light ones from peak hours.
+var energyOrder = map[string][]string{
+	EnergyHigh:   {EnergyHigh, EnergyMedium, EnergyLow},
+	EnergyMedium: {EnergyMedium, EnergyHigh, EnergyLow},
+	EnergyLow:    {EnergyLow, EnergyMedium, EnergyHigh},
+}
+
+// energyRank orders a day's tasks for slot assignment: high-energy tasks claim peak hours first.
+var energyRank = map[string]int{EnergyHigh: 0, EnergyMedium: 1, EnergyLow: 2}
+
+// ParseEnergyLevel reads "high", "medium" or "low" (or the Russian words) into its stored form.
+func ParseEnergyLevel(s string) (string, error) {
+	level, ok := energyLevels[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return "", fmt.Errorf("unknown energy level %q", s)
+	}
+	return level, nil
+}
+
+// energyOf is the level of a task; tasks without one count as medium.
+func energyOf(level string) string {
+	if level == "" {
+		return EnergyMedium
+	}
+	return level
+}
+
+// ParseEnergyCurve parses a curve such as "high 09:00-12:00 low after 16:00": a level followed by
+// one or more windows (HH:MM-HH:MM, after HH:MM, before HH:MM). Hours outside every window are
+// medium. Windows may not overlap.
+func ParseEnergyCurve(spec string) ([]models.EnergyWindow, error) {
+	tokens := strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool { return r == ' ' || r == ',' || r == ';' })
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty energy curve")
+	}
+
+	var windows []models.EnergyWindow
+	level := ""
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if l, ok := energyLevels[token]; ok {
+			level = l
+			continue
+		}
+		if level == "" {
+			return nil, fmt.Errorf("%q needs a level before it", token)
+		}
+		w := models.EnergyWindow{Level: level}
+		switch token {
+		case "after", "после", "before", "до":
+			if i+1 == len(tokens) {
+				return nil, fmt.Errorf("%q needs a time", token)
+			}
+			i++
+			minute, err := parseClockMinutes(tokens[i])
+			if err != nil {
+				return nil, err
+			}
+			if token == "after" || token == "после" {
+				w.From = minute
+			} else {
+				w.Until = minute
+			}
+		default:
+			from, until, ok := strings.Cut(token, "-")
+			if !ok {
+				return nil, fmt.Errorf("unknown energy window %q", token)
+			}
+			var err error
+			if w.From, err = parseClockMinutes(from); err != nil {
+				return nil, err
+			}
+			if w.Until, err = parseClockMinutes(until); err != nil {
+				return nil, err
+			}
+		}
+		if w.Until > 0 && w.From >= w.Until {
+			return nil, fmt.Errorf("empty energy window %q", token)
+		}
+		for _, other := range windows {
+			if windowsOverlap(w, other) {
+				return nil, fmt.Errorf("energy windows overlap")
+			}
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("energy curve has no windows")
+	}
+	return windows, nil
+}
+
+// FormatEnergyCurve serializes a curve back to the form accepted by ParseEnergyCurve.
+func FormatEnergyCurve(windows []models.EnergyWindow) string {
+	var parts []string
+	for _, w := range windows {
+		parts = append(parts, w.Level+" "+formatEnergyWindow(w))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatEnergyWindow(w models.EnergyWindow) string {
+	switch {
+	case w.From > 0 && w.Until > 0:
+		return formatClockMinutes(w.From) + "-" + formatClockMinutes(w.Until)
+	case w.Until > 0:
+		return "before " + formatClockMinutes(w.Until)
+	default:
+		return "after " + formatClockMinutes(w.From)
+	}
+}
+
+func windowsOverlap(a, b models.EnergyWindow) bool {
+	end := func(w models.EnergyWindow) int {
+		if w.Until == 0 {
+			return 24 * 60
+		}
+		return w.Until
+	}
+	return a.From < end(b) && b.From < end(a)
+}
+
+// energyCurveOf parses a stored curve; one that no longer parses means no curve.
+func energyCurveOf(spec string) []models.EnergyWindow {
+	if spec == "" {
+		return nil
+	}
+	windows, err := ParseEnergyCurve(spec)
+	if err != nil {
+		return nil
+	}
+	return windows
+}
+
+// slotEnergy is the level of the window a slot lies wholly inside, or medium.
+func slotEnergy(curve []models.EnergyWindow, slot *models.TimeSlot) string {
+	midnight := time.Date(slot.Start.Year(), slot.Start.Month(), slot.Start.Day(), 0, 0, 0, 0, slot.Start.Location())
+	start, end := int(slot.Start.Sub(midnight).Minutes()), int(slot.End.Sub(midnight).Minutes())
+	for _, w := range curve {
+		if start >= w.From && (w.Until == 0 || end <= w.Until) {
+			return w.Level
+		}
+	}
+	return EnergyMedium
+}
+
+// sortByEnergy puts a day's high-energy tasks before medium and low ones so that they get the peak
+// hours; tasks due at a time of that day stay in front.
+func sortByEnergy(tasks []models.ScheduledTaskInfo, dateKey string, loc *time.Location) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		di := !notAfterOn(tasks[i].Deadline, dateKey, loc).IsZero()
+		dj := !notAfterOn(tasks[j].Deadline, dateKey, loc).IsZero()
+		if di || dj {
+			return di && !dj
+		}
+		return energyRank[energyOf(tasks[i].Energy)] < energyRank[energyOf(tasks[j].Energy)]
+	})
+}
+
+// placeByEnergy places want hours of a task of the given level in daySlots: in hours of its own
+// level first, then in the nearest levels (energyOrder). Without a curve it is placeChunks.
+func placeByEnergy(daySlots []*models.TimeSlot, curve []models.EnergyWindow, level string, want, minChunk float64) []interval {
+	if len(curve) == 0 {
+		return placeChunks(daySlots, want, want, minChunk)
+	}
+	var placed []interval
+	for _, tier := range energyOrder[energyOf(level)] {
+		rest := want - hoursOf(placed)
+		if rest <= 1e-9 {
+			break
+		}
+		var kept []*models.TimeSlot
+		for _, slot := range daySlots {
+			if slotEnergy(curve, slot) == tier {
+				kept = append(kept, slot)
+			}
+		}
+		placed = append(placed, placeChunks(kept, rest, rest, minChunk)...)
+	}
+	return placed
+}
+
+// highEnergyLeft returns how many more hours of a high-energy task fit under the user's daily cap
+// on high-energy hours, given the tasks already on the day, or -1 when no cap applies to the task.
+func highEnergyLeft(user *models.User, task *models.Task, days ...[]models.ScheduledTaskInfo) float64 {
+	if user.MaxHighEnergyHours <= 0 || task.Energy != EnergyHigh {
+		return -1
+	}
+	left := user.MaxHighEnergyHours
+	for _, tasks := range days {
+		for _, info := range tasks {
+			if info.Energy == EnergyHigh {
+				left -= info.HoursAllocated
+			}
+		}
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
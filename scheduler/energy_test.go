@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestParseEnergyCurve(t *testing.T) {
+	windows, err := ParseEnergyCurve("высокая 09:00-12:00, low after 16:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := FormatEnergyCurve(windows); got != "high 09:00-12:00 low after 16:00" {
+		t.Errorf("got %q", got)
+	}
+
+	for _, spec := range []string{"", "09:00-12:00", "high", "high 12:00-09:00", "high before 12:00 low 11:00-13:00", "high soon"} {
+		if _, err := ParseEnergyCurve(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestPlanTimeAllocations_EnergyCurve(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00", EnergyCurve: "high 09:00-11:00 low after 15:00"}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	days := []models.DaySchedule{{
+		Date: monday,
+		Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, Title: "Email", HoursAllocated: 1, Energy: EnergyLow},
+			{TaskID: 2, Title: "Plain", HoursAllocated: 1},
+			{TaskID: 3, Title: "Design", HoursAllocated: 2, Energy: EnergyHigh},
+		},
+		TotalHours: 4,
+	}}
+
+	starts := make(map[int64]int)
+	for _, a := range PlanTimeAllocations(user, days, monday, nil) {
+		if _, ok := starts[a.TaskID]; !ok {
+			starts[a.TaskID] = a.Start.Hour()
+		}
+	}
+	if starts[3] != 9 {
+		t.Errorf("expected the high-energy task in the morning peak, got %d:00", starts[3])
+	}
+	if starts[2] != 11 {
+		t.Errorf("expected the plain task in medium hours, got %d:00", starts[2])
+	}
+	if starts[1] != 15 {
+		t.Errorf("expected the low-energy task after 15:00, got %d:00", starts[1])
+	}
+}
+
+func TestScheduler_MaxHighEnergyHours(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, SchedulingStrategy: StrategyASAP, MaxHighEnergyHours: 3}
+	tasks := []models.Task{
+		{ID: 1, Title: "Design", HoursRequired: 2, Priority: 9, Energy: EnergyHigh},
+		{ID: 2, Title: "Research", HoursRequired: 2, Priority: 8, Energy: EnergyHigh},
+		{ID: 3, Title: "Admin", HoursRequired: 2, Priority: 7, Energy: EnergyLow},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected all tasks planned, got %+v", result)
+	}
+	for _, day := range result.DaySchedules {
+		high := 0.0
+		for _, info := range day.Tasks {
+			if info.Energy == EnergyHigh {
+				high += info.HoursAllocated
+			}
+		}
+		if high > 3+1e-9 {
+			t.Errorf("%s: %.1f high-energy hours over the cap of 3", day.Date.Format("2006-01-02"), high)
+		}
+	}
+	if first := result.DaySchedules[0]; first.TotalHours < 5-1e-9 {
+		t.Errorf("expected the cap to leave room for other work on Monday, got %.1f h", first.TotalHours)
+	}
+}
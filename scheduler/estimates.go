@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+const (
+	// minBiasSamples is how many completed tasks a ratio needs before it is trusted.
+	minBiasSamples = 3
+	minBiasFactor  = 0.5
+	maxBiasFactor  = 3.0
+)
+
+// Priority bands used to group estimate accuracy.
+const (
+	BandLow    = "low"    // priority 1–3
+	BandMedium = "medium" // priority 4–7
+	BandHigh   = "high"   // priority 8–10
+)
+
+// BiasStat is actual/estimated hours over a group of completed tasks.
+type BiasStat struct {
+	Ratio   float64 // > 1 means the user underestimates
+	Samples int
+}
+
+// EstimateBias is the learned estimate accuracy of one user.
+type EstimateBias struct {
+	Overall BiasStat
+	ByBand  map[string]BiasStat
+}
+
+// RatioPoint is the estimate ratio of tasks completed in one month.
+type RatioPoint struct {
+	Month time.Time
+	BiasStat
+}
+
+// PriorityBand maps a 1–10 priority to BandLow, BandMedium or BandHigh.
+func PriorityBand(priority int) string {
+	switch {
+	case priority >= 8:
+		return BandHigh
+	case priority >= 4:
+		return BandMedium
+	default:
+		return BandLow
+	}
+}
+
+// ComputeEstimateBias aggregates samples overall and per priority band.
+func ComputeEstimateBias(samples []models.EstimateSample) EstimateBias {
+	var overall ratioSum
+	bands := make(map[string]*ratioSum)
+	for _, s := range samples {
+		overall.add(s)
+		band := PriorityBand(s.Priority)
+		if bands[band] == nil {
+			bands[band] = &ratioSum{}
+		}
+		bands[band].add(s)
+	}
+
+	bias := EstimateBias{Overall: overall.stat(), ByBand: make(map[string]BiasStat, len(bands))}
+	for band, sum := range bands {
+		bias.ByBand[band] = sum.stat()
+	}
+	return bias
+}
+
+// Factor returns the multiplier for the task's estimate: the ratio of its priority band,
+// falling back to the overall ratio, or 1 while there are too few samples.
+func (b EstimateBias) Factor(task *models.Task) float64 {
+	stat, ok := b.ByBand[PriorityBand(task.Priority)]
+	if !ok || stat.Samples < minBiasSamples {
+		stat = b.Overall
+	}
+	if stat.Samples < minBiasSamples || stat.Ratio <= 0 {
+		return 1
+	}
+	return math.Min(maxBiasFactor, math.Max(minBiasFactor, stat.Ratio))
+}
+
+// InflateEstimates returns a copy of tasks with HoursRequired scaled by the learned factor.
+// Logged time is kept, so only the remaining part of the corrected estimate is planned.
+func InflateEstimates(tasks []models.Task, bias EstimateBias) []models.Task {
+	out := make([]models.Task, len(tasks))
+	copy(out, tasks)
+	for i := range out {
+		if f := bias.Factor(&out[i]); f != 1 {
+			out[i].HoursRequired = math.Round(out[i].HoursRequired*f*100) / 100
+		}
+	}
+	return out
+}
+
+// EstimateRatioHistory groups samples by the month of completion, oldest first.
+func EstimateRatioHistory(samples []models.EstimateSample) []RatioPoint {
+	byMonth := make(map[time.Time]*ratioSum)
+	for _, s := range samples {
+		month := time.Date(s.CompletedAt.Year(), s.CompletedAt.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if byMonth[month] == nil {
+			byMonth[month] = &ratioSum{}
+		}
+		byMonth[month].add(s)
+	}
+
+	points := make([]RatioPoint, 0, len(byMonth))
+	for month, sum := range byMonth {
+		points = append(points, RatioPoint{Month: month, BiasStat: sum.stat()})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Month.Before(points[j].Month) })
+	return points
+}
+
+type ratioSum struct {
+	estimated, actual float64
+	n                 int
+}
+
+func (r *ratioSum) add(s models.EstimateSample) {
+	r.estimated += s.Estimated
+	r.actual += s.Actual
+	r.n++
+}
+
+func (r *ratioSum) stat() BiasStat {
+	if r.estimated <= 0 {
+		return BiasStat{Samples: r.n}
+	}
+	return BiasStat{Ratio: r.actual / r.estimated, Samples: r.n}
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func sample(priority int, estimated, actual float64, completed time.Time) models.EstimateSample {
+	return models.EstimateSample{Priority: priority, Estimated: estimated, Actual: actual, CompletedAt: completed}
+}
+
+func TestComputeEstimateBias(t *testing.T) {
+	oct := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	samples := []models.EstimateSample{
+		sample(9, 2, 3, oct),
+		sample(9, 4, 6, oct),
+		sample(8, 2, 3, oct),
+		sample(2, 4, 4, oct.AddDate(0, -1, 0)),
+	}
+
+	bias := ComputeEstimateBias(samples)
+	if bias.Overall.Samples != 4 || math.Abs(bias.Overall.Ratio-16.0/12.0) > 1e-9 {
+		t.Errorf("unexpected overall %+v", bias.Overall)
+	}
+	if high := bias.ByBand[BandHigh]; high.Samples != 3 || high.Ratio != 1.5 {
+		t.Errorf("unexpected high band %+v", high)
+	}
+
+	high := &models.Task{Priority: 10}
+	if f := bias.Factor(high); f != 1.5 {
+		t.Errorf("expected high-band factor 1.5, got %.2f", f)
+	}
+	// One low-priority sample is not enough, so the overall ratio is used.
+	low := &models.Task{Priority: 1}
+	if f := bias.Factor(low); math.Abs(f-16.0/12.0) > 1e-9 {
+		t.Errorf("expected overall factor for low band, got %.2f", f)
+	}
+
+	history := EstimateRatioHistory(samples)
+	if len(history) != 2 || history[0].Month.Month() != time.September || history[1].Samples != 3 {
+		t.Errorf("unexpected history %+v", history)
+	}
+}
+
+func TestFactor_NeedsSamplesAndIsClamped(t *testing.T) {
+	day := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	few := ComputeEstimateBias([]models.EstimateSample{sample(5, 1, 4, day)})
+	if f := few.Factor(&models.Task{Priority: 5}); f != 1 {
+		t.Errorf("expected neutral factor with one sample, got %.2f", f)
+	}
+
+	var wild []models.EstimateSample
+	for i := 0; i < 3; i++ {
+		wild = append(wild, sample(5, 1, 10, day))
+	}
+	if f := ComputeEstimateBias(wild).Factor(&models.Task{Priority: 5}); f != maxBiasFactor {
+		t.Errorf("expected factor clamped to %.1f, got %.2f", maxBiasFactor, f)
+	}
+}
+
+func TestInflateEstimates_KeepsLoggedTime(t *testing.T) {
+	day := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	bias := ComputeEstimateBias([]models.EstimateSample{sample(5, 2, 3, day), sample(5, 2, 3, day), sample(5, 2, 3, day)})
+	tasks := []models.Task{{ID: 1, Priority: 5, HoursRequired: 4, HoursSpent: 1}}
+
+	inflated := InflateEstimates(tasks, bias)
+	if inflated[0].HoursRequired != 6 || RemainingHours(&inflated[0]) != 5 {
+		t.Errorf("expected 6h estimate with 5h remaining, got %+v", inflated[0])
+	}
+	if tasks[0].HoursRequired != 4 {
+		t.Error("input tasks must not be modified")
+	}
+}
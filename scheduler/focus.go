@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// dayTaskLimitReached reports whether a day already holds the user's maximum of distinct flexible
+// tasks (MaxTasksPerDay) without taskID among them. Pinned meetings do not count.
+func dayTaskLimitReached(user *models.User, tasks []models.ScheduledTaskInfo, taskID int64) bool {
+	if user.MaxTasksPerDay <= 0 {
+		return false
+	}
+	distinct := 0
+	for _, info := range tasks {
+		if info.TaskID == taskID {
+			return false
+		}
+		if info.PinnedStart == nil {
+			distinct++
+		}
+	}
+	return distinct >= user.MaxTasksPerDay
+}
+
+// placeWithinTaskLimit places a task keeping to the daily task limit. When a task with a deadline
+// does not fit that way, lift switches the limit off and the rest is placed again: the limit
+// never costs a deadline.
+func placeWithinTaskLimit(user *models.User, strategy Strategy, p Placement, pref models.TimePreference, lift func()) float64 {
+	left := placePreferringDays(strategy, p, pref)
+	if left <= 1e-9 || !p.HasDeadline || user.MaxTasksPerDay <= 0 {
+		return left
+	}
+	lift()
+	p.Remaining = left
+	return placePreferringDays(strategy, p, pref)
+}
+
+// clusterByProject orders a day's flexible tasks so that tasks of one project follow each other,
+// each project where its first task was. Tasks due at a time of that day stay in front so their
+// deadlines hold; tasks without a project keep their own place.
+func clusterByProject(tasks []models.ScheduledTaskInfo, dateKey string, loc *time.Location) []models.ScheduledTaskInfo {
+	group := func(info *models.ScheduledTaskInfo) string {
+		if info.Project == "" {
+			return fmt.Sprintf("task:%d", info.TaskID)
+		}
+		return info.Project
+	}
+	first := make(map[string]int, len(tasks))
+	for i := range tasks {
+		if _, ok := first[group(&tasks[i])]; !ok {
+			first[group(&tasks[i])] = i
+		}
+	}
+
+	clustered := make([]models.ScheduledTaskInfo, len(tasks))
+	copy(clustered, tasks)
+	sort.SliceStable(clustered, func(i, j int) bool {
+		di := !notAfterOn(clustered[i].Deadline, dateKey, loc).IsZero()
+		dj := !notAfterOn(clustered[j].Deadline, dateKey, loc).IsZero()
+		if di || dj {
+			return di && !dj
+		}
+		return first[group(&clustered[i])] < first[group(&clustered[j])]
+	})
+	return clustered
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestPlanTimeAllocations_ClusterByProject(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00", ClusterProjects: true}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	noon := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	days := []models.DaySchedule{{
+		Date: monday,
+		Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, Title: "A site", HoursAllocated: 1, Project: "clienta"},
+			{TaskID: 2, Title: "Internal", HoursAllocated: 1},
+			{TaskID: 3, Title: "B report", HoursAllocated: 1, Project: "clientb", Deadline: &noon},
+			{TaskID: 4, Title: "A review", HoursAllocated: 1, Project: "clienta"},
+		},
+		TotalHours: 4,
+	}}
+
+	var order []int64
+	for _, a := range PlanTimeAllocations(user, days, monday, nil) {
+		order = append(order, a.TaskID)
+	}
+	want := []int64{3, 1, 4, 2}
+	if len(order) != len(want) {
+		t.Fatalf("got %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected the timed deadline first and clienta back to back, got %v", order)
+		}
+	}
+}
+
+func TestScheduler_MaxTasksPerDay(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, SchedulingStrategy: StrategyASAP, MaxTasksPerDay: 2}
+	tasks := []models.Task{
+		{ID: 1, Title: "One", HoursRequired: 1, Priority: 9},
+		{ID: 2, Title: "Two", HoursRequired: 1, Priority: 8},
+		{ID: 3, Title: "Three", HoursRequired: 1, Priority: 7},
+		{ID: 4, Title: "Due", HoursRequired: 1, Priority: 1, Deadline: &monday},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected all tasks planned, got %+v", result)
+	}
+	perDay := make(map[string][]int64)
+	for _, day := range result.DaySchedules {
+		for _, info := range day.Tasks {
+			perDay[day.Date.Format("2006-01-02")] = append(perDay[day.Date.Format("2006-01-02")], info.TaskID)
+		}
+	}
+	if got := perDay[monday.Format("2006-01-02")]; len(got) != 2 || got[0] != 4 {
+		t.Errorf("expected the due task and one more on Monday, got %v", got)
+	}
+	if got := perDay[tuesday.Format("2006-01-02")]; len(got) != 2 {
+		t.Errorf("expected the other two tasks on Tuesday, got %v", got)
+	}
+}
+
+func TestScheduler_MaxTasksPerDayYieldsToDeadline(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, MaxTasksPerDay: 1}
+	tasks := []models.Task{
+		{ID: 1, Title: "Due A", HoursRequired: 2, Priority: 9, Deadline: &monday},
+		{ID: 2, Title: "Due B", HoursRequired: 2, Priority: 1, Deadline: &monday},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	if !result.Success || len(result.DaySchedules) != 1 || len(result.DaySchedules[0].Tasks) != 2 {
+		t.Fatalf("expected both due tasks on Monday despite the limit, got %+v", result)
+	}
+}
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"math"
 	"sort"
 	"time"
 
@@ -8,8 +9,10 @@ import (
 )
 
 // ScheduleTaskIntoExisting places one new task into free slots, keeping existing day plans unchanged.
+// Days are chosen by the user's strategy, as in a full rebuild.
+// The task never starts before the last planned day of its predecessors found in existing.
 func ScheduleTaskIntoExisting(user *models.User, newTask *models.Task, existing []models.DaySchedule, startDate time.Time, busy []models.BusyInterval) ([]models.DaySchedule, bool) {
-	if newTask.HoursRequired <= 0 {
+	if RemainingHours(newTask) <= 0 {
 		return nil, false
 	}
 
@@ -17,78 +20,162 @@ func ScheduleTaskIntoExisting(user *models.User, newTask *models.Task, existing
 	slots := BuildWorkSlots(user, startDate, busy)
 
 	// Occupy slots with already planned tasks (same slot grid).
-	_ = applyDaySchedulesToSlots(slots, existing)
+	planned := applyDaySchedulesToSlots(user, slots, existing, startDate.Location())
+
+	if IsPinned(newTask) {
+		return pinTaskIntoExisting(newTask, planned, startDate)
+	}
 
 	slotsByDate := indexSlotsByDate(slots)
 	daySlots := make(map[string]*models.DaySchedule)
-	remaining := newTask.HoursRequired
+	remaining := RemainingHours(newTask)
+	minChunk := MinChunkHours(user, newTask.MinChunkMinutes)
+	maxPerDay := MaxHoursPerDay(user, newTask)
 
-	horizon := slotScheduler.horizonDays
 	current := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
-	daysChecked := 0
-
-	for remaining > 0 && daysChecked < horizon {
-		if !slotScheduler.isWorkDay(current) {
-			current = current.AddDate(0, 0, 1)
-			daysChecked++
-			continue
+	// Predecessors already in the plan push the first usable day to their last planned day.
+	if last, found := lastScheduledDateOf(newTask.DependsOn, existing); found && last.After(current) {
+		current = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, current.Location())
+	}
+	// On a day with their blocks the task only starts after them, as in a full rebuild.
+	blockEnds := blockEndsOf(planned)
+	if newTask.StartAfter != nil {
+		if earliest := wallClock(*newTask.StartAfter, current.Location()); earliest.After(current) {
+			current = time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, current.Location())
 		}
+	}
 
-		if newTask.Deadline != nil {
-			deadlineDay := time.Date(newTask.Deadline.Year(), newTask.Deadline.Month(), newTask.Deadline.Day(), 0, 0, 0, 0, current.Location())
-			if current.After(deadlineDay) {
-				return convertDayMapToSlice(daySlots), false
-			}
+	// Days already carry the existing plan: strategies that look at the load see it too.
+	existingLoad := make(map[string]float64)
+	existingDays := make(map[string]*models.DaySchedule, len(existing))
+	for i, day := range existing {
+		for _, info := range day.Tasks {
+			existingLoad[day.Date.Format("2006-01-02")] += info.HoursAllocated
 		}
+		existingDays[day.Date.Format("2006-01-02")] = &existing[i]
+	}
 
-		dateKey := current.Format("2006-01-02")
-		daySlotList := slotsByDate[dateKey]
-
-		for _, slot := range daySlotList {
-			if remaining <= 0 {
-				break
+	limitTasks := true
+	p := Placement{
+		First:     current,
+		Last:      current.AddDate(0, 0, slotScheduler.horizonDays-1),
+		Remaining: remaining,
+		MinChunk:  minChunk,
+		IsWorkDay: slotScheduler.isWorkDay,
+		Load: func(day time.Time) float64 {
+			key := day.Format("2006-01-02")
+			load := existingLoad[key]
+			if planned, ok := daySlots[key]; ok {
+				load += planned.TotalHours
 			}
-			free := slot.CapacityHours - slot.AllocatedHours
-			if free <= 1e-9 {
-				continue
+			return load
+		},
+		Book: func(day time.Time, want float64) float64 {
+			dateKey := day.Format("2006-01-02")
+			planned := daySlots[dateKey]
+			// The day's cap on task hours (DailyCapacity or its override) counts the existing plan too.
+			capLeft := DailyCapacityOn(user, day) - existingLoad[dateKey]
+			if planned != nil {
+				capLeft -= planned.TotalHours
 			}
-
-			toAllocate := remaining
-			if toAllocate > free {
-				toAllocate = free
+			want = math.Min(want, capLeft)
+			if maxPerDay > 0 {
+				limit := maxPerDay
+				if planned != nil {
+					limit -= planned.TotalHours
+				}
+				want = math.Min(want, limit)
+			}
+			if left := budgetLeft(user, newTask, day, existingDays, daySlots); left >= 0 {
+				want = math.Min(want, left)
+			}
+			var dayTasks, plannedTasks []models.ScheduledTaskInfo
+			if existingDay, ok := existingDays[dateKey]; ok {
+				dayTasks = existingDay.Tasks
+			}
+			if planned != nil {
+				plannedTasks = planned.Tasks
+			}
+			if left := highEnergyLeft(user, newTask, dayTasks, plannedTasks); left >= 0 {
+				want = math.Min(want, left)
+			}
+			if limitTasks && planned == nil && dayTaskLimitReached(user, dayTasks, newTask.ID) {
+				return 0
+			}
+			if want <= 1e-9 {
+				return 0
 			}
 
-			ds, ok := daySlots[dateKey]
-			if !ok {
-				ds = &models.DaySchedule{
-					Date:           current,
-					Tasks:          []models.ScheduledTaskInfo{},
-					TotalHours:     0,
-					AvailableHours: user.DailyCapacity,
+			from := notBeforeOn(newTask.StartAfter, dateKey, day.Location())
+			if after := predecessorsEndOn(newTask.DependsOn, blockEnds, dateKey); after.After(from) {
+				from = after
+			}
+			usable := slotsFrom(slotsByDate[dateKey], from)
+			usable = slotsUntil(usable, notAfterOn(newTask.Deadline, dateKey, day.Location()))
+			placed := hoursOf(placeChunks(usable, want, remaining, minChunk))
+			if placed <= 1e-9 {
+				return 0
+			}
+			if planned == nil {
+				planned = &models.DaySchedule{
+					Date: day,
+					Tasks: []models.ScheduledTaskInfo{{
+						TaskID:          newTask.ID,
+						Title:           newTask.Title,
+						Priority:        newTask.Priority,
+						Deadline:        newTask.Deadline,
+						MinChunkMinutes: newTask.MinChunkMinutes,
+						StartAfter:      newTask.StartAfter,
+						TimePreference:  newTask.TimePreference,
+						Project:         newTask.Project,
+						Energy:          newTask.Energy,
+						DependsOn:       newTask.DependsOn,
+					}},
+					AvailableHours: DailyCapacityOn(user, day),
 				}
-				daySlots[dateKey] = ds
+				daySlots[dateKey] = planned
 			}
+			planned.Tasks[0].HoursAllocated += placed
+			planned.TotalHours += placed
+			remaining -= placed
+			return placed
+		},
+	}
+	if newTask.Deadline != nil {
+		p.Last = deadlineDay(*newTask.Deadline, current.Location())
+		p.HasDeadline = true
+	}
 
-			ds.Tasks = append(ds.Tasks, models.ScheduledTaskInfo{
-				TaskID:         newTask.ID,
-				Title:          newTask.Title,
-				HoursAllocated: toAllocate,
-				Priority:       newTask.Priority,
-				Deadline:       newTask.Deadline,
-			})
-			ds.TotalHours += toAllocate
-			slot.AllocatedHours += toAllocate
-			remaining -= toAllocate
-		}
+	left := placeWithinTaskLimit(user, StrategyByName(user.SchedulingStrategy), p, timePreferenceOf(user, newTask.TimePreference, newTask.Project),
+		func() { limitTasks = false })
+	return convertDayMapToSlice(daySlots), left <= 1e-9
+}
 
-		current = current.AddDate(0, 0, 1)
-		daysChecked++
+// pinTaskIntoExisting books a pinned task at its exact time when that time is still free in the plan.
+func pinTaskIntoExisting(task *models.Task, planned []models.SlotAllocation, startDate time.Time) ([]models.DaySchedule, bool) {
+	iv, _ := pinnedInterval(task.PinnedStart, task.PinnedEnd, startDate.Location())
+	planStart := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+	if iv.start.Before(planStart) || pinnedConflict(iv, planned) {
+		return nil, false
 	}
 
-	if remaining > 1e-9 {
-		return convertDayMapToSlice(daySlots), false
-	}
-	return convertDayMapToSlice(daySlots), true
+	hours := iv.end.Sub(iv.start).Hours()
+	return []models.DaySchedule{{
+		Date: time.Date(iv.start.Year(), iv.start.Month(), iv.start.Day(), 0, 0, 0, 0, iv.start.Location()),
+		Tasks: []models.ScheduledTaskInfo{{
+			TaskID:         task.ID,
+			Title:          task.Title,
+			HoursAllocated: hours,
+			Priority:       task.Priority,
+			Deadline:       task.Deadline,
+			PinnedStart:    task.PinnedStart,
+			PinnedEnd:      task.PinnedEnd,
+			Project:        task.Project,
+			Energy:         task.Energy,
+			DependsOn:      task.DependsOn,
+		}},
+		TotalHours: hours,
+	}}, true
 }
 
 func indexSlotsByDate(slots []models.TimeSlot) map[string][]*models.TimeSlot {
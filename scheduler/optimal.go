@@ -0,0 +1,232 @@
+package scheduler
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// StrategyOptimal searches planning orders for the plan with the least weighted lateness.
+const StrategyOptimal = "optimal"
+
+// defaultSolverTrials bounds the optimal strategy's search when SOLVER_MAX_TRIALS is not set.
+const defaultSolverTrials = 300
+
+// optimalStrategy places each task like greedyStrategy; the search over orders and placements
+// happens in Scheduler.optimize. A single inserted task is placed greedily.
+type optimalStrategy struct{ greedyStrategy }
+
+func (optimalStrategy) Name() string { return StrategyOptimal }
+
+// solverTrials returns how many candidate plans one optimize call may evaluate. The search is
+// bounded by a count, not by time, so the same input always gives the same plan.
+func solverTrials() int {
+	if env := os.Getenv("SOLVER_MAX_TRIALS"); env != "" {
+		if v, err := strconv.Atoi(env); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return defaultSolverTrials
+}
+
+// PlanCost scores a plan, lower is better: every task hour planned after its deadline day costs
+// the task's priority per day late, an hour that could not be planned at all counts as late by the
+// whole horizon, and an hour over a day's capacity costs as much as a missed priority-1 hour.
+func PlanCost(user *models.User, tasks []models.Task, result *models.ScheduleResult) float64 {
+	costs, cost := taskCosts(user, tasks, result)
+	for i := range tasks {
+		cost += costs[tasks[i].ID]
+	}
+	return cost
+}
+
+// taskCosts splits PlanCost by task; overload is not any one task's and is returned separately.
+func taskCosts(user *models.User, tasks []models.Task, result *models.ScheduleResult) (map[int64]float64, float64) {
+	s := NewScheduler(user, tasks)
+	horizon := float64(s.planningHorizonDays)
+
+	planned := make(map[int64]float64)
+	var overload float64
+	for _, day := range result.DaySchedules {
+		for _, info := range day.Tasks {
+			planned[info.TaskID] += info.HoursAllocated
+		}
+		if over := day.TotalHours - s.capacityOn(day.Date); over > 1e-9 {
+			overload += over * horizon
+		}
+	}
+
+	costs := make(map[int64]float64)
+	for i := range s.tasks {
+		task := &s.tasks[i]
+		if task.Status == "completed" || task.Status == "cancelled" || IsPinned(task) || task.OpenSubtasks > 0 {
+			continue
+		}
+		weight := math.Max(1, float64(task.Priority))
+		if missing := RemainingHours(task) - planned[task.ID]; missing > 1e-9 {
+			costs[task.ID] += weight * missing * horizon
+		}
+		if task.Deadline == nil {
+			continue
+		}
+		for _, day := range result.DaySchedules {
+			due := deadlineDay(*task.Deadline, day.Date.Location())
+			if !day.Date.After(due) {
+				continue
+			}
+			late := math.Round(day.Date.Sub(due).Hours() / 24)
+			for _, info := range day.Tasks {
+				if info.TaskID == task.ID {
+					costs[task.ID] += weight * info.HoursAllocated * late
+				}
+			}
+		}
+	}
+	return costs, overload
+}
+
+// costlyTasks lists the tasks of order that add to the plan's cost — unplanned or late hours —
+// most expensive first, ties in planning order.
+func costlyTasks(costs map[int64]float64, order []int64) []int64 {
+	var ids []int64
+	for _, id := range order {
+		if costs[id] > 1e-9 {
+			ids = append(ids, id)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return costs[ids[i]] > costs[ids[j]] })
+	return ids
+}
+
+// optimize runs the greedy pass and then improves it by local search: for each task that adds to
+// the cost — hours unplanned or planned late, most expensive first — it tries moving the task
+// earlier in the planning order and switching tasks ahead of it between backward and forward
+// placement. Only strictly cheaper plans (PlanCost) are accepted, so the result is never worse
+// than the greedy one; after trials candidate plans the best plan so far is kept.
+func (s *Scheduler) optimize(startDate time.Time, trials int) *models.ScheduleResult {
+	run := s.solverTrial(nil, nil)
+	best := run.schedule(startDate)
+	bestCost := PlanCost(s.user, s.tasks, best)
+	order := rankedIDs(run.rank)
+	placements := map[int64]Strategy{}
+
+	try := func(o []int64, p map[int64]Strategy) bool {
+		trials--
+		trial := s.solverTrial(o, p)
+		result := trial.schedule(startDate)
+		if cost := PlanCost(s.user, s.tasks, result); cost < bestCost-1e-9 {
+			run, best, bestCost, order, placements = trial, result, cost, rankedIDs(trial.rank), p
+			return true
+		}
+		return false
+	}
+
+	for bestCost > 1e-9 {
+		improved := false
+		costs, _ := taskCosts(s.user, s.tasks, best)
+	search:
+		for _, id := range costlyTasks(costs, order) {
+			pos := indexOf(order, id)
+			if pos < 0 {
+				continue // blocked by a dependency cycle: no order helps
+			}
+			for i := 0; i < pos; i++ {
+				if trials <= 0 {
+					break search
+				}
+				if try(moveTo(order, pos, i), placements) {
+					improved = true
+					break search
+				}
+			}
+			for _, other := range order[:pos+1] {
+				if trials <= 0 {
+					break search
+				}
+				flipped, ok := s.flipPlacement(placements, other)
+				if ok && try(order, flipped) {
+					improved = true
+					break search
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	copy(s.workSlots, run.workSlots)
+	s.rank, s.effectiveDeadlines = run.rank, run.effectiveDeadlines
+	return best
+}
+
+// solverTrial returns a fresh greedy scheduler over a copy of the slot grid with a fixed planning
+// order (nil keeps deadline/priority order) and per-task placements.
+func (s *Scheduler) solverTrial(order []int64, placements map[int64]Strategy) *Scheduler {
+	t := NewSchedulerWithSlots(s.user, s.tasks, copySlots(s.workSlots))
+	t.planningHorizonDays = s.planningHorizonDays
+	t.strategy = greedyStrategy{}
+	t.taskStrategy = placements
+	if order != nil {
+		t.orderRank = make(map[int64]int, len(order))
+		for i, id := range order {
+			t.orderRank[id] = i
+		}
+	}
+	return t
+}
+
+// flipPlacement switches a task with a deadline between greedy (backward) and forward placement.
+// ok is false for tasks without a deadline, which are always placed forward.
+func (s *Scheduler) flipPlacement(placements map[int64]Strategy, taskID int64) (map[int64]Strategy, bool) {
+	task := s.taskByID(taskID)
+	if task == nil || task.Deadline == nil {
+		return nil, false
+	}
+	flipped := make(map[int64]Strategy, len(placements)+1)
+	for id, p := range placements {
+		flipped[id] = p
+	}
+	if _, forward := flipped[taskID]; forward {
+		delete(flipped, taskID)
+	} else {
+		flipped[taskID] = asapStrategy{}
+	}
+	return flipped, true
+}
+
+// rankedIDs lists task IDs in planning order.
+func rankedIDs(rank map[int64]int) []int64 {
+	ids := make([]int64, len(rank))
+	for id, i := range rank {
+		ids[i] = id
+	}
+	return ids
+}
+
+func indexOf(ids []int64, id int64) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveTo returns a copy of ids with the element at from moved to position to.
+func moveTo(ids []int64, from, to int) []int64 {
+	moved := make([]int64, 0, len(ids))
+	for i, id := range ids {
+		if i == to {
+			moved = append(moved, ids[from])
+		}
+		if i != from {
+			moved = append(moved, id)
+		}
+	}
+	return moved
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func scheduleWith(strategy string, tasks []models.Task, start time.Time) (*models.User, *models.ScheduleResult) {
+	user := &models.User{ID: 1, DailyCapacity: 4, WorkDays: []int{1, 2, 3, 4, 5}, SchedulingStrategy: strategy}
+	return user, NewScheduler(user, tasks).Schedule(start)
+}
+
+func TestOptimal_ReorderMeetsDeadlinesGreedyMisses(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	thursday := monday.AddDate(0, 0, 3)
+	friday := monday.AddDate(0, 0, 4)
+	// Greedy plans the important task first, backward into Thu–Fri, leaving no room for the
+	// task that may only start on Thursday.
+	tasks := []models.Task{
+		{ID: 1, Title: "Important", HoursRequired: 8, Priority: 9, Deadline: &friday},
+		{ID: 2, Title: "Late start", HoursRequired: 4, Priority: 5, Deadline: &friday, StartAfter: &thursday},
+	}
+
+	user, greedy := scheduleWith(StrategyGreedy, tasks, monday)
+	if greedy.Success {
+		t.Fatalf("expected greedy to miss a deadline, got %+v", greedy.DaySchedules)
+	}
+	_, optimal := scheduleWith(StrategyOptimal, tasks, monday)
+	if !optimal.Success {
+		t.Fatalf("expected optimal to plan both tasks, got unscheduled %v", optimal.UnscheduledTasks)
+	}
+	if cost := PlanCost(user, tasks, optimal); cost != 0 {
+		t.Errorf("expected zero cost, got %v", cost)
+	}
+}
+
+func TestOptimal_NeverWorseThanGreedy(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "10")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	day := func(n int) *time.Time { d := monday.AddDate(0, 0, n); return &d }
+
+	// Greedy misses a deadline in every case, so the search has something to improve.
+	cases := map[string][]models.Task{
+		"overloaded": {
+			{ID: 1, Title: "A", HoursRequired: 10, Priority: 9, Deadline: day(1)},
+			{ID: 2, Title: "B", HoursRequired: 6, Priority: 2, Deadline: day(1)},
+			{ID: 3, Title: "C", HoursRequired: 3, Priority: 5},
+		},
+		"start after": {
+			{ID: 1, Title: "A", HoursRequired: 8, Priority: 9, Deadline: day(4)},
+			{ID: 2, Title: "B", HoursRequired: 4, Priority: 5, Deadline: day(4), StartAfter: day(3)},
+			{ID: 3, Title: "C", HoursRequired: 6, Priority: 7, Deadline: day(2)},
+			{ID: 4, Title: "D", HoursRequired: 4, Priority: 1, Deadline: day(3), StartAfter: day(2)},
+		},
+		"dependencies": {
+			{ID: 1, Title: "A", HoursRequired: 8, Priority: 4, Deadline: day(3)},
+			{ID: 2, Title: "B", HoursRequired: 4, Priority: 8, Deadline: day(3), DependsOn: []int64{1}},
+			{ID: 3, Title: "C", HoursRequired: 6, Priority: 6, Deadline: day(1)},
+		},
+	}
+	for name, tasks := range cases {
+		user, greedy := scheduleWith(StrategyGreedy, tasks, monday)
+		_, optimal := scheduleWith(StrategyOptimal, tasks, monday)
+		g, o := PlanCost(user, tasks, greedy), PlanCost(user, tasks, optimal)
+		if g <= 1e-9 {
+			t.Fatalf("%s: expected greedy to be late, got cost %v", name, g)
+		}
+		if o > g+1e-9 {
+			t.Errorf("%s: optimal cost %v is worse than greedy %v", name, o, g)
+		}
+	}
+}
+
+func TestOptimal_SameInputSamePlan(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "10")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	day := func(n int) *time.Time { d := monday.AddDate(0, 0, n); return &d }
+	tasks := []models.Task{
+		{ID: 1, Title: "A", HoursRequired: 8, Priority: 9, Deadline: day(4)},
+		{ID: 2, Title: "B", HoursRequired: 4, Priority: 5, Deadline: day(4), StartAfter: day(3)},
+		{ID: 3, Title: "C", HoursRequired: 6, Priority: 7, Deadline: day(2)},
+		{ID: 4, Title: "D", HoursRequired: 4, Priority: 1, Deadline: day(3), StartAfter: day(2)},
+	}
+
+	_, first := scheduleWith(StrategyOptimal, tasks, monday)
+	for run := 0; run < 5; run++ {
+		_, again := scheduleWith(StrategyOptimal, tasks, monday)
+		if fmt.Sprint(again.DaySchedules) != fmt.Sprint(first.DaySchedules) ||
+			fmt.Sprint(again.UnscheduledTasks) != fmt.Sprint(first.UnscheduledTasks) {
+			t.Fatalf("run %d planned differently:\n%v\n%v", run, first.DaySchedules, again.DaySchedules)
+		}
+	}
+}
+
+func TestOptimal_ZeroTrialsKeepsGreedyPlan(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	t.Setenv("SOLVER_MAX_TRIALS", "0")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	thursday := monday.AddDate(0, 0, 3)
+	friday := monday.AddDate(0, 0, 4)
+	tasks := []models.Task{
+		{ID: 1, Title: "Important", HoursRequired: 8, Priority: 9, Deadline: &friday},
+		{ID: 2, Title: "Late start", HoursRequired: 4, Priority: 5, Deadline: &friday, StartAfter: &thursday},
+	}
+
+	user, greedy := scheduleWith(StrategyGreedy, tasks, monday)
+	_, optimal := scheduleWith(StrategyOptimal, tasks, monday)
+	if g, o := PlanCost(user, tasks, greedy), PlanCost(user, tasks, optimal); o != g {
+		t.Errorf("expected the greedy plan without trials, got cost %v vs greedy %v", o, g)
+	}
+}
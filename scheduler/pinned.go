@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// IsPinned reports whether the task is a fixed-time appointment.
+func IsPinned(task *models.Task) bool {
+	return task.PinnedStart != nil && task.PinnedEnd != nil && task.PinnedEnd.After(*task.PinnedStart)
+}
+
+// PinnedHours is the length of a pinned task's block.
+func PinnedHours(task *models.Task) float64 {
+	if !IsPinned(task) {
+		return 0
+	}
+	return task.PinnedEnd.Sub(*task.PinnedStart).Hours()
+}
+
+// wallClock reads t's date and clock in loc. Pinned times are stored as the user's wall clock,
+// so a value loaded from the database (UTC) and one parsed in the user's zone map to the same instant.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+// pinnedInterval returns the block of a pinned task or schedule entry in loc.
+func pinnedInterval(start, end *time.Time, loc *time.Location) (interval, bool) {
+	if start == nil || end == nil || !end.After(*start) {
+		return interval{}, false
+	}
+	return interval{start: wallClock(*start, loc), end: wallClock(*end, loc)}, true
+}
+
+// placePinnedTasks books pinned tasks at their exact times before flexible tasks are planned:
+// their hours count towards the day and their time is blocked on the slot grid.
+// Pinned tasks that start before the planning start have already taken place: they are not
+// planned and go to PastTasks instead.
+func (s *Scheduler) placePinnedTasks(result *models.ScheduleResult, startDate time.Time, daySlots map[string]*models.DaySchedule, lastDays map[int64]time.Time) {
+	loc := startDate.Location()
+	planStart := s.normalizeDate(startDate)
+
+	for i := range s.tasks {
+		task := &s.tasks[i]
+		if !IsPinned(task) || task.Status == "completed" || task.Status == "cancelled" || task.OpenSubtasks > 0 {
+			continue
+		}
+		iv, _ := pinnedInterval(task.PinnedStart, task.PinnedEnd, loc)
+		if iv.start.Before(planStart) {
+			result.PastTasks = append(result.PastTasks, task.ID)
+			continue
+		}
+
+		date := s.normalizeDate(iv.start)
+		dateKey := s.formatDate(date)
+		day, exists := daySlots[dateKey]
+		if !exists {
+			day = &models.DaySchedule{Date: date, Tasks: []models.ScheduledTaskInfo{}}
+			daySlots[dateKey] = day
+		}
+		hours := iv.end.Sub(iv.start).Hours()
+		day.Tasks = append(day.Tasks, models.ScheduledTaskInfo{
+			TaskID:         task.ID,
+			Title:          task.Title,
+			HoursAllocated: hours,
+			Priority:       task.Priority,
+			Deadline:       task.Deadline,
+			PinnedStart:    task.PinnedStart,
+			PinnedEnd:      task.PinnedEnd,
+			Project:        task.Project,
+			Energy:         task.Energy,
+			DependsOn:      task.DependsOn,
+		})
+		day.TotalHours += hours
+		day.AvailableHours = s.capacityOn(date) - day.TotalHours
+
+		if len(s.workSlots) > 0 {
+			BlockSlotsFromBusy(s.workSlots, []models.BusyInterval{{Start: iv.start, End: iv.end, Source: "pinned"}})
+		}
+		s.noteBlockEnds(task.ID, []interval{iv})
+		lastDays[task.ID] = date
+	}
+}
+
+// pinnedConflict reports whether a pinned block overlaps work already placed in the plan.
+// Calendar events are not checked: the appointment itself is often already in the calendar.
+func pinnedConflict(iv interval, allocations []models.SlotAllocation) bool {
+	for _, a := range allocations {
+		if a.Start.Before(iv.end) && iv.start.Before(a.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func pinnedAt(day time.Time, fromHour, toHour int) (*time.Time, *time.Time) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), fromHour, 0, 0, 0, day.Location())
+	end := time.Date(day.Year(), day.Month(), day.Day(), toHour, 0, 0, 0, day.Location())
+	return &start, &end
+}
+
+func morningUser() *models.User {
+	return &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "13:00"}
+}
+
+func TestSchedule_PinnedTaskBlocksItsSlot(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	callStart, callEnd := pinnedAt(monday, 10, 11)
+	tasks := []models.Task{
+		{ID: 1, Title: "Flexible", HoursRequired: 3, Priority: 5},
+		{ID: 2, Title: "Call", HoursRequired: 1, Priority: 1, PinnedStart: callStart, PinnedEnd: callEnd},
+	}
+
+	result := NewSchedulerWithSlots(user, tasks, BuildWorkSlots(user, monday, nil)).Schedule(monday)
+	if !result.Success || len(result.DaySchedules) != 1 || result.DaySchedules[0].TotalHours != 4 {
+		t.Fatalf("expected both tasks on Monday, got %+v", result)
+	}
+
+	allocations := PlanTimeAllocations(user, result.DaySchedules, monday, nil)
+	var call *models.SlotAllocation
+	for i := range allocations {
+		a := &allocations[i]
+		if a.TaskID == 2 {
+			call = a
+			continue
+		}
+		if a.Start.Before(*callEnd) && callStart.Before(a.End) {
+			t.Errorf("flexible block %s-%s overlaps the call", a.Start.Format("15:04"), a.End.Format("15:04"))
+		}
+	}
+	if call == nil || !call.Pinned || !call.Start.Equal(*callStart) || !call.End.Equal(*callEnd) {
+		t.Errorf("expected pinned call at 10:00-11:00, got %+v", call)
+	}
+}
+
+func TestSchedule_PinnedStoredWallClockUsesUserZone(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	moscow := time.FixedZone("MSK", 3*60*60)
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, moscow)
+	// Loaded from a TIMESTAMP column: the wall clock comes back labelled UTC.
+	callStart, callEnd := pinnedAt(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), 9, 10)
+	tasks := []models.Task{{ID: 1, Title: "Call", HoursRequired: 1, PinnedStart: callStart, PinnedEnd: callEnd}}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	allocations := PlanTimeAllocations(user, result.DaySchedules, monday, nil)
+	if len(allocations) != 1 || allocations[0].Start.Hour() != 9 || allocations[0].Start.Location() != moscow {
+		t.Fatalf("expected the call at 09:00 MSK, got %+v", allocations)
+	}
+}
+
+func TestSchedule_PinnedInThePastIsSkipped(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	callStart, callEnd := pinnedAt(monday.AddDate(0, 0, -3), 10, 11)
+	tasks := []models.Task{
+		{ID: 1, Title: "Old call", HoursRequired: 1, PinnedStart: callStart, PinnedEnd: callEnd},
+		{ID: 2, Title: "Follow-up", HoursRequired: 2, Priority: 5, DependsOn: []int64{1}},
+	}
+
+	result := NewScheduler(morningUser(), tasks).Schedule(monday)
+	if len(result.UnscheduledTasks) != 0 {
+		t.Fatalf("a call that already took place must not be reported, got %v", result.UnscheduledTasks)
+	}
+	if len(result.PastTasks) != 1 || result.PastTasks[0] != 1 {
+		t.Errorf("expected the old call among the past tasks, got %v", result.PastTasks)
+	}
+	if day, ok := firstDayOf(1, result.DaySchedules); ok {
+		t.Errorf("the past call must not be planned again, got it on %s", day.Format("2006-01-02"))
+	}
+	if day, ok := firstDayOf(2, result.DaySchedules); !ok || !day.Equal(monday) {
+		t.Errorf("the follow-up should not wait for a past call, got %v %v", day, ok)
+	}
+}
+
+func TestSchedule_SuccessorWaitsForPinnedDay(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "10")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	callStart, callEnd := pinnedAt(monday.AddDate(0, 0, 2), 10, 11)
+	tasks := []models.Task{
+		{ID: 1, Title: "Kickoff", HoursRequired: 1, PinnedStart: callStart, PinnedEnd: callEnd},
+		{ID: 2, Title: "Follow-up", HoursRequired: 2, Priority: 9, DependsOn: []int64{1}},
+	}
+
+	result := NewScheduler(morningUser(), tasks).Schedule(monday)
+	for _, day := range result.DaySchedules {
+		for _, info := range day.Tasks {
+			if info.TaskID == 2 && day.Date.Before(monday.AddDate(0, 0, 2)) {
+				t.Errorf("follow-up planned on %s, before the kickoff", day.Date.Format("2006-01-02"))
+			}
+		}
+	}
+}
+
+func TestScheduleTaskIntoExisting_Pinned(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	callStart, callEnd := pinnedAt(monday, 11, 12)
+	call := &models.Task{ID: 9, Title: "Call", HoursRequired: 1, PinnedStart: callStart, PinnedEnd: callEnd}
+
+	light := []models.DaySchedule{{Date: monday, Tasks: []models.ScheduledTaskInfo{{TaskID: 1, Title: "A", HoursAllocated: 2}}, TotalHours: 2}}
+	days, ok := ScheduleTaskIntoExisting(user, call, light, monday, nil)
+	if !ok || len(days) != 1 || days[0].Tasks[0].PinnedStart == nil {
+		t.Fatalf("expected the call to be pinned into free time, got %+v (ok=%v)", days, ok)
+	}
+
+	full := []models.DaySchedule{{Date: monday, Tasks: []models.ScheduledTaskInfo{{TaskID: 1, Title: "A", HoursAllocated: 4}}, TotalHours: 4}}
+	if _, ok := ScheduleTaskIntoExisting(user, call, full, monday, nil); ok {
+		t.Error("expected a conflict with planned work")
+	}
+
+	// A flexible task inserted later goes around the pinned call.
+	withCall := append(light, days...)
+	flexible := &models.Task{ID: 10, Title: "B", HoursRequired: 1}
+	days, ok = ScheduleTaskIntoExisting(user, flexible, withCall, monday, nil)
+	if !ok {
+		t.Fatal("expected the flexible task to fit")
+	}
+	for _, a := range PlanTimeAllocations(user, append(withCall, days...), monday, nil) {
+		if a.TaskID == 10 && a.Start.Before(*callEnd) && callStart.Before(a.End) {
+			t.Errorf("flexible task placed over the call: %s-%s", a.Start.Format("15:04"), a.End.Format("15:04"))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// DiffBlocks compares two timed plans day by day. A task whose blocks on a day only exist in after
+// is added, only in before is removed, and one whose blocks exist in both but differ is moved.
+// Both plans are expected in time order, as PlanTimeAllocations returns them. Days without
+// changes are omitted; days and tasks come in time order.
+func DiffBlocks(before, after []models.SlotAllocation) []models.DayDiff {
+	type key struct {
+		day    string
+		taskID int64
+	}
+	old := make(map[key][]models.SlotAllocation)
+	cur := make(map[key][]models.SlotAllocation)
+	var keys []key
+	seen := make(map[key]bool)
+	collect := func(allocations []models.SlotAllocation, into map[key][]models.SlotAllocation) {
+		for _, a := range allocations {
+			k := key{a.Start.Format("2006-01-02"), a.TaskID}
+			into[k] = append(into[k], a)
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	collect(before, old)
+	collect(after, cur)
+
+	byDay := make(map[string]*models.DayDiff)
+	var days []string
+	for _, k := range keys {
+		was, now := old[k], cur[k]
+		var kind string
+		switch {
+		case len(was) == 0:
+			kind = models.BlockAdded
+		case len(now) == 0:
+			kind = models.BlockRemoved
+		case !sameBlocks(was, now):
+			kind = models.BlockMoved
+		default:
+			continue
+		}
+		title := ""
+		if len(now) > 0 {
+			title = now[0].Title
+		} else {
+			title = was[0].Title
+		}
+
+		diff := byDay[k.day]
+		if diff == nil {
+			date, _ := time.Parse("2006-01-02", k.day)
+			diff = &models.DayDiff{Date: date}
+			byDay[k.day] = diff
+			days = append(days, k.day)
+		}
+		diff.Changes = append(diff.Changes, models.BlockChange{TaskID: k.taskID, Title: title, Kind: kind, Before: was, After: now})
+	}
+
+	sort.Strings(days)
+	result := make([]models.DayDiff, 0, len(days))
+	for _, day := range days {
+		diff := byDay[day]
+		sort.SliceStable(diff.Changes, func(i, j int) bool {
+			return firstBlockStart(diff.Changes[i]).Before(firstBlockStart(diff.Changes[j]))
+		})
+		result = append(result, *diff)
+	}
+	return result
+}
+
+// NewlyLate returns tasks with a deadline that the new plan could not fit although the saved
+// plan covered all their remaining hours.
+func NewlyLate(tasks []models.Task, baseline []models.DaySchedule, result *models.ScheduleResult) []int64 {
+	planned := make(map[int64]float64)
+	for _, day := range baseline {
+		for _, info := range day.Tasks {
+			planned[info.TaskID] += info.HoursAllocated
+		}
+	}
+	missing := make(map[int64]bool, len(result.UnscheduledTasks))
+	for _, id := range result.UnscheduledTasks {
+		missing[id] = true
+	}
+
+	var late []int64
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Deadline == nil || !missing[task.ID] {
+			continue
+		}
+		if planned[task.ID] >= RemainingHours(task)-1e-6 {
+			late = append(late, task.ID)
+		}
+	}
+	return late
+}
+
+func sameBlocks(a, b []models.SlotAllocation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Start.Equal(b[i].Start) || !a[i].End.Equal(b[i].End) {
+			return false
+		}
+	}
+	return true
+}
+
+func firstBlockStart(c models.BlockChange) time.Time {
+	if len(c.After) > 0 {
+		return c.After[0].Start
+	}
+	return c.Before[0].Start
+}
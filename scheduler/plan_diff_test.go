@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func block(taskID int64, start time.Time, hours float64) models.SlotAllocation {
+	return models.SlotAllocation{TaskID: taskID, Title: "T", Start: start, End: start.Add(time.Duration(hours * float64(time.Hour)))}
+}
+
+func TestDiffBlocks(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	before := []models.SlotAllocation{
+		block(1, monday.Add(9*time.Hour), 2),
+		block(2, monday.Add(11*time.Hour), 1),
+		block(3, tuesday.Add(9*time.Hour), 1),
+	}
+	after := []models.SlotAllocation{
+		block(1, monday.Add(9*time.Hour), 2),
+		block(2, monday.Add(12*time.Hour), 1),
+		block(3, tuesday.Add(9*time.Hour), 1),
+		block(4, tuesday.Add(10*time.Hour), 2),
+	}
+	before = append(before, block(5, tuesday.Add(12*time.Hour), 1))
+
+	days := DiffBlocks(before, after)
+	if len(days) != 2 {
+		t.Fatalf("expected changes on two days, got %+v", days)
+	}
+	if !days[0].Date.Equal(monday) || len(days[0].Changes) != 1 || days[0].Changes[0].TaskID != 2 || days[0].Changes[0].Kind != models.BlockMoved {
+		t.Errorf("expected task 2 moved on Monday, got %+v", days[0])
+	}
+	if len(days[1].Changes) != 2 || days[1].Changes[0].Kind != models.BlockAdded || days[1].Changes[1].Kind != models.BlockRemoved {
+		t.Errorf("expected task 4 added and task 5 removed on Tuesday, got %+v", days[1].Changes)
+	}
+	if len(DiffBlocks(after, after)) != 0 {
+		t.Error("identical plans must have no changes")
+	}
+}
+
+func TestNewlyLate(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: 1, HoursRequired: 4, Deadline: &monday},
+		{ID: 2, HoursRequired: 4, Deadline: &monday},
+		{ID: 3, HoursRequired: 4},
+	}
+	baseline := []models.DaySchedule{baselineDay(monday,
+		models.ScheduledTaskInfo{TaskID: 1, HoursAllocated: 4},
+		models.ScheduledTaskInfo{TaskID: 2, HoursAllocated: 2},
+		models.ScheduledTaskInfo{TaskID: 3, HoursAllocated: 4},
+	)}
+	result := &models.ScheduleResult{UnscheduledTasks: []int64{1, 2, 3}}
+
+	late := NewlyLate(tasks, baseline, result)
+	if len(late) != 1 || late[0] != 1 {
+		t.Errorf("only task 1 was on time before, got %v", late)
+	}
+}
@@ -0,0 +1,196 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// weekdayNames are the day names FormatTimePreference writes, by ISO weekday.
+var weekdayNames = [8]string{1: "mon", 2: "tue", 3: "wed", 4: "thu", 5: "fri", 6: "sat", 7: "sun"}
+
+var preferenceWeekdays = map[string]int{
+	"mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6, "sun": 7,
+	"пн": 1, "вт": 2, "ср": 3, "чт": 4, "пт": 5, "сб": 6, "вс": 7,
+}
+
+// Named parts of the day, as [from, until) minutes.
+var dayParts = map[string][2]int{
+	"morning": {0, 12 * 60}, "утро": {0, 12 * 60}, "утром": {0, 12 * 60},
+	"afternoon": {12 * 60, 17 * 60}, "день": {12 * 60, 17 * 60}, "днём": {12 * 60, 17 * 60}, "днем": {12 * 60, 17 * 60},
+	"evening": {17 * 60, 0}, "вечер": {17 * 60, 0}, "вечером": {17 * 60, 0},
+}
+
+// ParseTimePreference parses a preference such as "morning", "after 14:00", "10:00-12:00" or
+// "not mon,fri" (Russian words and day names work too: "утром не пн"). Terms combine; the last
+// time of day given wins.
+func ParseTimePreference(spec string) (models.TimePreference, error) {
+	var pref models.TimePreference
+	tokens := strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool { return r == ' ' || r == ',' || r == ';' })
+	if len(tokens) == 0 {
+		return pref, fmt.Errorf("empty preference")
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if part, ok := dayParts[token]; ok {
+			pref.From, pref.Until = part[0], part[1]
+			continue
+		}
+		switch token {
+		case "after", "после", "before", "до":
+			if i+1 == len(tokens) {
+				return pref, fmt.Errorf("%q needs a time", token)
+			}
+			i++
+			minute, err := parseClockMinutes(tokens[i])
+			if err != nil {
+				return pref, err
+			}
+			if token == "after" || token == "после" {
+				pref.From = minute
+			} else {
+				pref.Until = minute
+			}
+		case "not", "не":
+			start := i
+			for i+1 < len(tokens) {
+				wd, ok := preferenceWeekdays[tokens[i+1]]
+				if !ok {
+					break
+				}
+				if !containsWeekday(pref.AvoidWeekdays, wd) {
+					pref.AvoidWeekdays = append(pref.AvoidWeekdays, wd)
+				}
+				i++
+			}
+			if i == start {
+				return pref, fmt.Errorf("%q needs weekdays", token)
+			}
+		default:
+			from, until, ok := strings.Cut(token, "-")
+			if !ok {
+				return pref, fmt.Errorf("unknown preference %q", token)
+			}
+			f, err := parseClockMinutes(from)
+			if err != nil {
+				return pref, err
+			}
+			u, err := parseClockMinutes(until)
+			if err != nil {
+				return pref, err
+			}
+			pref.From, pref.Until = f, u
+		}
+	}
+
+	if pref.Until > 0 && pref.From >= pref.Until {
+		return pref, fmt.Errorf("empty time window")
+	}
+	if len(pref.AvoidWeekdays) == 7 {
+		return pref, fmt.Errorf("every day is avoided")
+	}
+	sort.Ints(pref.AvoidWeekdays)
+	return pref, nil
+}
+
+// FormatTimePreference serializes a preference back to the form accepted by ParseTimePreference.
+func FormatTimePreference(pref models.TimePreference) string {
+	var parts []string
+	switch {
+	case pref.From > 0 && pref.Until > 0:
+		parts = append(parts, formatClockMinutes(pref.From)+"-"+formatClockMinutes(pref.Until))
+	case pref.From > 0:
+		parts = append(parts, "after "+formatClockMinutes(pref.From))
+	case pref.Until > 0:
+		parts = append(parts, "before "+formatClockMinutes(pref.Until))
+	}
+	if len(pref.AvoidWeekdays) > 0 {
+		names := make([]string, 0, len(pref.AvoidWeekdays))
+		for _, wd := range pref.AvoidWeekdays {
+			names = append(names, weekdayNames[wd])
+		}
+		parts = append(parts, "not "+strings.Join(names, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// TimePreferenceSpec returns the stored preference a task is planned with: its own, else its
+// project's (/prefer), else "".
+func TimePreferenceSpec(user *models.User, taskSpec, project string) string {
+	if taskSpec != "" || project == "" {
+		return taskSpec
+	}
+	for _, p := range user.ProjectTimePrefs {
+		if p.Project == project {
+			return p.TimePreference
+		}
+	}
+	return ""
+}
+
+// timePreferenceOf parses the preference a task is planned with (see TimePreferenceSpec); one that
+// no longer parses means no preference.
+func timePreferenceOf(user *models.User, taskSpec, project string) models.TimePreference {
+	spec := TimePreferenceSpec(user, taskSpec, project)
+	if spec == "" {
+		return models.TimePreference{}
+	}
+	pref, err := ParseTimePreference(spec)
+	if err != nil {
+		return models.TimePreference{}
+	}
+	return pref
+}
+
+func parseClockMinutes(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClockMinutes(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// avoidsDay reports whether the preference keeps the task off date.
+func avoidsDay(pref models.TimePreference, date time.Time) bool {
+	return containsWeekday(pref.AvoidWeekdays, isoWeekday(date))
+}
+
+// preferredSlots keeps the slots of one day that lie wholly inside the preferred time of day,
+// or none when the day is avoided.
+func preferredSlots(daySlots []*models.TimeSlot, pref models.TimePreference) []*models.TimeSlot {
+	if len(daySlots) == 0 || avoidsDay(pref, daySlots[0].Date) {
+		return nil
+	}
+	var kept []*models.TimeSlot
+	for _, slot := range daySlots {
+		midnight := time.Date(slot.Start.Year(), slot.Start.Month(), slot.Start.Day(), 0, 0, 0, 0, slot.Start.Location())
+		start, end := int(slot.Start.Sub(midnight).Minutes()), int(slot.End.Sub(midnight).Minutes())
+		if start >= pref.From && (pref.Until == 0 || end <= pref.Until) {
+			kept = append(kept, slot)
+		}
+	}
+	return kept
+}
+
+// placePreferringDays places a task with strategy on the days its preference does not avoid, and
+// only what is left there on any work day.
+func placePreferringDays(strategy Strategy, p Placement, pref models.TimePreference) float64 {
+	if len(pref.AvoidWeekdays) == 0 {
+		return strategy.Place(p)
+	}
+	preferred := p
+	preferred.IsWorkDay = func(day time.Time) bool { return p.IsWorkDay(day) && !avoidsDay(pref, day) }
+	p.Remaining = strategy.Place(preferred)
+	if p.Remaining <= 1e-9 {
+		return p.Remaining
+	}
+	return strategy.Place(p)
+}
@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestParseTimePreference(t *testing.T) {
+	cases := map[string]string{
+		"morning":             "before 12:00",
+		"утром не пн":         "before 12:00 not mon",
+		"after 14:00":         "after 14:00",
+		"вечером":             "after 17:00",
+		"10:00-12:00 not fri": "10:00-12:00 not fri",
+		"не пн, ср":           "not mon,wed",
+	}
+	for spec, want := range cases {
+		pref, err := ParseTimePreference(spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", spec, err)
+			continue
+		}
+		if got := FormatTimePreference(pref); got != want {
+			t.Errorf("%q: got %q, want %q", spec, got, want)
+		}
+	}
+
+	for _, bad := range []string{"", "soon", "after", "after 25:00", "12:00-10:00", "not", "not mon tue wed thu fri sat sun"} {
+		if _, err := ParseTimePreference(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestPlanTimeAllocations_PreferredTimeFirst(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00"}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	days := []models.DaySchedule{{
+		Date: monday,
+		Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, Title: "Mail", HoursAllocated: 2},
+			{TaskID: 2, Title: "Writing", HoursAllocated: 2, TimePreference: "after 14:00"},
+			{TaskID: 3, Title: "Standup notes", HoursAllocated: 2, TimePreference: "before 10:00"},
+		},
+		TotalHours: 6,
+	}}
+
+	allocations := PlanTimeAllocations(user, days, monday, nil)
+	byTask := make(map[int64][]models.SlotAllocation)
+	for _, a := range allocations {
+		byTask[a.TaskID] = append(byTask[a.TaskID], a)
+	}
+
+	if w := byTask[2]; len(w) != 1 || w[0].Start.Hour() != 14 || w[0].End.Hour() != 16 || w[0].OffPreference {
+		t.Errorf("expected Writing at 14:00–16:00 in its preferred time, got %+v", w)
+	}
+	if m := byTask[1]; len(m) == 0 || m[0].OffPreference {
+		t.Errorf("expected Mail without a preference flag, got %+v", m)
+	}
+	var preferred, off float64
+	for _, a := range byTask[3] {
+		if a.OffPreference {
+			off += a.End.Sub(a.Start).Hours()
+		} else {
+			preferred += a.End.Sub(a.Start).Hours()
+		}
+	}
+	if preferred != 1 || off != 1 {
+		t.Errorf("expected 1h of Standup notes before 10:00 and 1h flagged elsewhere, got %v/%v", preferred, off)
+	}
+}
+
+func TestSchedule_AvoidsPreferredOffDays(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{{ID: 1, Title: "Deep work", HoursRequired: 4, Priority: 5, TimePreference: "not mon"}}
+
+	result := NewScheduler(morningUser(), tasks).Schedule(monday)
+	if first, ok := firstDayOf(1, result.DaySchedules); !result.Success || !ok || first.Weekday() != time.Tuesday {
+		t.Fatalf("expected the task moved off Monday, got %+v", result.DaySchedules)
+	}
+
+	// A deadline on Monday leaves no other day: the preference gives way.
+	tasks[0].Deadline = &monday
+	result = NewScheduler(morningUser(), tasks).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected the task planned on Monday anyway, got %+v", result)
+	}
+}
+
+func TestTimePreferenceSpec_ProjectInheritanceAndOverride(t *testing.T) {
+	user := &models.User{ProjectTimePrefs: []models.ProjectTimePref{{Project: "writing", TimePreference: "after 14:00"}}}
+
+	if got := TimePreferenceSpec(user, "", "writing"); got != "after 14:00" {
+		t.Errorf("a task without its own preference should take its project's, got %q", got)
+	}
+	if got := TimePreferenceSpec(user, "before 10:00", "writing"); got != "before 10:00" {
+		t.Errorf("a task's own preference should win, got %q", got)
+	}
+	if got := TimePreferenceSpec(user, "", "other"); got != "" {
+		t.Errorf("a project without a preference gives none, got %q", got)
+	}
+}
+
+func TestPlanTimeAllocations_ProjectPreference(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}, WorkStart: "09:00", WorkEnd: "17:00",
+		ProjectTimePrefs: []models.ProjectTimePref{{Project: "writing", TimePreference: "after 14:00"}}}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	days := []models.DaySchedule{{
+		Date: monday,
+		Tasks: []models.ScheduledTaskInfo{
+			{TaskID: 1, Title: "Mail", HoursAllocated: 2},
+			{TaskID: 2, Title: "Chapter", HoursAllocated: 2, Project: "writing"},
+			{TaskID: 3, Title: "Outline", HoursAllocated: 1, Project: "writing", TimePreference: "before 10:00"},
+		},
+		TotalHours: 5,
+	}}
+
+	byTask := make(map[int64][]models.SlotAllocation)
+	for _, a := range PlanTimeAllocations(user, days, monday, nil) {
+		byTask[a.TaskID] = append(byTask[a.TaskID], a)
+	}
+	if c := byTask[2]; len(c) != 1 || c[0].Start.Hour() < 14 || c[0].OffPreference {
+		t.Errorf("expected Chapter after 14:00 from its project, got %+v", c)
+	}
+	if o := byTask[3]; len(o) != 1 || o[0].Start.Hour() != 9 || o[0].OffPreference {
+		t.Errorf("expected Outline at 09:00 by its own preference, got %+v", o)
+	}
+}
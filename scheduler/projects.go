@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// ProjectBudget returns the weekly hour budget of a project, or 0 when it has none.
+func ProjectBudget(user *models.User, project string) float64 {
+	if project == "" {
+		return 0
+	}
+	for _, b := range user.ProjectBudgets {
+		if b.Project == project {
+			return b.WeeklyHours
+		}
+	}
+	return 0
+}
+
+// WeekStart returns the Monday of date's week, at midnight.
+func WeekStart(date time.Time) time.Time {
+	monday := date.AddDate(0, 0, 1-isoWeekday(date))
+	return time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, date.Location())
+}
+
+// ProjectHours sums the planned hours of each project across days; tasks without a project are left out.
+func ProjectHours(days []models.DaySchedule) map[string]float64 {
+	hours := make(map[string]float64)
+	for _, day := range days {
+		for _, info := range day.Tasks {
+			if info.Project != "" {
+				hours[info.Project] += info.HoursAllocated
+			}
+		}
+	}
+	return hours
+}
+
+// projectHoursInWeek sums the hours of a project's tasks planned in date's week, except those of
+// the task skip (0 = none).
+func projectHoursInWeek(project string, date time.Time, days map[string]*models.DaySchedule, skip int64) float64 {
+	var total float64
+	monday := WeekStart(date)
+	for i := 0; i < 7; i++ {
+		day, ok := days[monday.AddDate(0, 0, i).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		for _, info := range day.Tasks {
+			if info.Project == project && info.TaskID != skip {
+				total += info.HoursAllocated
+			}
+		}
+	}
+	return total
+}
+
+// budgetLeft returns how many more hours the task's project may get in date's week, counting the
+// plans in days, or -1 when the project has no budget.
+func budgetLeft(user *models.User, task *models.Task, date time.Time, days ...map[string]*models.DaySchedule) float64 {
+	budget := ProjectBudget(user, task.Project)
+	if budget <= 0 {
+		return -1
+	}
+	for _, d := range days {
+		budget -= projectHoursInWeek(task.Project, date, d, 0)
+	}
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
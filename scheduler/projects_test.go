@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestScheduler_ProjectBudgetCapsWeek(t *testing.T) {
+	thursday := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+	user := &models.User{
+		ID:             1,
+		DailyCapacity:  8,
+		WorkDays:       []int{1, 2, 3, 4, 5},
+		ProjectBudgets: []models.ProjectBudget{{Project: "clienta", WeeklyHours: 6}},
+	}
+	tasks := []models.Task{
+		{ID: 1, Title: "Client A site", HoursRequired: 10, Project: "clienta"},
+		{ID: 2, Title: "Internal", HoursRequired: 4},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(thursday)
+	if !result.Success {
+		t.Fatalf("expected all tasks planned, got %+v", result)
+	}
+	weeks := make(map[string]float64)
+	for _, day := range result.DaySchedules {
+		for _, info := range day.Tasks {
+			if info.Project == "clienta" {
+				weeks[WeekStart(day.Date).Format("2006-01-02")] += info.HoursAllocated
+			}
+		}
+	}
+	if weeks["2025-01-06"] != 6 || weeks["2025-01-13"] != 4 {
+		t.Errorf("expected 6 h in the first week and the other 4 h in the next, got %v", weeks)
+	}
+	if hours := ProjectHours(result.DaySchedules); hours["clienta"] != 10 || len(hours) != 1 {
+		t.Errorf("expected only clienta with 10 h, got %v", hours)
+	}
+}
+
+func TestScheduleTaskIntoExisting_ProjectBudget(t *testing.T) {
+	thursday := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+	user := &models.User{
+		ID:             1,
+		DailyCapacity:  8,
+		WorkDays:       []int{1, 2, 3, 4, 5},
+		WorkStart:      "09:00",
+		WorkEnd:        "17:00",
+		ProjectBudgets: []models.ProjectBudget{{Project: "clienta", WeeklyHours: 6}},
+	}
+	existing := []models.DaySchedule{{
+		Date:       thursday,
+		Tasks:      []models.ScheduledTaskInfo{{TaskID: 1, Title: "Client A site", HoursAllocated: 5, Project: "clienta"}},
+		TotalHours: 5,
+	}}
+	task := &models.Task{ID: 2, Title: "Client A review", HoursRequired: 3, Project: "clienta"}
+
+	days, ok := ScheduleTaskIntoExisting(user, task, existing, thursday, nil)
+	if !ok {
+		t.Fatal("expected the task to fit")
+	}
+	var thisWeek float64
+	for _, day := range days {
+		if day.Date.Before(time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)) {
+			thisWeek += day.TotalHours
+		}
+	}
+	if thisWeek != 1 {
+		t.Errorf("expected 1 h left in the budget this week, got %v in %+v", thisWeek, days)
+	}
+}
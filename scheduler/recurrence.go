@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+var rruleWeekdays = map[string]int{"MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6, "SU": 7}
+
+// Occurrences returns the rule's occurrence dates within [from, to] (inclusive, date-level, UTC midnight).
+// COUNT is counted from the rule start, so occurrences before from still use up the count.
+func Occurrences(rule models.RecurrenceRule, from, to time.Time) []time.Time {
+	start := utcDate(rule.Start)
+	from, to = utcDate(from), utcDate(to)
+	if rule.Until != nil && utcDate(*rule.Until).Before(to) {
+		to = utcDate(*rule.Until)
+	}
+
+	var out []time.Time
+	seen := 0
+	for d := start; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if !matchesRule(rule, start, d) {
+			continue
+		}
+		seen++
+		if rule.Count > 0 && seen > rule.Count {
+			break
+		}
+		if !d.Before(from) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func matchesRule(rule models.RecurrenceRule, start, d time.Time) bool {
+	interval := rule.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch rule.Freq {
+	case "daily":
+		if len(rule.Weekdays) > 0 && !containsWeekday(rule.Weekdays, isoWeekday(d)) {
+			return false
+		}
+		return daysBetween(start, d)%interval == 0
+	case "weekly":
+		weekdays := rule.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = []int{isoWeekday(start)}
+		}
+		if !containsWeekday(weekdays, isoWeekday(d)) {
+			return false
+		}
+		weekStart := start.AddDate(0, 0, 1-isoWeekday(start))
+		return (daysBetween(weekStart, d)/7)%interval == 0
+	case "monthly":
+		monthDay := rule.MonthDay
+		if monthDay == 0 {
+			monthDay = start.Day()
+		}
+		// Short months use their last day (e.g. day 31 falls on 30.04)
+		lastDay := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if monthDay > lastDay {
+			monthDay = lastDay
+		}
+		if d.Day() != monthDay {
+			return false
+		}
+		months := (d.Year()-start.Year())*12 + int(d.Month()) - int(start.Month())
+		return months%interval == 0
+	}
+	return false
+}
+
+// ParseRRULE parses "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;UNTIL=20261231;COUNT=10;DTSTART=20261001".
+// A missing DTSTART is left zero for the caller to fill.
+func ParseRRULE(s string) (models.RecurrenceRule, error) {
+	rule := models.RecurrenceRule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"), ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(value))
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY":
+				rule.Freq = strings.ToLower(value)
+			default:
+				return rule, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			rule.Weekdays = nil
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[day]
+				if !ok {
+					return rule, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				if !containsWeekday(rule.Weekdays, wd) {
+					rule.Weekdays = append(rule.Weekdays, wd)
+				}
+			}
+			sort.Ints(rule.Weekdays)
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 31 {
+				return rule, fmt.Errorf("invalid BYMONTHDAY %q", value)
+			}
+			rule.MonthDay = n
+		case "UNTIL":
+			until, err := parseRRULEDate(value)
+			if err != nil {
+				return rule, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			rule.Until = &until
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("invalid COUNT %q", value)
+			}
+			rule.Count = n
+		case "DTSTART":
+			start, err := parseRRULEDate(value)
+			if err != nil {
+				return rule, fmt.Errorf("invalid DTSTART %q", value)
+			}
+			rule.Start = start
+		default:
+			return rule, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("FREQ is required")
+	}
+	return rule, nil
+}
+
+// FormatRRULE serializes a rule back to the RRULE form accepted by ParseRRULE.
+func FormatRRULE(rule models.RecurrenceRule) string {
+	parts := []string{"FREQ=" + strings.ToUpper(rule.Freq)}
+	if rule.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", rule.Interval))
+	}
+	if len(rule.Weekdays) > 0 {
+		names := make([]string, 0, len(rule.Weekdays))
+		for _, wd := range rule.Weekdays {
+			for name, v := range rruleWeekdays {
+				if v == wd {
+					names = append(names, name)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+	if rule.MonthDay > 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", rule.MonthDay))
+	}
+	if rule.Until != nil {
+		parts = append(parts, "UNTIL="+rule.Until.Format("20060102"))
+	}
+	if rule.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", rule.Count))
+	}
+	if !rule.Start.IsZero() {
+		parts = append(parts, "DTSTART="+rule.Start.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func parseRRULEDate(value string) (time.Time, error) {
+	if len(value) > 8 {
+		value = value[:8] // drop a time part such as T235959Z
+	}
+	return time.Parse("20060102", value)
+}
+
+func utcDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours()+12) / 24
+}
+
+func containsWeekday(days []int, wd int) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
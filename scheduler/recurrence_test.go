@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func dates(ts []time.Time) []string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format("2006-01-02")
+	}
+	return out
+}
+
+func TestOccurrences(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC) // Thursday
+	until := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rule models.RecurrenceRule
+		from time.Time
+		to   time.Time
+		want []string
+	}{
+		{
+			name: "weekly on Friday",
+			rule: models.RecurrenceRule{Freq: "weekly", Weekdays: []int{5}, Start: start},
+			from: start, to: start.AddDate(0, 0, 20),
+			want: []string{"2026-10-02", "2026-10-09", "2026-10-16"},
+		},
+		{
+			name: "every 2 weeks defaults to start weekday",
+			rule: models.RecurrenceRule{Freq: "weekly", Interval: 2, Start: start},
+			from: start, to: start.AddDate(0, 0, 30),
+			want: []string{"2026-10-01", "2026-10-15", "2026-10-29"},
+		},
+		{
+			name: "daily on weekdays with until",
+			rule: models.RecurrenceRule{Freq: "daily", Weekdays: []int{1, 2, 3, 4, 5}, Start: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), Until: &until},
+			from: start, to: start.AddDate(0, 1, 0),
+			want: []string{"2026-10-15", "2026-10-16", "2026-10-19", "2026-10-20"},
+		},
+		{
+			name: "count is consumed before from",
+			rule: models.RecurrenceRule{Freq: "daily", Count: 5, Start: start},
+			from: time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC), to: start.AddDate(0, 1, 0),
+			want: []string{"2026-10-04", "2026-10-05"},
+		},
+		{
+			name: "monthly on the 31st clamps to short months",
+			rule: models.RecurrenceRule{Freq: "monthly", MonthDay: 31, Start: start},
+			from: start, to: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+			want: []string{"2026-10-31", "2026-11-30", "2026-12-31"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := dates(Occurrences(tc.rule, tc.from, tc.to))
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("got %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseRRULE_RoundTrip(t *testing.T) {
+	in := "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;UNTIL=20261231;DTSTART=20261001"
+	rule, err := ParseRRULE(in)
+	if err != nil {
+		t.Fatalf("ParseRRULE: %v", err)
+	}
+	if rule.Freq != "weekly" || rule.Interval != 2 || len(rule.Weekdays) != 2 || rule.Until == nil {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+	if out := FormatRRULE(rule); out != in {
+		t.Errorf("FormatRRULE = %q, want %q", out, in)
+	}
+
+	for _, bad := range []string{"", "FREQ=YEARLY", "FREQ=DAILY;BYDAY=XX", "FREQ=DAILY;COUNT=0", "BYDAY=MO"} {
+		if _, err := ParseRRULE(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScheduler_RecurringInstancesPlannedByDeadline(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 8, WorkDays: []int{1, 2, 3, 4, 5}}
+	rule := models.RecurrenceRule{Freq: "weekly", Weekdays: []int{5}, Start: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)}
+
+	var tasks []models.Task
+	recurringID := int64(7)
+	for i, d := range Occurrences(rule, rule.Start, rule.Start.AddDate(0, 0, 13)) {
+		deadline := d
+		tasks = append(tasks, models.Task{ID: int64(i + 1), Title: "Weekly report", HoursRequired: 2, Deadline: &deadline, RecurringID: &recurringID, Occurrence: &deadline})
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(tasks))
+	}
+
+	result := NewScheduler(user, tasks).Schedule(rule.Start)
+	if !result.Success {
+		t.Fatalf("expected success, got %s", result.Message)
+	}
+	for _, ds := range result.DaySchedules {
+		for _, info := range ds.Tasks {
+			deadline := tasks[info.TaskID-1].Deadline
+			if ds.Date.After(*deadline) {
+				t.Errorf("instance %d planned on %v after its deadline %v", info.TaskID, ds.Date, deadline)
+			}
+		}
+	}
+}
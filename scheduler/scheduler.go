@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"math"
 	"os"
 	"sort"
 	"strconv"
@@ -14,7 +15,18 @@ type Scheduler struct {
 	user                *models.User
 	tasks               []models.Task
 	planningHorizonDays int
-	workSlots           []models.TimeSlot // optional grid with calendar busy blocks
+	workSlots           []models.TimeSlot            // optional grid with calendar busy blocks
+	busyHours           []float64                    // calendar time blocked in each work slot before planning
+	effectiveDeadlines  map[int64]time.Time          // deadlines implied by dependent tasks
+	baseline            map[int64]map[string]float64 // stable mode: task -> day -> hours to keep
+	keptDays            map[int64][]time.Time        // stable mode: baseline days a task kept
+	rank                map[int64]int                // position of each task in the planning order
+	strategy            Strategy                     // how a task's hours are spread over its days
+	orderRank           map[int64]int                // solver: fixed planning order instead of deadline/priority
+	taskStrategy        map[int64]Strategy           // solver: per-task placement overriding strategy
+	ignoreTaskLimit     bool                         // while set, bookOnDay does not apply MaxTasksPerDay
+	now                 time.Time                    // reference day for priority aging; zero = no aging
+	blockEnds           map[int64]time.Time          // slot grid: end of each task's latest block
 }
 
 // NewScheduler creates a new scheduler instance
@@ -28,8 +40,9 @@ func NewScheduler(user *models.User, tasks []models.Task) *Scheduler {
 
 	return &Scheduler{
 		user:                user,
-		tasks:               tasks,
+		tasks:               RollUpSubtasks(tasks),
 		planningHorizonDays: horizon,
+		strategy:            StrategyByName(user.SchedulingStrategy),
 	}
 }
 
@@ -37,37 +50,103 @@ func NewScheduler(user *models.User, tasks []models.Task) *Scheduler {
 func NewSchedulerWithSlots(user *models.User, tasks []models.Task, workSlots []models.TimeSlot) *Scheduler {
 	s := NewScheduler(user, tasks)
 	s.workSlots = workSlots
+	s.busyHours = make([]float64, len(workSlots))
+	for i := range workSlots {
+		s.busyHours[i] = workSlots[i].AllocatedHours
+	}
 	return s
 }
 
 // Schedule distributes tasks across days using deadline-aware algorithm
 func (s *Scheduler) Schedule(startDate time.Time) *models.ScheduleResult {
+	if _, ok := s.strategy.(optimalStrategy); ok && len(s.baseline) == 0 {
+		return s.optimize(startDate, solverTrials())
+	}
+	return s.schedule(startDate)
+}
+
+// schedule is one planning pass: tasks in planning order, each placed by its strategy.
+func (s *Scheduler) schedule(startDate time.Time) *models.ScheduleResult {
 	result := &models.ScheduleResult{
 		Success:          true,
 		DaySchedules:     []models.DaySchedule{},
 		UnscheduledTasks: []int64{},
 	}
 
+	s.now = startDate
+	s.blockEnds = make(map[int64]time.Time)
+
+	// Create day slots map
+	daySlots := make(map[string]*models.DaySchedule)
+	lastDays := make(map[int64]time.Time)
+	failed := make(map[int64]bool)
+
+	// Pinned tasks are fixed: book them first so flexible tasks plan around them
+	s.placePinnedTasks(result, startDate, daySlots, lastDays)
+
 	// Hard rescheduling expects that we allocate all "active" tasks.
 	// We treat tasks as schedulable if they are not completed/cancelled.
 	schedulableTasks := s.filterSchedulableTasks()
-	if len(schedulableTasks) == 0 {
+	if len(schedulableTasks) == 0 && len(daySlots) == 0 {
 		result.Message = "Нет задач для планирования"
 		return result
 	}
 
-	// Sort tasks by priority and deadline
-	sortedTasks := s.sortTasksByDeadlineAndPriority(schedulableTasks)
+	// Sort tasks by priority and deadline, keeping predecessors ahead of their successors
+	sortedTasks, blocked := s.orderByDependencies(schedulableTasks)
+	for i := range schedulableTasks {
+		if blocker, ok := blocked[schedulableTasks[i].ID]; ok {
+			s.markUnscheduled(result, schedulableTasks[i].ID, blocker)
+		}
+	}
 
-	// Create day slots map
-	daySlots := make(map[string]*models.DaySchedule)
+	s.rank = make(map[int64]int, len(sortedTasks))
+	for i := range sortedTasks {
+		s.rank[sortedTasks[i].ID] = i
+	}
+	// Stable mode: tasks first keep what they can of their baseline days, in planning order.
+	kept := s.keepBaseline(sortedTasks, startDate, daySlots)
 
 	// Schedule tasks
 	for i := range sortedTasks {
-		scheduled := s.scheduleTask(&sortedTasks[i], startDate, daySlots)
+		task := &sortedTasks[i]
+		earliest, blocker, ok := s.earliestAfterPredecessors(task, lastDays, failed)
+		if !ok {
+			failed[task.ID] = true
+			s.markUnscheduled(result, task.ID, blocker)
+			continue
+		}
+		if hours := kept[task.ID]; hours > 0 {
+			// Only the hours the baseline could not hold are placed anew.
+			rest := *task
+			rest.HoursSpent += hours
+			if RemainingHours(&rest) <= 1e-9 {
+				if last, found := lastAllocatedDate(task.ID, daySlots); found {
+					lastDays[task.ID] = last
+				}
+				continue
+			}
+			task = &rest
+		}
+
+		scheduled := s.scheduleTask(task, startDate, earliest, daySlots)
 		if !scheduled {
-			result.UnscheduledTasks = append(result.UnscheduledTasks, sortedTasks[i].ID)
-			result.Success = false
+			failed[task.ID] = true
+			// Only blame the predecessor when it actually pushed the start past the planning start.
+			if blocker != 0 && !earliest.After(s.normalizeDate(startDate)) {
+				blocker = 0
+			}
+			s.markUnscheduled(result, task.ID, blocker)
+			if blocker == 0 {
+				if result.Diagnoses == nil {
+					result.Diagnoses = make(map[int64]models.Diagnosis)
+				}
+				result.Diagnoses[task.ID] = s.diagnose(task, startDate, earliest, daySlots)
+			}
+			continue
+		}
+		if last, found := lastAllocatedDate(task.ID, daySlots); found {
+			lastDays[task.ID] = last
 		}
 	}
 
@@ -83,182 +162,244 @@ func (s *Scheduler) Schedule(startDate time.Time) *models.ScheduleResult {
 	return result
 }
 
+// markUnscheduled records a task that could not be planned, optionally with the predecessor blocking it.
+func (s *Scheduler) markUnscheduled(result *models.ScheduleResult, taskID, blocker int64) {
+	result.UnscheduledTasks = append(result.UnscheduledTasks, taskID)
+	result.Success = false
+	if blocker == 0 {
+		return
+	}
+	if result.BlockedBy == nil {
+		result.BlockedBy = make(map[int64]int64)
+	}
+	result.BlockedBy[taskID] = blocker
+}
+
+// deadlineFor returns the deadline used for planning: the task's own or one implied by dependent tasks.
+func (s *Scheduler) deadlineFor(task *models.Task) *time.Time {
+	if d, ok := s.effectiveDeadlines[task.ID]; ok {
+		return &d
+	}
+	return task.Deadline
+}
+
+func (s *Scheduler) setEffectiveDeadline(taskID int64, deadline time.Time) {
+	if s.effectiveDeadlines == nil {
+		s.effectiveDeadlines = make(map[int64]time.Time)
+	}
+	s.effectiveDeadlines[taskID] = deadline
+}
+
+// RemainingHours is the part of the estimate not yet covered by logged time.
+func RemainingHours(task *models.Task) float64 {
+	if rest := task.HoursRequired - task.HoursSpent; rest > 0 {
+		return rest
+	}
+	return 0
+}
+
 // filterSchedulableTasks returns tasks that should participate in planning.
-// It excludes only completed/cancelled tasks.
+// It excludes completed/cancelled tasks, tasks whose estimate is fully logged, parents with open
+// subtasks (the subtasks are planned instead) and pinned tasks, which are placed by placePinnedTasks.
 func (s *Scheduler) filterSchedulableTasks() []models.Task {
 	active := []models.Task{}
 	for i := range s.tasks {
-		if s.tasks[i].Status != "completed" && s.tasks[i].Status != "cancelled" {
+		if s.tasks[i].Status != "completed" && s.tasks[i].Status != "cancelled" && RemainingHours(&s.tasks[i]) > 0 &&
+			s.tasks[i].OpenSubtasks == 0 && !IsPinned(&s.tasks[i]) {
 			active = append(active, s.tasks[i])
 		}
 	}
 	return active
 }
 
-// sortTasksByDeadlineAndPriority sorts tasks by deadline (closest first) and priority
+// sortTasksByDeadlineAndPriority sorts tasks by deadline (closest first) and priority.
+// With priority aging, tasks without a deadline are ordered by their effective priority, and
+// those that aged past the top priority by their soft deadline among the deadline tasks.
 func (s *Scheduler) sortTasksByDeadlineAndPriority(tasks []models.Task) []models.Task {
 	sorted := make([]models.Task, len(tasks))
 	copy(sorted, tasks)
 
+	if s.orderRank != nil {
+		sort.SliceStable(sorted, func(i, j int) bool { return s.orderRank[sorted[i].ID] < s.orderRank[sorted[j].ID] })
+		return sorted
+	}
+
 	sort.Slice(sorted, func(i, j int) bool {
+		di, dj := s.orderDeadline(&sorted[i]), s.orderDeadline(&sorted[j])
+
 		// Tasks with deadlines come first
-		if sorted[i].Deadline != nil && sorted[j].Deadline == nil {
+		if di != nil && dj == nil {
 			return true
 		}
-		if sorted[i].Deadline == nil && sorted[j].Deadline != nil {
+		if di == nil && dj != nil {
 			return false
 		}
 
 		// If both have deadlines, sort by deadline
-		if sorted[i].Deadline != nil && sorted[j].Deadline != nil {
-			if !sorted[i].Deadline.Equal(*sorted[j].Deadline) {
-				return sorted[i].Deadline.Before(*sorted[j].Deadline)
+		if di != nil && dj != nil {
+			if !di.Equal(*dj) {
+				return di.Before(*dj)
 			}
 		}
 
 		// If deadlines are equal or both don't have deadlines, sort by priority
-		if sorted[i].Priority != sorted[j].Priority {
-			return sorted[i].Priority > sorted[j].Priority
+		pi, pj := EffectivePriority(s.user, &sorted[i], s.now), EffectivePriority(s.user, &sorted[j], s.now)
+		if pi != pj {
+			return pi > pj
 		}
 
 		// If priority is equal, sort by hours (smaller tasks first)
-		return sorted[i].HoursRequired < sorted[j].HoursRequired
+		return RemainingHours(&sorted[i]) < RemainingHours(&sorted[j])
 	})
 
 	return sorted
 }
 
-// scheduleTask attempts to schedule a single task
-func (s *Scheduler) scheduleTask(task *models.Task, startDate time.Time, daySlots map[string]*models.DaySchedule) bool {
-	normalizedStart := s.normalizeDate(startDate)
-
-	if task.Deadline != nil {
-		return s.scheduleTaskBackward(task, normalizedStart, daySlots)
+// scheduleTask attempts to schedule a single task no earlier than notBefore (zero means no limit)
+func (s *Scheduler) scheduleTask(task *models.Task, startDate, notBefore time.Time, daySlots map[string]*models.DaySchedule) bool {
+	first := s.firstDayFor(task, startDate, notBefore)
+	strategy := s.strategy
+	if override, ok := s.taskStrategy[task.ID]; ok {
+		strategy = override
 	}
-
-	return s.scheduleTaskForward(task, normalizedStart, daySlots)
+	defer func() { s.ignoreTaskLimit = false }()
+	left := placeWithinTaskLimit(s.user, strategy, s.placement(task, first, daySlots), timePreferenceOf(s.user, task.TimePreference, task.Project),
+		func() { s.ignoreTaskLimit = true })
+	return left <= 1e-9
 }
 
-func (s *Scheduler) scheduleTaskForward(task *models.Task, startDate time.Time, daySlots map[string]*models.DaySchedule) bool {
-	remainingHours := task.HoursRequired
-	currentDate := startDate
-	maxDaysToCheck := s.planningHorizonDays
-	daysChecked := 0
-
-	for remainingHours > 1e-9 && daysChecked < maxDaysToCheck {
-		if !s.isWorkDay(currentDate) {
-			currentDate = currentDate.AddDate(0, 0, 1)
-			daysChecked++
-			continue
-		}
-
-		if task.Deadline != nil && currentDate.After(*task.Deadline) {
-			return false
-		}
-
-		s.allocateToDay(task, currentDate, &remainingHours, daySlots)
-
-		if remainingHours <= 1e-9 {
-			return true
-		}
-
-		currentDate = currentDate.AddDate(0, 0, 1)
-		daysChecked++
+// firstDayFor returns the first day a task may use: the planning start, pushed back by
+// its predecessors (notBefore) and its start_after.
+func (s *Scheduler) firstDayFor(task *models.Task, startDate, notBefore time.Time) time.Time {
+	first := s.normalizeDate(startDate)
+	if !notBefore.IsZero() && notBefore.After(first) {
+		first = s.normalizeDate(notBefore)
 	}
-
-	return remainingHours <= 1e-9
-}
-
-func (s *Scheduler) scheduleTaskBackward(task *models.Task, startDate time.Time, daySlots map[string]*models.DaySchedule) bool {
-	remainingHours := task.HoursRequired
-	deadline := s.normalizeDate(*task.Deadline)
-
-	currentDate := deadline
-	if currentDate.Before(startDate) {
-		return false
-	}
-
-	for remainingHours > 1e-9 && (currentDate.After(startDate) || currentDate.Equal(startDate)) {
-		if s.isWorkDay(currentDate) {
-			s.allocateToDay(task, currentDate, &remainingHours, daySlots)
+	if task.StartAfter != nil {
+		if earliest := wallClock(*task.StartAfter, startDate.Location()); earliest.After(first) {
+			first = s.normalizeDate(earliest)
 		}
-		currentDate = currentDate.AddDate(0, 0, -1)
 	}
-
-	return remainingHours <= 1e-9
+	return first
 }
 
 func (s *Scheduler) allocateToDay(task *models.Task, date time.Time, remainingHours *float64, daySlots map[string]*models.DaySchedule) {
+	*remainingHours -= s.bookOnDay(task, date, *remainingHours, *remainingHours, daySlots)
+}
+
+// bookOnDay books up to want of the task's remaining hours on a date and returns the hours booked.
+// remaining is what the task still needs in total: it decides whether a short last block is allowed.
+func (s *Scheduler) bookOnDay(task *models.Task, date time.Time, want, remaining float64, daySlots map[string]*models.DaySchedule) float64 {
 	dateKey := s.formatDate(date)
+	capacity := s.capacityOn(date)
 	daySlot, exists := daySlots[dateKey]
 	if !exists {
 		daySlot = &models.DaySchedule{
 			Date:           date,
 			Tasks:          []models.ScheduledTaskInfo{},
 			TotalHours:     0,
-			AvailableHours: s.user.DailyCapacity,
+			AvailableHours: capacity,
 		}
 		daySlots[dateKey] = daySlot
 	}
+	if !s.ignoreTaskLimit && dayTaskLimitReached(s.user, daySlot.Tasks, task.ID) {
+		return 0
+	}
 
-	availableHours := s.user.DailyCapacity - daySlot.TotalHours
+	availableHours := math.Min(want, capacity-daySlot.TotalHours)
+	// On the task's start_after day only the time after that moment is usable,
+	// and on the day of a timed deadline only the time before it.
+	notBefore := notBeforeOn(task.StartAfter, dateKey, date.Location())
+	notAfter := notAfterOn(s.deadlineFor(task), dateKey, date.Location())
+	// On a predecessor's last day only the time after its last block is usable.
+	if after := predecessorsEndOn(task.DependsOn, s.blockEnds, dateKey); after.After(notBefore) {
+		notBefore = after
+	}
 	if len(s.workSlots) > 0 {
-		slotFree := FreeHoursOnDate(s.workSlots, dateKey)
+		slotFree := freeHours(slotsOn(s.workSlots, dateKey, notBefore, notAfter))
 		if slotFree < availableHours {
 			availableHours = slotFree
 		}
+	} else if !notBefore.IsZero() || !notAfter.IsZero() {
+		availableHours = math.Min(availableHours, workHoursBetween(s.user, date, notBefore, notAfter))
 	}
-
-	if availableHours > 1e-9 {
-		hoursToAllocate := *remainingHours
-		if hoursToAllocate > availableHours {
-			hoursToAllocate = availableHours
+	if maxPerDay := MaxHoursPerDay(s.user, task); maxPerDay > 0 {
+		for i := range daySlot.Tasks {
+			if daySlot.Tasks[i].TaskID == task.ID {
+				maxPerDay -= daySlot.Tasks[i].HoursAllocated
+			}
 		}
-
-		if len(s.workSlots) > 0 {
-			hoursToAllocate = allocateOnSlots(s.workSlots, dateKey, hoursToAllocate)
+		if maxPerDay < availableHours {
+			availableHours = maxPerDay
 		}
+	}
+	// The project's weekly budget counts every task of the project planned in the date's week.
+	if left := budgetLeft(s.user, task, date, daySlots); left >= 0 && left < availableHours {
+		availableHours = left
+	}
+	if left := highEnergyLeft(s.user, task, daySlot.Tasks); left >= 0 && left < availableHours {
+		availableHours = left
+	}
 
-		if hoursToAllocate > 1e-9 {
-			found := false
-			for i := range daySlot.Tasks {
-				if daySlot.Tasks[i].TaskID == task.ID {
-					daySlot.Tasks[i].HoursAllocated += hoursToAllocate
-					found = true
-					break
-				}
-			}
-
-			if !found {
-				daySlot.Tasks = append(daySlot.Tasks, models.ScheduledTaskInfo{
-					TaskID:         task.ID,
-					Title:          task.Title,
-					HoursAllocated: hoursToAllocate,
-					Priority:       task.Priority,
-					Deadline:       task.Deadline,
-				})
-			}
+	if availableHours <= 1e-9 {
+		return 0
+	}
+	// A session shorter than the task's minimum chunk is not worth booking: skip the day instead.
+	minChunk := MinChunkHours(s.user, task.MinChunkMinutes)
+	hoursToAllocate := fitChunk(math.Min(remaining, availableHours), remaining, minChunk)
+
+	if len(s.workSlots) > 0 && hoursToAllocate > 1e-9 {
+		blocks := allocateOnSlots(s.workSlots, dateKey, hoursToAllocate, remaining, minChunk, notBefore, notAfter)
+		hoursToAllocate = hoursOf(blocks)
+		s.noteBlockEnds(task.ID, blocks)
+	}
 
-			daySlot.TotalHours += hoursToAllocate
-			daySlot.AvailableHours = s.user.DailyCapacity - daySlot.TotalHours
-			*remainingHours -= hoursToAllocate
+	if hoursToAllocate <= 1e-9 {
+		return 0
+	}
+	found := false
+	for i := range daySlot.Tasks {
+		if daySlot.Tasks[i].TaskID == task.ID {
+			daySlot.Tasks[i].HoursAllocated += hoursToAllocate
+			found = true
+			break
 		}
 	}
-}
 
-// isWorkDay checks if a date is a work day for the user
-func (s *Scheduler) isWorkDay(date time.Time) bool {
-	weekday := int(date.Weekday())
-	if weekday == 0 {
-		weekday = 7 // Sunday = 7
+	if !found {
+		daySlot.Tasks = append(daySlot.Tasks, models.ScheduledTaskInfo{
+			TaskID:          task.ID,
+			Title:           task.Title,
+			HoursAllocated:  hoursToAllocate,
+			Priority:        task.Priority,
+			Deadline:        task.Deadline,
+			MinChunkMinutes: task.MinChunkMinutes,
+			StartAfter:      task.StartAfter,
+			TimePreference:  task.TimePreference,
+			Project:         task.Project,
+			Energy:          task.Energy,
+			DependsOn:       task.DependsOn,
+		})
 	}
 
-	for _, workDay := range s.user.WorkDays {
-		if workDay == weekday {
-			return true
-		}
+	daySlot.TotalHours += hoursToAllocate
+	daySlot.AvailableHours = capacity - daySlot.TotalHours
+	return hoursToAllocate
+}
+
+// capacityOn returns how many task hours fit on a date: DailyCapacity (or the date's override)
+// limited by the day's work windows.
+func (s *Scheduler) capacityOn(date time.Time) float64 {
+	capacity := DailyCapacityOn(s.user, date)
+	if workHours := WorkHoursOn(s.user, date); workHours < capacity {
+		capacity = workHours
 	}
-	return false
+	return capacity
+}
+
+// isWorkDay checks if a date is a work day for the user (weekday in WorkDays and not a day off)
+func (s *Scheduler) isWorkDay(date time.Time) bool {
+	return IsWorkDay(s.user, date)
 }
 
 // normalizeDate removes time component from date
@@ -318,21 +459,14 @@ func NewSlotScheduler(user *models.User) *SlotScheduler {
 }
 
 // BuildDailySlots generates in-memory time slots for working days
-// between startDate and startDate + horizon.
+// between startDate and startDate + horizon, following the user's weekly work windows.
 func (s *SlotScheduler) BuildDailySlots(startDate time.Time) []models.TimeSlot {
 	var slots []models.TimeSlot
 
 	// Normalize start date to user's time zone and midnight
 	current := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
 
-	workStart := s.user.WorkStart
-	workEnd := s.user.WorkEnd
-	if workStart == "" {
-		workStart = "09:00"
-	}
-	if workEnd == "" {
-		workEnd = "18:00"
-	}
+	slotDuration := time.Duration(s.slotMinutes) * time.Minute
 
 	for day := 0; day < s.horizonDays; day++ {
 		if !s.isWorkDay(current) {
@@ -340,36 +474,28 @@ func (s *SlotScheduler) BuildDailySlots(startDate time.Time) []models.TimeSlot {
 			continue
 		}
 
-		// Parse working hours for this day
-		startClock, errStart := time.ParseInLocation("15:04", workStart, current.Location())
-		endClock, errEnd := time.ParseInLocation("15:04", workEnd, current.Location())
-		if errStart != nil || errEnd != nil || !endClock.After(startClock) {
-			// If working hours are invalid, skip slot generation for this day
-			current = current.AddDate(0, 0, 1)
-			continue
-		}
-
-		dayStart := time.Date(current.Year(), current.Month(), current.Day(), startClock.Hour(), startClock.Minute(), 0, 0, current.Location())
-		dayEnd := time.Date(current.Year(), current.Month(), current.Day(), endClock.Hour(), endClock.Minute(), 0, 0, current.Location())
+		// Each working window of the day gets its own run of slots (e.g. 09-13 and 14-18);
+		// lunch and short breaks are left out of the grid
+		for _, period := range FocusPeriodsOn(s.user, current) {
+			for t := period.Start; t.Before(period.End); t = t.Add(slotDuration) {
+				end := t.Add(slotDuration)
+				if end.After(period.End) {
+					end = period.End
+				}
 
-		slotDuration := time.Duration(s.slotMinutes) * time.Minute
-		for t := dayStart; t.Before(dayEnd); t = t.Add(slotDuration) {
-			end := t.Add(slotDuration)
-			if end.After(dayEnd) {
-				end = dayEnd
+				capacity := end.Sub(t).Hours()
+				slots = append(slots, models.TimeSlot{
+					UserID:         s.user.ID,
+					Date:           current,
+					Start:          t,
+					End:            end,
+					CapacityHours:  capacity,
+					AllocatedHours: 0,
+					TaskID:         nil,
+					Source:         "",
+					AfterBreak:     period.AfterBreak && t.Equal(period.Start),
+				})
 			}
-
-			capacity := end.Sub(t).Hours()
-			slots = append(slots, models.TimeSlot{
-				UserID:         s.user.ID,
-				Date:           current,
-				Start:          t,
-				End:            end,
-				CapacityHours:  capacity,
-				AllocatedHours: 0,
-				TaskID:         nil,
-				Source:         "",
-			})
 		}
 
 		current = current.AddDate(0, 0, 1)
@@ -378,19 +504,9 @@ func (s *SlotScheduler) BuildDailySlots(startDate time.Time) []models.TimeSlot {
 	return slots
 }
 
-// isWorkDay checks if a date is a work day for the user (reuses user's WorkDays).
+// isWorkDay checks if a date is a work day for the user (reuses user's WorkDays and days off).
 func (s *SlotScheduler) isWorkDay(date time.Time) bool {
-	weekday := int(date.Weekday())
-	if weekday == 0 {
-		weekday = 7 // Sunday = 7
-	}
-
-	for _, workDay := range s.user.WorkDays {
-		if workDay == weekday {
-			return true
-		}
-	}
-	return false
+	return IsWorkDay(s.user, date)
 }
 
 // AssignTasksToSlots performs simple greedy assignment of tasks to free slots.
@@ -406,7 +522,7 @@ func (s *SlotScheduler) AssignTasksToSlots(tasks []models.Task, slots []models.T
 
 	for i := range sortedTasks {
 		task := &sortedTasks[i]
-		remaining := task.HoursRequired
+		remaining := RemainingHours(task)
 		for j := range result {
 			if remaining <= 0 {
 				break
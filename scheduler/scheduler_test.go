@@ -179,6 +179,23 @@ func TestScheduler_DeadlineBeforeStartFails(t *testing.T) {
 	}
 }
 
+func TestScheduler_PlansOnlyRemainingHours(t *testing.T) {
+	user := &models.User{ID: 1, DailyCapacity: 4, WorkDays: []int{1, 2, 3, 4, 5}}
+	tasks := []models.Task{
+		{ID: 1, Title: "Half done", HoursRequired: 8, HoursSpent: 5.5, Status: "in_progress"},
+		{ID: 2, Title: "Overrun", HoursRequired: 2, HoursSpent: 3},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if !result.Success || len(result.DaySchedules) != 1 {
+		t.Fatalf("expected one day, got %+v", result)
+	}
+	day := result.DaySchedules[0]
+	if len(day.Tasks) != 1 || day.Tasks[0].TaskID != 1 || day.Tasks[0].HoursAllocated != 2.5 {
+		t.Errorf("expected only 2.5 remaining hours of task 1, got %+v", day.Tasks)
+	}
+}
+
 func TestSlotScheduler_AssignTasksToSlots(t *testing.T) {
 	loc := time.UTC
 	user := &models.User{
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"math"
 	"sort"
 	"time"
 
@@ -15,54 +16,110 @@ func PlanTimeAllocations(user *models.User, daySchedules []models.DaySchedule, s
 	}
 
 	slots := BuildWorkSlots(user, startDate, busy)
-	return applyDaySchedulesToSlots(slots, daySchedules)
+	return applyDaySchedulesToSlots(user, slots, daySchedules, startDate.Location())
 }
 
 // applyDaySchedulesToSlots fills slots from day-level plans and returns merged timed allocations.
-func applyDaySchedulesToSlots(slots []models.TimeSlot, daySchedules []models.DaySchedule) []models.SlotAllocation {
+// Pinned tasks keep their exact times (in loc); flexible tasks are laid out around them.
+func applyDaySchedulesToSlots(user *models.User, slots []models.TimeSlot, daySchedules []models.DaySchedule, loc *time.Location) []models.SlotAllocation {
 	slotsByDate := indexSlotsByDate(slots)
+	curve := energyCurveOf(user.EnergyCurve)
 	var allocations []models.SlotAllocation
 
 	for _, day := range daySchedules {
 		dateKey := day.Date.Format("2006-01-02")
 		daySlots := slotsByDate[dateKey]
-		if len(daySlots) == 0 {
-			continue
-		}
+		ends := make(map[int64]time.Time) // end of each task's last block laid out on the day
 
-		slotIdx := 0
+		// Pinned tasks first: their time is taken before any flexible task is laid out.
 		for _, task := range day.Tasks {
-			remaining := task.HoursAllocated
-			for remaining > 0 && slotIdx < len(daySlots) {
-				slot := daySlots[slotIdx]
-				free := slot.CapacityHours - slot.AllocatedHours
-				if free <= 1e-9 {
-					slotIdx++
-					continue
+			iv, ok := pinnedInterval(task.PinnedStart, task.PinnedEnd, loc)
+			if !ok {
+				continue
+			}
+			for _, slot := range daySlots {
+				if h := overlapHours(slot.Start, slot.End, iv.start, iv.end); h > 1e-9 {
+					slot.AllocatedHours = math.Min(slot.CapacityHours, slot.AllocatedHours+h)
 				}
+			}
+			allocations = append(allocations, models.SlotAllocation{
+				TaskID:   task.TaskID,
+				Title:    task.Title,
+				Priority: task.Priority,
+				Deadline: task.Deadline,
+				Start:    iv.start,
+				End:      iv.end,
+				Pinned:   true,
+			})
+			ends[task.TaskID] = iv.end
+		}
 
-				toAllocate := remaining
-				if toAllocate > free {
-					toAllocate = free
-				}
+		flexible := make([]models.ScheduledTaskInfo, 0, len(day.Tasks))
+		for _, task := range day.Tasks {
+			if _, pinned := pinnedInterval(task.PinnedStart, task.PinnedEnd, loc); !pinned {
+				flexible = append(flexible, task)
+			}
+		}
+		// Tasks due at a time of the day go first (earliest first) so their work ends before the
+		// deadline, then tasks with a preferred time so they get their preferred slots.
+		sort.SliceStable(flexible, func(i, j int) bool {
+			di := notAfterOn(flexible[i].Deadline, dateKey, loc)
+			dj := notAfterOn(flexible[j].Deadline, dateKey, loc)
+			if di.IsZero() && dj.IsZero() {
+				return TimePreferenceSpec(user, flexible[i].TimePreference, flexible[i].Project) != "" &&
+					TimePreferenceSpec(user, flexible[j].TimePreference, flexible[j].Project) == ""
+			}
+			if di.IsZero() || dj.IsZero() {
+				return !di.IsZero() && dj.IsZero()
+			}
+			return di.Before(dj)
+		})
+		if len(curve) > 0 {
+			sortByEnergy(flexible, dateKey, loc)
+		}
+		if user.ClusterProjects {
+			flexible = clusterByProject(flexible, dateKey, loc)
+		}
+		flexible = orderDayByDependencies(flexible)
 
-				allocStart := slot.Start.Add(time.Duration(slot.AllocatedHours * float64(time.Hour)))
-				allocEnd := allocStart.Add(time.Duration(toAllocate * float64(time.Hour)))
+		for _, task := range flexible {
+			minChunk := MinChunkHours(user, task.MinChunkMinutes)
+			from := notBeforeOn(task.StartAfter, dateKey, loc)
+			if after := predecessorsEnd(task, ends); after.After(from) {
+				from = after
+			}
+			usable := slotsFrom(daySlots, from)
+			usable = slotsUntil(usable, notAfterOn(task.Deadline, dateKey, loc))
+
+			// Preferred slots first; blocks that had to go elsewhere are marked OffPreference.
+			prefSpec := TimePreferenceSpec(user, task.TimePreference, task.Project)
+			var placed, fallback []interval
+			if prefSpec != "" {
+				placed = placeByEnergy(preferredSlots(usable, timePreferenceOf(user, task.TimePreference, task.Project)), curve, task.Energy, task.HoursAllocated, minChunk)
+			}
+			if rest := task.HoursAllocated - hoursOf(placed); rest > 1e-9 {
+				fallback = placeByEnergy(usable, curve, task.Energy, rest, minChunk)
+			}
+			if rest := task.HoursAllocated - hoursOf(placed) - hoursOf(fallback); rest > 1e-9 && minChunk > 0 {
+				// No run is long enough any more (e.g. the plan predates new calendar events): keep the hours.
+				fallback = append(fallback, placeByEnergy(usable, curve, task.Energy, rest, 0)...)
+			}
+			if prefSpec == "" {
+				placed, fallback = append(placed, fallback...), nil
+			}
 
+			for i, iv := range append(placed, fallback...) {
 				allocations = append(allocations, models.SlotAllocation{
-					TaskID:   task.TaskID,
-					Title:    task.Title,
-					Priority: task.Priority,
-					Deadline: task.Deadline,
-					Start:    allocStart,
-					End:      allocEnd,
+					TaskID:        task.TaskID,
+					Title:         task.Title,
+					Priority:      task.Priority,
+					Deadline:      task.Deadline,
+					Start:         iv.start,
+					End:           iv.end,
+					OffPreference: i >= len(placed),
 				})
-
-				slot.AllocatedHours += toAllocate
-				remaining -= toAllocate
-
-				if slot.AllocatedHours >= slot.CapacityHours-1e-9 {
-					slotIdx++
+				if iv.end.After(ends[task.TaskID]) {
+					ends[task.TaskID] = iv.end
 				}
 			}
 		}
@@ -71,7 +128,8 @@ func applyDaySchedulesToSlots(slots []models.TimeSlot, daySchedules []models.Day
 	return MergeSlotAllocations(allocations)
 }
 
-// MergeSlotAllocations joins consecutive blocks of the same task into one interval.
+// MergeSlotAllocations joins consecutive blocks of the same task into one interval. A block in the
+// task's preferred time stays apart from an adjoining one outside it.
 func MergeSlotAllocations(allocations []models.SlotAllocation) []models.SlotAllocation {
 	if len(allocations) == 0 {
 		return nil
@@ -88,7 +146,7 @@ func MergeSlotAllocations(allocations []models.SlotAllocation) []models.SlotAllo
 	for i := 1; i < len(allocations); i++ {
 		cur := allocations[i]
 		last := &merged[len(merged)-1]
-		if cur.TaskID == last.TaskID && cur.Start.Equal(last.End) {
+		if cur.TaskID == last.TaskID && cur.Start.Equal(last.End) && cur.OffPreference == last.OffPreference {
 			last.End = cur.End
 			continue
 		}
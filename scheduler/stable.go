@@ -0,0 +1,293 @@
+package scheduler
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// ScheduleStable rebuilds the plan with the previous one as a baseline: every task first keeps
+// its baseline days as far as its constraints and the day capacity still allow, and only the
+// rest is planned anew. When keeping the baseline costs a task that a fresh plan would fit, or
+// breaks a dependency, the lowest-ranked task in the way gives up its baseline and planning repeats.
+// workSlots is not modified.
+func ScheduleStable(user *models.User, tasks []models.Task, workSlots []models.TimeSlot, baseline []models.DaySchedule, startDate time.Time) *models.ScheduleResult {
+	fresh := NewSchedulerWithSlots(user, tasks, copySlots(workSlots)).Schedule(startDate)
+	freshMissing := make(map[int64]bool, len(fresh.UnscheduledTasks))
+	for _, id := range fresh.UnscheduledTasks {
+		freshMissing[id] = true
+	}
+
+	keep := baselineHours(baseline)
+	for {
+		s := NewSchedulerWithSlots(user, tasks, copySlots(workSlots))
+		s.baseline = keep
+		result := s.Schedule(startDate)
+
+		release := s.releaseCandidate(result, startDate, freshMissing)
+		if release == 0 {
+			orderLikeBaseline(result.DaySchedules, baseline)
+			return result
+		}
+		delete(keep, release)
+	}
+}
+
+// keepBaseline books each task's baseline days that are still valid (work day, inside its
+// start/deadline window, capacity left) and returns the hours kept per task.
+func (s *Scheduler) keepBaseline(sorted []models.Task, startDate time.Time, daySlots map[string]*models.DaySchedule) map[int64]float64 {
+	kept := make(map[int64]float64)
+	if len(s.baseline) == 0 {
+		return kept
+	}
+	s.keptDays = make(map[int64][]time.Time)
+	loc := startDate.Location()
+
+	for i := range sorted {
+		task := &sorted[i]
+		days, ok := s.baseline[task.ID]
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(days))
+		for key := range days {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		first := s.firstDayFor(task, startDate, time.Time{})
+		deadline := s.deadlineFor(task)
+		left := RemainingHours(task)
+		for _, key := range keys {
+			if left <= 1e-9 {
+				break
+			}
+			date, err := time.ParseInLocation("2006-01-02", key, loc)
+			if err != nil || date.Before(first) || !s.isWorkDay(date) {
+				continue
+			}
+			if deadline != nil && date.After(deadlineDay(*deadline, loc)) {
+				continue
+			}
+			want := math.Min(days[key], left)
+			rest := want
+			s.allocateToDay(task, date, &rest, daySlots)
+			if placed := want - rest; placed > 1e-9 {
+				left -= placed
+				kept[task.ID] += placed
+				s.keptDays[task.ID] = append(s.keptDays[task.ID], date)
+			}
+		}
+	}
+	return kept
+}
+
+// releaseCandidate returns a task that must give up its baseline, or 0 when the plan is acceptable:
+// a successor kept ahead of its predecessor's last day, or else the lowest-ranked task that kept
+// days in the window of a task the fresh plan could fit.
+func (s *Scheduler) releaseCandidate(result *models.ScheduleResult, startDate time.Time, freshMissing map[int64]bool) int64 {
+	if len(s.keptDays) == 0 {
+		return 0
+	}
+	days := make(map[string]*models.DaySchedule, len(result.DaySchedules))
+	for i := range result.DaySchedules {
+		days[s.formatDate(result.DaySchedules[i].Date)] = &result.DaySchedules[i]
+	}
+
+	for i := range s.tasks {
+		task := &s.tasks[i]
+		if len(s.keptDays[task.ID]) == 0 {
+			continue
+		}
+		first, ok := firstAllocatedDate(task.ID, days)
+		if !ok {
+			continue
+		}
+		for _, p := range task.DependsOn {
+			if last, found := lastAllocatedDate(p, days); found && first.Before(last) {
+				return task.ID
+			}
+		}
+	}
+
+	loc := startDate.Location()
+	horizonEnd := s.normalizeDate(startDate).AddDate(0, 0, s.planningHorizonDays-1)
+	for _, id := range result.UnscheduledTasks {
+		if freshMissing[id] {
+			continue
+		}
+		lost := s.taskByID(id)
+		if lost == nil {
+			continue
+		}
+		from, to := s.firstDayFor(lost, startDate, time.Time{}), horizonEnd
+		if d := s.deadlineFor(lost); d != nil {
+			to = deadlineDay(*d, loc)
+		}
+
+		var pick int64
+		for taskID, kept := range s.keptDays {
+			inWindow := false
+			for _, day := range kept {
+				if !day.Before(from) && !day.After(to) {
+					inWindow = true
+					break
+				}
+			}
+			if inWindow && (pick == 0 || s.rank[taskID] > s.rank[pick]) {
+				pick = taskID
+			}
+		}
+		if pick == 0 {
+			pick = s.lowestRankedKept()
+		}
+		return pick
+	}
+	return 0
+}
+
+func (s *Scheduler) lowestRankedKept() int64 {
+	var pick int64
+	for taskID, kept := range s.keptDays {
+		if len(kept) > 0 && (pick == 0 || s.rank[taskID] > s.rank[pick]) {
+			pick = taskID
+		}
+	}
+	return pick
+}
+
+func (s *Scheduler) taskByID(id int64) *models.Task {
+	for i := range s.tasks {
+		if s.tasks[i].ID == id {
+			return &s.tasks[i]
+		}
+	}
+	return nil
+}
+
+func firstAllocatedDate(taskID int64, daySlots map[string]*models.DaySchedule) (time.Time, bool) {
+	var first time.Time
+	found := false
+	for _, day := range daySlots {
+		for _, info := range day.Tasks {
+			if info.TaskID == taskID && info.HoursAllocated > 1e-9 {
+				if !found || day.Date.Before(first) {
+					first = day.Date
+				}
+				found = true
+			}
+		}
+	}
+	return first, found
+}
+
+// baselineHours indexes a plan as task -> day -> hours. Pinned entries are skipped: pinned tasks
+// are always placed at their own time.
+func baselineHours(baseline []models.DaySchedule) map[int64]map[string]float64 {
+	hours := make(map[int64]map[string]float64)
+	for _, day := range baseline {
+		key := day.Date.Format("2006-01-02")
+		for _, info := range day.Tasks {
+			if info.PinnedStart != nil {
+				continue
+			}
+			if hours[info.TaskID] == nil {
+				hours[info.TaskID] = make(map[string]float64)
+			}
+			hours[info.TaskID][key] += info.HoursAllocated
+		}
+	}
+	return hours
+}
+
+// orderLikeBaseline keeps each day's tasks in the baseline order, new ones last, so that
+// time-of-day placement of unchanged tasks stays where it was.
+func orderLikeBaseline(days []models.DaySchedule, baseline []models.DaySchedule) {
+	positions := make(map[string]map[int64]int, len(baseline))
+	for _, day := range baseline {
+		pos := make(map[int64]int, len(day.Tasks))
+		for i, info := range day.Tasks {
+			pos[info.TaskID] = i
+		}
+		positions[day.Date.Format("2006-01-02")] = pos
+	}
+	for i := range days {
+		pos := positions[days[i].Date.Format("2006-01-02")]
+		at := func(id int64) int {
+			if p, ok := pos[id]; ok {
+				return p
+			}
+			return len(pos)
+		}
+		tasks := days[i].Tasks
+		sort.SliceStable(tasks, func(a, b int) bool { return at(tasks[a].TaskID) < at(tasks[b].TaskID) })
+	}
+}
+
+func copySlots(slots []models.TimeSlot) []models.TimeSlot {
+	return append([]models.TimeSlot(nil), slots...)
+}
+
+// DiffPlans reports tasks whose hours moved between days from baseline to plan. Hours that only
+// appear (new task, larger estimate) or only disappear (logged time, completed task) are not moves.
+func DiffPlans(baseline, plan []models.DaySchedule) []models.TaskMove {
+	type taskDays struct {
+		title string
+		hours map[string]float64
+		first time.Time
+	}
+	index := func(days []models.DaySchedule) map[int64]*taskDays {
+		m := make(map[int64]*taskDays)
+		for _, day := range days {
+			key := day.Date.Format("2006-01-02")
+			date, _ := time.Parse("2006-01-02", key)
+			for _, info := range day.Tasks {
+				td := m[info.TaskID]
+				if td == nil {
+					td = &taskDays{title: info.Title, hours: make(map[string]float64), first: date}
+					m[info.TaskID] = td
+				}
+				td.hours[key] += info.HoursAllocated
+				if date.Before(td.first) {
+					td.first = date
+				}
+			}
+		}
+		return m
+	}
+	before, after := index(baseline), index(plan)
+
+	var moves []models.TaskMove
+	for id, old := range before {
+		cur, ok := after[id]
+		if !ok {
+			continue
+		}
+		var removed, added float64
+		for key, h := range old.hours {
+			removed += math.Max(0, h-cur.hours[key])
+		}
+		for key, h := range cur.hours {
+			added += math.Max(0, h-old.hours[key])
+		}
+		moved := math.Min(removed, added)
+		if moved <= 1e-6 {
+			continue
+		}
+		moves = append(moves, models.TaskMove{
+			TaskID:     id,
+			Title:      cur.title,
+			MovedHours: moved,
+			ShiftDays:  int(math.Round(cur.first.Sub(old.first).Hours() / 24)),
+		})
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].MovedHours != moves[j].MovedHours {
+			return moves[i].MovedHours > moves[j].MovedHours
+		}
+		return moves[i].TaskID < moves[j].TaskID
+	})
+	return moves
+}
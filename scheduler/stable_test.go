@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func baselineDay(date time.Time, tasks ...models.ScheduledTaskInfo) models.DaySchedule {
+	day := models.DaySchedule{Date: date, Tasks: tasks}
+	for _, t := range tasks {
+		day.TotalHours += t.HoursAllocated
+	}
+	return day
+}
+
+func TestScheduleStable_KeepsBaselineDays(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	wednesday := monday.AddDate(0, 0, 2)
+	tasks := []models.Task{
+		{ID: 1, Title: "Old", HoursRequired: 4, Priority: 5},
+		{ID: 2, Title: "New", HoursRequired: 4, Priority: 10},
+	}
+	baseline := []models.DaySchedule{baselineDay(wednesday, models.ScheduledTaskInfo{TaskID: 1, Title: "Old", HoursAllocated: 4})}
+
+	result := ScheduleStable(user, tasks, BuildWorkSlots(user, monday, nil), baseline, monday)
+	if !result.Success {
+		t.Fatalf("expected both tasks planned, got %+v", result)
+	}
+	if first, _ := firstDayOf(1, result.DaySchedules); !first.Equal(wednesday) {
+		t.Errorf("expected task 1 to stay on Wednesday, got %v", first)
+	}
+	if first, _ := firstDayOf(2, result.DaySchedules); !first.Equal(monday) {
+		t.Errorf("expected the new task on Monday, got %v", first)
+	}
+	if moves := DiffPlans(baseline, result.DaySchedules); len(moves) != 0 {
+		t.Errorf("expected no moves, got %+v", moves)
+	}
+}
+
+func TestScheduleStable_MovesWhenConstraintsChange(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	wednesday := monday.AddDate(0, 0, 2)
+	tasks := []models.Task{{ID: 1, Title: "Now due Tuesday", HoursRequired: 4, Priority: 5, Deadline: &tuesday}}
+	baseline := []models.DaySchedule{baselineDay(wednesday, models.ScheduledTaskInfo{TaskID: 1, Title: "Now due Tuesday", HoursAllocated: 4})}
+
+	result := ScheduleStable(user, tasks, BuildWorkSlots(user, monday, nil), baseline, monday)
+	moves := DiffPlans(baseline, result.DaySchedules)
+	if !result.Success || len(moves) != 1 || moves[0].MovedHours != 4 || moves[0].ShiftDays != -1 {
+		t.Fatalf("expected task 1 moved 4h one day earlier, got %+v / %+v", result.DaySchedules, moves)
+	}
+}
+
+func TestScheduleStable_ReleasesBaselineForUrgentTask(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: 1, Title: "Flexible", HoursRequired: 4, Priority: 1},
+		{ID: 2, Title: "Urgent", HoursRequired: 4, Priority: 5, Deadline: &monday},
+	}
+	baseline := []models.DaySchedule{baselineDay(monday, models.ScheduledTaskInfo{TaskID: 1, Title: "Flexible", HoursAllocated: 4})}
+
+	result := ScheduleStable(user, tasks, BuildWorkSlots(user, monday, nil), baseline, monday)
+	if !result.Success {
+		t.Fatalf("expected the urgent task to fit, got %+v", result)
+	}
+	moves := DiffPlans(baseline, result.DaySchedules)
+	if len(moves) != 1 || moves[0].TaskID != 1 || moves[0].ShiftDays != 1 {
+		t.Errorf("expected task 1 to move to Tuesday, got %+v", moves)
+	}
+}
+
+func TestScheduleStable_KeepsDependencyOrder(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: 1, Title: "First", HoursRequired: 4, Priority: 5},
+		{ID: 2, Title: "Second", HoursRequired: 4, Priority: 5, DependsOn: []int64{1}},
+	}
+	baseline := []models.DaySchedule{baselineDay(monday, models.ScheduledTaskInfo{TaskID: 2, Title: "Second", HoursAllocated: 4})}
+
+	result := ScheduleStable(user, tasks, BuildWorkSlots(user, monday, nil), baseline, monday)
+	first, _ := firstDayOf(2, result.DaySchedules)
+	if !result.Success || !first.After(monday) {
+		t.Fatalf("expected task 2 to move after task 1, got %+v", result.DaySchedules)
+	}
+}
+
+func TestDiffPlans_IgnoresGrowthAndShrink(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	baseline := []models.DaySchedule{baselineDay(monday, models.ScheduledTaskInfo{TaskID: 1, HoursAllocated: 2})}
+	plan := []models.DaySchedule{
+		baselineDay(monday, models.ScheduledTaskInfo{TaskID: 1, HoursAllocated: 2}),
+		baselineDay(monday.AddDate(0, 0, 1), models.ScheduledTaskInfo{TaskID: 1, HoursAllocated: 3}),
+	}
+	if moves := DiffPlans(baseline, plan); len(moves) != 0 {
+		t.Errorf("extra hours are not a move, got %+v", moves)
+	}
+	if moves := DiffPlans(plan, baseline); len(moves) != 0 {
+		t.Errorf("fewer hours are not a move, got %+v", moves)
+	}
+}
@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// notBeforeOn returns the earliest usable moment on the day dateKey for a task with the given
+// start_after, or zero when the whole day is usable. Times are read in loc.
+func notBeforeOn(startAfter *time.Time, dateKey string, loc *time.Location) time.Time {
+	if startAfter == nil {
+		return time.Time{}
+	}
+	start := wallClock(*startAfter, loc)
+	if start.Format("2006-01-02") != dateKey {
+		return time.Time{}
+	}
+	return start
+}
+
+// slotsFrom keeps the slots that start at or after from; zero from keeps all of them.
+func slotsFrom(daySlots []*models.TimeSlot, from time.Time) []*models.TimeSlot {
+	if from.IsZero() {
+		return daySlots
+	}
+	var kept []*models.TimeSlot
+	for _, slot := range daySlots {
+		if !slot.Start.Before(from) {
+			kept = append(kept, slot)
+		}
+	}
+	return kept
+}
+
+// workHoursBetween returns working hours on date between from and until; a zero bound
+// leaves that side of the day open.
+func workHoursBetween(user *models.User, date, from, until time.Time) float64 {
+	var hours float64
+	for _, p := range FocusPeriodsOn(user, date) {
+		start, end := p.Start, p.End
+		if from.After(start) {
+			start = from
+		}
+		if !until.IsZero() && until.Before(end) {
+			end = until
+		}
+		if end.After(start) {
+			hours += end.Sub(start).Hours()
+		}
+	}
+	return hours
+}
+
+// WindowHours returns the working capacity between a task's earliest start and its deadline
+// (the whole deadline day for a date-only deadline), ignoring other tasks and calendar events.
+// Used to explain why a task did not fit.
+func WindowHours(user *models.User, startAfter, deadline time.Time) float64 {
+	loc := startAfter.Location()
+	first := time.Date(startAfter.Year(), startAfter.Month(), startAfter.Day(), 0, 0, 0, 0, loc)
+	last := deadlineDay(deadline, loc)
+
+	var total float64
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if !IsWorkDay(user, day) {
+			continue
+		}
+		from, until := time.Time{}, time.Time{}
+		if day.Equal(first) {
+			from = startAfter
+		}
+		if day.Equal(last) && HasDeadlineTime(deadline) {
+			until = wallClock(deadline, loc)
+		}
+		total += math.Min(DailyCapacityOn(user, day), workHoursBetween(user, day, from, until))
+	}
+	return total
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func firstDayOf(taskID int64, days []models.DaySchedule) (time.Time, bool) {
+	for _, day := range days {
+		for _, info := range day.Tasks {
+			if info.TaskID == taskID {
+				return day.Date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func TestSchedule_StartAfterForward(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "14")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	wednesday := monday.AddDate(0, 0, 2)
+	tasks := []models.Task{{ID: 1, Title: "After materials", HoursRequired: 4, Priority: 9, StartAfter: &wednesday}}
+
+	result := NewScheduler(morningUser(), tasks).Schedule(monday)
+	if first, ok := firstDayOf(1, result.DaySchedules); !result.Success || !ok || !first.Equal(wednesday) {
+		t.Fatalf("expected the task to start on Wednesday, got %+v", result.DaySchedules)
+	}
+}
+
+func TestSchedule_StartAfterBackwardWindow(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "14")
+	user := &models.User{ID: 1, DailyCapacity: 4, WorkDays: []int{1, 2, 3, 4, 5}}
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	thursday := monday.AddDate(0, 0, 3)
+	friday := monday.AddDate(0, 0, 4)
+
+	fits := []models.Task{{ID: 1, Title: "Fits", HoursRequired: 8, StartAfter: &thursday, Deadline: &friday}}
+	result := NewScheduler(user, fits).Schedule(monday)
+	if first, ok := firstDayOf(1, result.DaySchedules); !result.Success || !ok || first.Before(thursday) {
+		t.Fatalf("expected 8h on Thursday and Friday, got %+v", result.DaySchedules)
+	}
+
+	tooBig := []models.Task{{ID: 2, Title: "Too big", HoursRequired: 10, StartAfter: &thursday, Deadline: &friday}}
+	result = NewScheduler(user, tooBig).Schedule(monday)
+	if result.Success || len(result.UnscheduledTasks) != 1 {
+		t.Errorf("expected the task not to fit its window, got %+v", result)
+	}
+	if got := WindowHours(user, thursday, friday); got != 8 {
+		t.Errorf("WindowHours = %v, want 8", got)
+	}
+}
+
+func TestSchedule_StartAfterTimeOfDay(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "5")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	at11 := monday.Add(11 * time.Hour)
+	tasks := []models.Task{{ID: 1, Title: "After lunch call", HoursRequired: 3, StartAfter: &at11}}
+
+	result := NewSchedulerWithSlots(user, tasks, BuildWorkSlots(user, monday, nil)).Schedule(monday)
+	if !result.Success || len(result.DaySchedules) != 2 || result.DaySchedules[0].TotalHours != 2 {
+		t.Fatalf("expected 2h on Monday after 11:00 and the rest on Tuesday, got %+v", result.DaySchedules)
+	}
+	allocations := PlanTimeAllocations(user, result.DaySchedules, monday, nil)
+	if len(allocations) == 0 || allocations[0].Start.Before(at11) {
+		t.Errorf("expected Monday's block to start at 11:00, got %+v", allocations)
+	}
+
+	// Without a slot grid the day-level plan still counts only the hours after 11:00.
+	result = NewScheduler(user, tasks).Schedule(monday)
+	if len(result.DaySchedules) == 0 || result.DaySchedules[0].TotalHours != 2 {
+		t.Errorf("expected 2h on Monday, got %+v", result.DaySchedules)
+	}
+	if got := WindowHours(user, at11, monday.AddDate(0, 0, 1)); got != 6 {
+		t.Errorf("WindowHours = %v, want 6", got)
+	}
+}
+
+func TestScheduleTaskIntoExisting_StartAfter(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "14")
+	user := morningUser()
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tuesdayNoon := monday.AddDate(0, 0, 1).Add(12 * time.Hour)
+	task := &models.Task{ID: 3, Title: "Later", HoursRequired: 2, StartAfter: &tuesdayNoon}
+
+	days, ok := ScheduleTaskIntoExisting(user, task, nil, monday, nil)
+	if !ok || len(days) != 2 || !days[0].Date.Equal(monday.AddDate(0, 0, 1)) || days[0].TotalHours != 1 {
+		t.Fatalf("expected 1h on Tuesday after 12:00 and 1h on Wednesday, got %+v (ok=%v)", days, ok)
+	}
+}
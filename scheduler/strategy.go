@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// Strategy names stored in users.scheduling_strategy.
+const (
+	StrategyGreedy   = "greedy"
+	StrategyASAP     = "asap"
+	StrategyBalanced = "balanced"
+)
+
+// StrategyNames lists the selectable strategies, default first.
+var StrategyNames = []string{StrategyGreedy, StrategyASAP, StrategyBalanced, StrategyOptimal}
+
+// Strategy decides on which days one task's hours go. Tasks reach it one at a time in planning
+// order; the day-level limits (capacity, calendar, chunks, start_after) are enforced by Book.
+type Strategy interface {
+	Name() string
+	// Place books the task's hours and returns how many hours could not be placed.
+	Place(p Placement) float64
+}
+
+// Placement is one task's window of days and the ways to inspect and fill them.
+type Placement struct {
+	First       time.Time // first day the task may use
+	Last        time.Time // deadline day, or the end of the planning horizon
+	HasDeadline bool
+	Remaining   float64
+	MinChunk    float64                                   // task's shortest session in hours, 0 = any
+	IsWorkDay   func(day time.Time) bool                  // false for weekends and days off
+	Load        func(day time.Time) float64               // hours already planned on a day
+	Book        func(day time.Time, want float64) float64 // books up to want hours on a day, returns hours booked
+}
+
+// StrategyByName returns the strategy with the given name, or the greedy one when it is unknown.
+func StrategyByName(name string) Strategy {
+	switch name {
+	case StrategyASAP:
+		return asapStrategy{}
+	case StrategyBalanced:
+		return balancedStrategy{}
+	case StrategyOptimal:
+		return optimalStrategy{}
+	default:
+		return greedyStrategy{}
+	}
+}
+
+// greedyStrategy fills tasks with a deadline backward from the deadline day, so work lands as
+// late as allowed and earlier days stay free for other tasks, and the rest forward from First.
+type greedyStrategy struct{}
+
+func (greedyStrategy) Name() string { return StrategyGreedy }
+
+func (greedyStrategy) Place(p Placement) float64 {
+	if p.HasDeadline {
+		return placeBackward(p)
+	}
+	return placeForward(p)
+}
+
+// asapStrategy front-loads every task: each one fills the earliest free days.
+type asapStrategy struct{}
+
+func (asapStrategy) Name() string { return StrategyASAP }
+
+func (asapStrategy) Place(p Placement) float64 { return placeForward(p) }
+
+// balancedStrategy levels the load: the task's hours go, one step at a time, to the least
+// loaded work day of its window. Without a deadline the window is the plan built so far,
+// growing by a day only when it is full.
+type balancedStrategy struct{}
+
+func (balancedStrategy) Name() string { return StrategyBalanced }
+
+// balanceStep is the smallest piece the balanced strategy hands to a day.
+const balanceStep = 1.0
+
+func (balancedStrategy) Place(p Placement) float64 {
+	remaining := p.Remaining
+	step := math.Max(balanceStep, p.MinChunk)
+
+	end := p.Last
+	if !p.HasDeadline {
+		end = p.First
+		for day := p.First; !day.After(p.Last); day = day.AddDate(0, 0, 1) {
+			if p.Load(day) > 1e-9 {
+				end = day
+			}
+		}
+	}
+
+	full := make(map[time.Time]bool)
+	for remaining > 1e-9 {
+		var pick time.Time
+		for day := p.First; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if full[day] || !p.IsWorkDay(day) {
+				continue
+			}
+			if pick.IsZero() || p.Load(day) < p.Load(pick)-1e-9 {
+				pick = day
+			}
+		}
+		if pick.IsZero() {
+			if p.HasDeadline || !end.Before(p.Last) {
+				break
+			}
+			end = end.AddDate(0, 0, 1)
+			continue
+		}
+		booked := p.Book(pick, math.Min(step, remaining))
+		if booked <= 1e-9 {
+			full[pick] = true
+			continue
+		}
+		remaining -= booked
+	}
+	return remaining
+}
+
+func placeForward(p Placement) float64 {
+	remaining := p.Remaining
+	for day := p.First; remaining > 1e-9 && !day.After(p.Last); day = day.AddDate(0, 0, 1) {
+		if p.IsWorkDay(day) {
+			remaining -= p.Book(day, remaining)
+		}
+	}
+	return remaining
+}
+
+func placeBackward(p Placement) float64 {
+	remaining := p.Remaining
+	for day := p.Last; remaining > 1e-9 && !day.Before(p.First); day = day.AddDate(0, 0, -1) {
+		if p.IsWorkDay(day) {
+			remaining -= p.Book(day, remaining)
+		}
+	}
+	return remaining
+}
+
+// placement describes task for the scheduler's strategy, booking through bookOnDay.
+func (s *Scheduler) placement(task *models.Task, first time.Time, daySlots map[string]*models.DaySchedule) Placement {
+	remaining := RemainingHours(task)
+	p := Placement{
+		First:     first,
+		Last:      first.AddDate(0, 0, s.planningHorizonDays-1),
+		Remaining: remaining,
+		MinChunk:  MinChunkHours(s.user, task.MinChunkMinutes),
+		IsWorkDay: s.isWorkDay,
+		Load: func(day time.Time) float64 {
+			if planned, ok := daySlots[s.formatDate(day)]; ok {
+				return planned.TotalHours
+			}
+			return 0
+		},
+		Book: func(day time.Time, want float64) float64 {
+			placed := s.bookOnDay(task, day, want, remaining, daySlots)
+			remaining -= placed
+			return placed
+		},
+	}
+	if deadline := s.deadlineFor(task); deadline != nil {
+		p.Last = deadlineDay(*deadline, first.Location())
+		p.HasDeadline = true
+	}
+	return p
+}
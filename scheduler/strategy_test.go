@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func hoursByDay(taskID int64, days []models.DaySchedule) map[time.Weekday]float64 {
+	hours := make(map[time.Weekday]float64)
+	for _, day := range days {
+		for _, info := range day.Tasks {
+			if info.TaskID == taskID {
+				hours[day.Date.Weekday()] += info.HoursAllocated
+			}
+		}
+	}
+	return hours
+}
+
+func TestStrategies_DeadlineTask(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	friday := monday.AddDate(0, 0, 4)
+	tasks := []models.Task{{ID: 1, Title: "Report", HoursRequired: 4, Priority: 5, Deadline: &friday}}
+
+	cases := []struct {
+		strategy string
+		want     map[time.Weekday]float64
+	}{
+		{StrategyGreedy, map[time.Weekday]float64{time.Friday: 4}},
+		{StrategyASAP, map[time.Weekday]float64{time.Monday: 4}},
+		{StrategyBalanced, map[time.Weekday]float64{time.Monday: 1, time.Tuesday: 1, time.Wednesday: 1, time.Thursday: 1}},
+	}
+	for _, tc := range cases {
+		user := morningUser()
+		user.SchedulingStrategy = tc.strategy
+		result := NewScheduler(user, tasks).Schedule(monday)
+		if !result.Success {
+			t.Fatalf("%s: expected the task planned, got %+v", tc.strategy, result)
+		}
+		got := hoursByDay(1, result.DaySchedules)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.strategy, got, tc.want)
+			continue
+		}
+		for day, h := range tc.want {
+			if got[day] != h {
+				t.Errorf("%s: got %v, want %v", tc.strategy, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestBalancedStrategy_LevelsTaskWithoutDeadline(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	user.SchedulingStrategy = StrategyBalanced
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	wednesday := monday.AddDate(0, 0, 2)
+	tasks := []models.Task{
+		{ID: 1, Title: "Due Wednesday", HoursRequired: 6, Priority: 5, Deadline: &wednesday},
+		{ID: 2, Title: "Whenever", HoursRequired: 3, Priority: 5},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected both tasks planned, got %+v", result)
+	}
+	for _, day := range result.DaySchedules {
+		if day.TotalHours != 3 {
+			t.Errorf("expected 3h on every day Mon–Wed, got %v on %s", day.TotalHours, day.Date.Weekday())
+		}
+	}
+	if len(result.DaySchedules) != 3 {
+		t.Errorf("expected the plan to stay within Mon–Wed, got %d days", len(result.DaySchedules))
+	}
+}
+
+func TestBalancedStrategy_GrowsWindowWhenFull(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	user := morningUser()
+	user.SchedulingStrategy = StrategyBalanced
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{{ID: 1, Title: "Big", HoursRequired: 10, Priority: 5}}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	got := hoursByDay(1, result.DaySchedules)
+	if !result.Success || got[time.Monday] != 4 || got[time.Tuesday] != 4 || got[time.Wednesday] != 2 {
+		t.Errorf("expected 4+4+2 from Monday, got %v", got)
+	}
+}
+
+func TestScheduleTaskIntoExisting_UsesStrategy(t *testing.T) {
+	t.Setenv("PLANNING_HORIZON_DAYS", "7")
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	thursday := monday.AddDate(0, 0, 3)
+	task := &models.Task{ID: 1, Title: "Report", HoursRequired: 2, Priority: 5, Deadline: &thursday}
+
+	user := morningUser()
+	days, ok := ScheduleTaskIntoExisting(user, task, nil, monday, nil)
+	if !ok || len(days) != 1 || !days[0].Date.Equal(thursday) {
+		t.Errorf("greedy: expected the task on its deadline day, got %+v (ok=%v)", days, ok)
+	}
+
+	user.SchedulingStrategy = StrategyASAP
+	days, ok = ScheduleTaskIntoExisting(user, task, nil, monday, nil)
+	if !ok || len(days) != 1 || !days[0].Date.Equal(monday) {
+		t.Errorf("asap: expected the task on Monday, got %+v (ok=%v)", days, ok)
+	}
+
+	user.SchedulingStrategy = StrategyBalanced
+	existing := []models.DaySchedule{baselineDay(monday, models.ScheduledTaskInfo{TaskID: 9, HoursAllocated: 3})}
+	days, ok = ScheduleTaskIntoExisting(user, task, existing, monday, nil)
+	if !ok || len(days) != 2 || !days[0].Date.Equal(monday.AddDate(0, 0, 1)) {
+		t.Errorf("balanced: expected the task to skip the loaded Monday, got %+v (ok=%v)", days, ok)
+	}
+}
+
+func TestStrategyByName_DefaultsToGreedy(t *testing.T) {
+	if got := StrategyByName("").Name(); got != StrategyGreedy {
+		t.Errorf("empty name: got %q", got)
+	}
+	if got := StrategyByName("bogus").Name(); got != StrategyGreedy {
+		t.Errorf("unknown name: got %q", got)
+	}
+	for _, name := range StrategyNames {
+		if got := StrategyByName(name).Name(); got != name {
+			t.Errorf("%s: got %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+// RollUpSubtasks prepares a task list with subtasks for planning. A parent with open subtasks is
+// not planned itself: its HoursRequired and HoursSpent become the sums over the open subtasks
+// (so it shows the work left) and OpenSubtasks is set. Open subtasks are planned in position
+// order: each depends on the previous one, the first inherits the parent's dependencies, subtasks
+// without a deadline inherit the parent's, and tasks that depend on the parent wait for its last
+// open subtask instead. The input is left untouched; rolling up twice gives the same result.
+func RollUpSubtasks(tasks []models.Task) []models.Task {
+	children := make(map[int64][]int)
+	for i := range tasks {
+		if p := tasks[i].ParentID; p != nil && isOpen(&tasks[i]) {
+			children[*p] = append(children[*p], i)
+		}
+	}
+	if len(children) == 0 {
+		return tasks
+	}
+
+	out := make([]models.Task, len(tasks))
+	copy(out, tasks)
+	for i := range out {
+		out[i].DependsOn = append([]int64(nil), tasks[i].DependsOn...)
+	}
+
+	lastChild := make(map[int64]int64)
+	for i := range out {
+		parent := &out[i]
+		kids := children[parent.ID]
+		if len(kids) == 0 || !isOpen(parent) {
+			continue
+		}
+		sort.SliceStable(kids, func(a, b int) bool { return out[kids[a]].Position < out[kids[b]].Position })
+
+		var required, spent float64
+		prev := int64(0)
+		for _, k := range kids {
+			child := &out[k]
+			required += child.HoursRequired
+			spent += child.HoursSpent
+			if child.Deadline == nil {
+				child.Deadline = parent.Deadline
+			}
+			if prev == 0 {
+				child.DependsOn = appendMissing(child.DependsOn, parent.DependsOn...)
+			} else {
+				child.DependsOn = appendMissing(child.DependsOn, prev)
+			}
+			prev = child.ID
+		}
+		parent.HoursRequired, parent.HoursSpent = required, spent
+		parent.OpenSubtasks = len(kids)
+		lastChild[parent.ID] = prev
+	}
+
+	for i := range out {
+		for j, p := range out[i].DependsOn {
+			if last, ok := lastChild[p]; ok && last != out[i].ID {
+				out[i].DependsOn[j] = last
+			}
+		}
+	}
+	return out
+}
+
+func isOpen(task *models.Task) bool {
+	return task.Status != "completed" && task.Status != "cancelled"
+}
+
+func appendMissing(ids []int64, add ...int64) []int64 {
+	for _, id := range add {
+		found := false
+		for _, have := range ids {
+			if have == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
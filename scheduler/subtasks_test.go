@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adkhorst/planbot/models"
+)
+
+func TestRollUpSubtasks(t *testing.T) {
+	parentID := int64(1)
+	deadline := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: 1, Title: "Report", HoursRequired: 1, Deadline: &deadline, DependsOn: []int64{9}},
+		{ID: 3, Title: "Write", HoursRequired: 3, HoursSpent: 1, ParentID: &parentID, Position: 2},
+		{ID: 2, Title: "Collect data", HoursRequired: 2, ParentID: &parentID, Position: 1},
+		{ID: 4, Title: "Outline", HoursRequired: 5, ParentID: &parentID, Position: 0, Status: "completed"},
+		{ID: 5, Title: "Send", HoursRequired: 1, DependsOn: []int64{1}},
+		{ID: 9, Title: "Access", HoursRequired: 1},
+	}
+
+	rolled := RollUpSubtasks(tasks)
+	again := RollUpSubtasks(rolled)
+	for _, got := range [][]models.Task{rolled, again} {
+		parent := got[0]
+		if parent.HoursRequired != 5 || parent.HoursSpent != 1 || parent.OpenSubtasks != 2 {
+			t.Errorf("expected the parent to sum its open subtasks, got %+v", parent)
+		}
+		if got[2].Deadline == nil || !got[2].Deadline.Equal(deadline) || len(got[2].DependsOn) != 1 || got[2].DependsOn[0] != 9 {
+			t.Errorf("expected the first subtask to take the parent's deadline and dependencies, got %+v", got[2])
+		}
+		if len(got[1].DependsOn) != 1 || got[1].DependsOn[0] != 2 {
+			t.Errorf("expected the second subtask after the first, got %v", got[1].DependsOn)
+		}
+		if len(got[4].DependsOn) != 1 || got[4].DependsOn[0] != 3 {
+			t.Errorf("expected a dependency on the parent to wait for its last subtask, got %v", got[4].DependsOn)
+		}
+	}
+	if tasks[0].HoursRequired != 1 || len(tasks[4].DependsOn) != 1 || tasks[4].DependsOn[0] != 1 || tasks[2].Deadline != nil {
+		t.Error("expected the input left untouched")
+	}
+}
+
+func TestScheduler_SubtasksPlannedInOrder(t *testing.T) {
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	friday := monday.AddDate(0, 0, 4)
+	user := &models.User{ID: 1, DailyCapacity: 2, WorkDays: []int{1, 2, 3, 4, 5}}
+	parentID := int64(1)
+	tasks := []models.Task{
+		{ID: 1, Title: "Report", HoursRequired: 1, Priority: 5, Deadline: &friday},
+		{ID: 2, Title: "Draft", HoursRequired: 2, Priority: 1, ParentID: &parentID, Position: 1},
+		{ID: 3, Title: "Review", HoursRequired: 2, Priority: 9, ParentID: &parentID, Position: 2},
+	}
+
+	result := NewScheduler(user, tasks).Schedule(monday)
+	if !result.Success {
+		t.Fatalf("expected all subtasks planned, got %+v", result)
+	}
+	first := make(map[int64]time.Time)
+	for _, day := range result.DaySchedules {
+		for _, info := range day.Tasks {
+			if info.TaskID == 1 {
+				t.Fatalf("expected the parent itself not planned, got it on %s", day.Date.Format("02.01"))
+			}
+			if _, ok := first[info.TaskID]; !ok {
+				first[info.TaskID] = day.Date
+			}
+		}
+	}
+	if !first[2].Before(first[3]) {
+		t.Errorf("expected Draft before Review despite priorities, got %v", first)
+	}
+	if cost := PlanCost(user, tasks, result); cost != 0 {
+		t.Errorf("expected no cost for the parent's own estimate, got %v", cost)
+	}
+}
@@ -46,15 +46,22 @@ func BlockSlotsFromBusy(slots []models.TimeSlot, busy []models.BusyInterval) {
 	}
 }
 
-// FreeHoursOnDate returns remaining bookable hours on a date from the slot grid.
-func FreeHoursOnDate(slots []models.TimeSlot, dateKey string) float64 {
-	var free float64
+// slotsOn returns the slots of one day that start at or after from and end by until
+// (zero bounds keep the whole day).
+func slotsOn(slots []models.TimeSlot, dateKey string, from, until time.Time) []*models.TimeSlot {
+	var daySlots []*models.TimeSlot
 	for i := range slots {
-		if slots[i].Date.Format("2006-01-02") != dateKey {
-			continue
+		if slots[i].Date.Format("2006-01-02") == dateKey {
+			daySlots = append(daySlots, &slots[i])
 		}
-		rem := slots[i].CapacityHours - slots[i].AllocatedHours
-		if rem > 0 {
+	}
+	return slotsUntil(slotsFrom(daySlots, from), until)
+}
+
+func freeHours(daySlots []*models.TimeSlot) float64 {
+	var free float64
+	for _, slot := range daySlots {
+		if rem := slot.CapacityHours - slot.AllocatedHours; rem > 0 {
 			free += rem
 		}
 	}
@@ -81,28 +88,8 @@ func HorizonEndDate(startDate time.Time) time.Time {
 	return startDate.AddDate(0, 0, PlanningHorizonDays())
 }
 
-// allocateOnSlots marks hours as used on the slot grid; returns hours actually placed.
-func allocateOnSlots(slots []models.TimeSlot, dateKey string, hours float64) float64 {
-	remaining := hours
-	var placed float64
-	for i := range slots {
-		if slots[i].Date.Format("2006-01-02") != dateKey {
-			continue
-		}
-		free := slots[i].CapacityHours - slots[i].AllocatedHours
-		if free <= 1e-9 {
-			continue
-		}
-		toAllocate := remaining
-		if toAllocate > free {
-			toAllocate = free
-		}
-		slots[i].AllocatedHours += toAllocate
-		remaining -= toAllocate
-		placed += toAllocate
-		if remaining <= 1e-9 {
-			break
-		}
-	}
-	return placed
+// allocateOnSlots marks hours as used on the slot grid, in slots between from and until (see slotsOn);
+// returns the blocks actually placed. left and minChunk are passed to placeChunks to keep work sessions long enough.
+func allocateOnSlots(slots []models.TimeSlot, dateKey string, hours, left, minChunk float64, from, until time.Time) []interval {
+	return placeChunks(slotsOn(slots, dateKey, from, until), hours, left, minChunk)
 }
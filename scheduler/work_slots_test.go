@@ -32,7 +32,7 @@ func TestBlockSlotsFromBusy_ReducesFreeHours(t *testing.T) {
 	}
 	BlockSlotsFromBusy(slots, busy)
 
-	free := FreeHoursOnDate(slots, "2025-01-06")
+	free := freeHours(slotsOn(slots, "2025-01-06", time.Time{}, time.Time{}))
 	if free != 2.0 {
 		t.Errorf("expected 2 free hours after blocking 1h, got %f", free)
 	}
@@ -41,19 +41,6 @@ func TestBlockSlotsFromBusy_ReducesFreeHours(t *testing.T) {
 	}
 }
 
-func TestFreeHoursOnDate_UnknownDate(t *testing.T) {
-	slots := []models.TimeSlot{
-		{
-			Date:           time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
-			CapacityHours:  1,
-			AllocatedHours: 0,
-		},
-	}
-	if got := FreeHoursOnDate(slots, "2099-01-01"); got != 0 {
-		t.Errorf("expected 0 free hours for unknown date, got %f", got)
-	}
-}
-
 func TestPlanningHorizonDays_FromEnv(t *testing.T) {
 	t.Setenv("PLANNING_HORIZON_DAYS", "14")
 	if got := PlanningHorizonDays(); got != 14 {